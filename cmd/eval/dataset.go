@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// conversation 标注数据集中的一段对话（JSONL 每行一条）
+type conversation struct {
+	ID        string  `json:"id"`
+	UserID    string  `json:"user_id"`    // 为空时使用 "eval-<id>"
+	SessionID string  `json:"session_id"` // 为空时使用 id
+	Language  string  `json:"language"`   // 可选：判定使用的 Prompt 语言
+	Turns     []turn  `json:"turns"`
+	Queries   []query `json:"queries"`
+}
+
+// turn 一轮对话及其标注
+type turn struct {
+	Input       string `json:"input"`
+	Output      string `json:"output"`
+	ShouldStage *bool  `json:"should_stage"` // 是否应进入暂存区，未标注时不参与统计
+	Category    string `json:"category"`     // 期望分类，未标注时不参与统计
+}
+
+// query 召回查询及其相关记忆
+type query struct {
+	Query string `json:"query"`
+	// Relevant 每一项代表一条相关记忆，召回内容包含该关键词（忽略大小写）即视为命中；
+	// 同一条记忆有多种表述时用 "|" 分隔，如 "咖啡|coffee"
+	Relevant []string `json:"relevant"`
+}
+
+func loadDataset(path string) ([]conversation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var conversations []conversation
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+		var c conversation
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("line-%d", line)
+		}
+		if c.UserID == "" {
+			c.UserID = "eval-" + c.ID
+		}
+		if c.SessionID == "" {
+			c.SessionID = c.ID
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, scanner.Err()
+}
+
+// turnKey 写入 STM 时附带的标识，用于把判定结果对应回标注
+func turnKey(c conversation, index int) string {
+	return fmt.Sprintf("%s#%d", c.ID, index)
+}
+
+// matchesRelevant 召回内容是否命中某条相关记忆
+func matchesRelevant(content, relevant string) bool {
+	content = strings.ToLower(content)
+	for _, keyword := range strings.Split(relevant, "|") {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword != "" && strings.Contains(content, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,344 @@
+// eval 离线评估：把标注数据集回放进记忆漏斗（STM → 判定 → Staging → LTM），
+// 统计暂存决策的 precision/recall、分类准确率，以及标注查询在 LTM 上的 recall@k 与 MRR。
+//
+// 用法：
+//
+//	go run ./cmd/eval -dataset data.jsonl -llm record -fixtures fixtures.jsonl   # 调用真实模型并录制
+//	go run ./cmd/eval -dataset data.jsonl -llm replay -fixtures fixtures.jsonl   # 离线回放录制结果
+//	go run ./cmd/eval -dataset data.jsonl -llm live -embedder hash -out report.json
+//
+// 存储全部使用进程内实现，不会读写 Redis / MySQL / 向量库；阈值等其余配置沿用 .env。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"ai-memory/pkg/config"
+	"ai-memory/pkg/llm"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/memory"
+	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
+)
+
+func main() {
+	datasetPath := flag.String("dataset", "", "标注数据集（JSONL，每行一段对话）")
+	llmMode := flag.String("llm", "replay", "判定模型: live（使用 LLM_PROVIDER）/ record（live 并录制）/ replay（回放录制文件）")
+	embedderMode := flag.String("embedder", "", "向量化: live / record / replay / hash（特征哈希，无需模型），留空与 -llm 相同")
+	fixturesPath := flag.String("fixtures", "", "录制文件（JSONL）：record 模式追加写入，replay 模式读取")
+	hashDimension := flag.Int("hash-dim", 256, "hash 向量化的维度")
+	k := flag.Int("k", 5, "recall@k 的 k")
+	promotePending := flag.Bool("promote-pending", true, "将等待人工确认的中等信心条目也晋升到 LTM（只评估判定与召回，不模拟人工审核）")
+	outPath := flag.String("out", "", "JSON 报告输出路径（可选）")
+	flag.Parse()
+
+	if *datasetPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: eval -dataset <file.jsonl> [-llm live|replay] [-fixtures <file.jsonl>] [-k 5] [-out report.json]")
+		os.Exit(2)
+	}
+	if *embedderMode == "" {
+		*embedderMode = *llmMode
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load config:", err)
+	}
+	if err := logger.Init(cfg); err != nil {
+		panic("Failed to initialize logger: " + err.Error())
+	}
+	defer logger.Shutdown()
+
+	// 数据集一次性回放，不等待触发阈值与晋升等待时间；评估不受每日预算限制
+	cfg.STMJudgeMinMessages = 1
+	cfg.StagingMinOccurrences = 1
+	cfg.StagingMinWaitHours = 0
+	cfg.LLMBudgetUserDailyTokens, cfg.LLMBudgetUserDailyCalls = 0, 0
+	cfg.LLMBudgetGlobalDailyTokens, cfg.LLMBudgetGlobalDailyCalls = 0, 0
+
+	conversations, err := loadDataset(*datasetPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load dataset:", err)
+		os.Exit(1)
+	}
+
+	replaying := *llmMode == "replay" || *embedderMode == "replay"
+	recording := *llmMode == "record" || *embedderMode == "record"
+	if (replaying || recording) && *fixturesPath == "" {
+		fmt.Fprintln(os.Stderr, "-fixtures is required in record/replay mode")
+		os.Exit(2)
+	}
+	if replaying && recording {
+		fmt.Fprintln(os.Stderr, "cannot record and replay the same fixtures file")
+		os.Exit(2)
+	}
+
+	var replay *llm.ReplayLLM
+	if replaying {
+		if replay, err = llm.LoadReplayLLM(*fixturesPath); err != nil {
+			fmt.Fprintln(os.Stderr, "load fixtures:", err)
+			os.Exit(1)
+		}
+	}
+
+	ctx := context.Background()
+	llmClient := newEvalLLM(cfg, *llmMode, replay)
+	embedder, dimension, err := newEvalEmbedder(ctx, cfg, *embedderMode, replay, *hashDimension)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "init embedder:", err)
+		os.Exit(1)
+	}
+
+	// 录制：同一文件同时记录判定调用与向量化调用
+	if recording {
+		var recordLLM llm.LLM
+		var recordEmbedder llm.Embedder
+		if *llmMode == "record" {
+			recordLLM = llmClient
+		}
+		if *embedderMode == "record" {
+			recordEmbedder = embedder
+		}
+		recorder, err := llm.NewRecordingLLM(*fixturesPath, recordLLM, recordEmbedder)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "open fixtures:", err)
+			os.Exit(1)
+		}
+		defer recorder.Close()
+		if recordLLM != nil {
+			llmClient = recorder
+		}
+		if recordEmbedder != nil {
+			embedder = recorder
+		}
+	}
+
+	vectorStore := store.NewInMemoryVectorStore("")
+	if err := vectorStore.Init(ctx, dimension); err != nil {
+		fmt.Fprintln(os.Stderr, "init vector store:", err)
+		os.Exit(1)
+	}
+	manager := memory.NewManager(cfg, vectorStore, store.NewInMemoryListStore(), nil, embedder, llmClient, store.NewInMemoryStagingStore(30), nil)
+	defer manager.Shutdown()
+
+	r := run(ctx, manager, vectorStore, conversations, *k, *promotePending)
+	if replay != nil {
+		r.ReplayMisses = replay.Misses()
+	}
+
+	r.print()
+	if *outPath != "" {
+		if err := r.writeJSON(*outPath); err != nil {
+			fmt.Fprintln(os.Stderr, "write report:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// judgment 一轮对话的判定结果
+type judgment struct {
+	staged   bool
+	category string
+}
+
+func run(ctx context.Context, manager *memory.Manager, vectorStore memory.VectorStore, conversations []conversation, k int, promotePending bool) *report {
+	r := &report{
+		Conversations: len(conversations),
+		Category:      categoryMetrics{Confused: make(map[string]int)},
+		Recall:        recallMetrics{K: k},
+	}
+
+	// 后台调度器也可能触发判定，观察者需要加锁
+	var mu sync.Mutex
+	judgments := make(map[string]judgment)
+	manager.SetJudgeObserver(func(record types.Record, result *types.JudgeResult, staged bool) {
+		if key, ok := record.Metadata["eval_turn"].(string); ok {
+			mu.Lock()
+			judgments[key] = judgment{staged: staged, category: string(result.Category)}
+			mu.Unlock()
+		}
+	})
+
+	// 1. 写入 STM 并判定
+	for _, c := range conversations {
+		for i, t := range c.Turns {
+			metadata := map[string]interface{}{"eval_turn": turnKey(c, i)}
+			if c.Language != "" {
+				metadata["language"] = c.Language
+			}
+			if err := manager.Add(ctx, c.UserID, c.SessionID, t.Input, t.Output, metadata); err != nil {
+				logger.Error("eval: add turn failed", err, "conversation", c.ID)
+			}
+		}
+		if err := manager.JudgeAndStageFromSTM(ctx, c.UserID, c.SessionID); err != nil {
+			logger.Error("eval: judge failed", err, "conversation", c.ID)
+		}
+	}
+
+	// 2. 晋升到 LTM
+	if err := manager.PromoteStagingToLTM(ctx); err != nil {
+		logger.Error("eval: promotion failed", err)
+	}
+	if promotePending {
+		for _, c := range conversations {
+			entries, _ := manager.GetStagingEntries(ctx, c.UserID)
+			for _, entry := range entries {
+				if entry.Status != types.StagingPending {
+					continue
+				}
+				if err := manager.ConfirmStagingEntry(ctx, entry.ID); err != nil {
+					logger.Error("eval: confirm pending entry failed", err, "entry", entry.ID)
+				}
+			}
+		}
+	}
+
+	// 3. 判定指标
+	mu.Lock()
+	observed := make(map[string]judgment, len(judgments))
+	for key, j := range judgments {
+		observed[key] = j
+	}
+	mu.Unlock()
+	for _, c := range conversations {
+		for i, t := range c.Turns {
+			r.Turns++
+			j, ok := observed[turnKey(c, i)]
+			if !ok {
+				r.Unjudged++
+				continue
+			}
+			if t.ShouldStage != nil {
+				r.Staging.add(*t.ShouldStage, j.staged)
+			}
+			if t.Category != "" {
+				r.Category.Labeled++
+				if t.Category == j.category {
+					r.Category.Correct++
+				} else {
+					r.Category.Confused[t.Category+"->"+j.category]++
+				}
+			}
+		}
+	}
+	r.Staging.finish()
+	r.Category.Accuracy = ratio(r.Category.Correct, r.Category.Labeled)
+
+	// 4. 召回指标（只检索 LTM）
+	var recallSum, rrSum float64
+	for _, c := range conversations {
+		for _, q := range c.Queries {
+			if len(q.Relevant) == 0 {
+				continue
+			}
+			records, err := manager.Recall(ctx, c.UserID, "", types.RecallOptions{Query: q.Query, TopK: k})
+			if err != nil {
+				logger.Error("eval: recall failed", err, "query", q.Query)
+			}
+			recall, rr := scoreQuery(records, q.Relevant, k)
+			recallSum += recall
+			rrSum += rr
+			r.Recall.Queries++
+		}
+	}
+	if r.Recall.Queries > 0 {
+		r.Recall.RecallAt = recallSum / float64(r.Recall.Queries)
+		r.Recall.MRR = rrSum / float64(r.Recall.Queries)
+	}
+	if count, err := vectorStore.Count(ctx, map[string]interface{}{}); err == nil {
+		r.Recall.LTMCount = int(count)
+	}
+	return r
+}
+
+// scoreQuery 返回 top-k 中命中的相关记忆比例，以及第一条相关结果排名的倒数
+func scoreQuery(records []types.Record, relevant []string, k int) (recall float64, reciprocalRank float64) {
+	if len(records) > k {
+		records = records[:k]
+	}
+
+	hit := make([]bool, len(relevant))
+	for rank, record := range records {
+		for i, item := range relevant {
+			if !matchesRelevant(record.Content, item) {
+				continue
+			}
+			hit[i] = true
+			if reciprocalRank == 0 {
+				reciprocalRank = 1 / float64(rank+1)
+			}
+		}
+	}
+
+	found := 0
+	for _, h := range hit {
+		if h {
+			found++
+		}
+	}
+	return ratio(found, len(relevant)), reciprocalRank
+}
+
+// newEvalLLM live/record 模式直接使用 LLM_PROVIDER 对应的客户端（不启用重试、熔断与故障切换）
+func newEvalLLM(cfg *config.Config, mode string, replay *llm.ReplayLLM) llm.LLM {
+	switch mode {
+	case "replay":
+		return replay
+	case "live", "record":
+		switch cfg.LLMProvider {
+		case "openai":
+			return llm.NewOpenAIClient(cfg)
+		case "ollama":
+			return llm.NewOllamaClient(cfg)
+		case "anthropic":
+			return llm.NewAnthropicClient(cfg)
+		default:
+			panic("unknown llm provider: " + cfg.LLMProvider)
+		}
+	default:
+		panic("unknown eval llm mode: " + mode)
+	}
+}
+
+// newEvalEmbedder 返回向量化客户端及其向量维度
+func newEvalEmbedder(ctx context.Context, cfg *config.Config, mode string, replay *llm.ReplayLLM, hashDimension int) (memory.Embedder, int, error) {
+	switch mode {
+	case "hash":
+		embedder := memory.NewHashEmbedder(hashDimension)
+		return embedder, embedder.Dimension(), nil
+	case "replay":
+		if replay.Dimension() == 0 {
+			return nil, 0, fmt.Errorf("fixtures contain no embeddings")
+		}
+		return replay, replay.Dimension(), nil
+	case "live", "record":
+		provider := cfg.EmbeddingProvider
+		if provider == "" {
+			provider = cfg.LLMProvider
+		}
+		var client llm.Embedder
+		switch provider {
+		case "openai":
+			client = llm.NewOpenAIClient(cfg)
+		case "ollama":
+			client = llm.NewOllamaClient(cfg)
+		default:
+			panic("unknown embedding provider: " + provider)
+		}
+
+		embedder := llm.NewFallbackEmbedder(cfg.EmbeddingDimension, llm.NamedEmbedder{Name: provider, Embedder: client})
+		probeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		if err := embedder.VerifyDimensions(probeCtx); err != nil {
+			return nil, 0, err
+		}
+		return embedder, embedder.Dimension(), nil
+	default:
+		panic("unknown eval embedder mode: " + mode)
+	}
+}
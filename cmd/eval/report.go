@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// report 评估结果
+type report struct {
+	Conversations int `json:"conversations"`
+	Turns         int `json:"turns"`
+	Unjudged      int `json:"unjudged"` // 判定失败（含回放未命中）的轮次
+	ReplayMisses  int `json:"replay_misses"`
+
+	Staging  stagingMetrics  `json:"staging"`
+	Category categoryMetrics `json:"category"`
+	Recall   recallMetrics   `json:"recall"`
+}
+
+// stagingMetrics 暂存决策（进入暂存区或绿色通道）的混淆矩阵
+type stagingMetrics struct {
+	TP        int     `json:"tp"`
+	FP        int     `json:"fp"`
+	FN        int     `json:"fn"`
+	TN        int     `json:"tn"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+type categoryMetrics struct {
+	Labeled  int            `json:"labeled"`
+	Correct  int            `json:"correct"`
+	Accuracy float64        `json:"accuracy"`
+	Confused map[string]int `json:"confused,omitempty"` // "期望->实际" -> 次数
+}
+
+type recallMetrics struct {
+	K        int     `json:"k"`
+	Queries  int     `json:"queries"`
+	RecallAt float64 `json:"recall_at_k"`
+	MRR      float64 `json:"mrr"`
+	LTMCount int     `json:"ltm_count"`
+}
+
+func (s *stagingMetrics) add(expected, actual bool) {
+	switch {
+	case expected && actual:
+		s.TP++
+	case !expected && actual:
+		s.FP++
+	case expected && !actual:
+		s.FN++
+	default:
+		s.TN++
+	}
+}
+
+func (s *stagingMetrics) finish() {
+	s.Precision = ratio(s.TP, s.TP+s.FP)
+	s.Recall = ratio(s.TP, s.TP+s.FN)
+	if s.Precision+s.Recall > 0 {
+		s.F1 = 2 * s.Precision * s.Recall / (s.Precision + s.Recall)
+	}
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func (r *report) print() {
+	fmt.Println("========== Memory Eval Report ==========")
+	fmt.Printf("conversations: %d  turns: %d  unjudged: %d  replay misses: %d\n",
+		r.Conversations, r.Turns, r.Unjudged, r.ReplayMisses)
+	fmt.Println()
+	fmt.Println("[Staging decisions]")
+	fmt.Printf("  TP=%d FP=%d FN=%d TN=%d\n", r.Staging.TP, r.Staging.FP, r.Staging.FN, r.Staging.TN)
+	fmt.Printf("  precision=%.3f recall=%.3f f1=%.3f\n", r.Staging.Precision, r.Staging.Recall, r.Staging.F1)
+	fmt.Println()
+	fmt.Println("[Category]")
+	fmt.Printf("  accuracy=%.3f (%d/%d)\n", r.Category.Accuracy, r.Category.Correct, r.Category.Labeled)
+	pairs := make([]string, 0, len(r.Category.Confused))
+	for pair := range r.Category.Confused {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	for _, pair := range pairs {
+		fmt.Printf("  %s: %d\n", pair, r.Category.Confused[pair])
+	}
+	fmt.Println()
+	fmt.Println("[Recall]")
+	fmt.Printf("  queries=%d ltm=%d recall@%d=%.3f mrr=%.3f\n",
+		r.Recall.Queries, r.Recall.LTMCount, r.Recall.K, r.Recall.RecallAt, r.Recall.MRR)
+}
+
+func (r *report) writeJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
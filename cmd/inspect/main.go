@@ -7,6 +7,7 @@ import (
 	"ai-memory/pkg/config"
 	"ai-memory/pkg/logger"
 	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
 )
 
 func main() {
@@ -21,8 +22,12 @@ func main() {
 	redisStore := store.NewRedisStore(cfg)
 	if err := redisStore.Ping(ctx); err == nil {
 		// Count items?
-		// pattern: memory:stm:*:*
-		keys, _ := redisStore.ScanKeys(ctx, "memory:stm:*:*")
+		// pattern: [tenant:*:]memory:stm:*:*
+		var keys []string
+		for _, pattern := range types.TenantKeyPatterns("memory:stm:*:*") {
+			matched, _ := redisStore.ScanKeys(ctx, pattern)
+			keys = append(keys, matched...)
+		}
 		items := 0
 		for _, key := range keys {
 			l, _ := redisStore.LRange(ctx, key, 0, -1)
@@ -0,0 +1,97 @@
+// 登录会话：令牌存储、/api 请求自动附加 Bearer 令牌与当前租户、过期时用刷新令牌续期
+
+const TOKEN_KEY = 'token'
+const REFRESH_KEY = 'refresh_token'
+const USER_KEY = 'user'
+const TENANT_KEY = 'tenant'
+
+export function saveSession(data) {
+    localStorage.setItem(TOKEN_KEY, data.token)
+    localStorage.setItem(REFRESH_KEY, data.refresh_token)
+    localStorage.setItem(USER_KEY, JSON.stringify(data.user))
+}
+
+export function clearSession() {
+    localStorage.removeItem(TOKEN_KEY)
+    localStorage.removeItem(REFRESH_KEY)
+    localStorage.removeItem(USER_KEY)
+}
+
+export function currentUser() {
+    return JSON.parse(localStorage.getItem(USER_KEY) || '{}')
+}
+
+// 当前操作的租户（通过 X-Tenant-ID 请求头传给服务端，未选择时为默认租户）
+export function currentTenant() {
+    return localStorage.getItem(TENANT_KEY) || 'default'
+}
+
+export function setTenant(tenant) {
+    localStorage.setItem(TENANT_KEY, tenant)
+}
+
+// 当前用户是否拥有指定权限（与 pkg/auth/role.go 一致，仅用于界面展示，服务端仍会校验）
+export function hasPermission(perm) {
+    return (currentUser().permissions || []).includes(perm)
+}
+
+const isApiRequest = (url) => url.startsWith('/api/') && url !== '/api/login' && url !== '/api/auth/refresh'
+
+// 多个请求同时 401 时只发起一次刷新
+let refreshing = null
+
+const refreshSession = (rawFetch) => {
+    if (!refreshing) {
+        const refreshToken = localStorage.getItem(REFRESH_KEY)
+        refreshing = (refreshToken
+            ? rawFetch('/api/auth/refresh', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ refresh_token: refreshToken })
+            }).then(async (res) => {
+                if (!res.ok) return false
+                saveSession(await res.json())
+                return true
+            }).catch(() => false)
+            : Promise.resolve(false)
+        ).finally(() => { refreshing = null })
+    }
+    return refreshing
+}
+
+const withToken = (init = {}) => {
+    const headers = new Headers(init.headers || {})
+    const token = localStorage.getItem(TOKEN_KEY)
+    if (token) headers.set('Authorization', `Bearer ${token}`)
+    if (!headers.has('X-Tenant-ID')) headers.set('X-Tenant-ID', currentTenant())
+    return { ...init, headers }
+}
+
+export function installAuthFetch(router) {
+    const rawFetch = window.fetch.bind(window)
+
+    window.fetch = async (input, init) => {
+        const url = typeof input === 'string' ? input : input.url
+        if (!isApiRequest(url)) {
+            return rawFetch(input, init)
+        }
+
+        let res = await rawFetch(input, withToken(init))
+        if (res.status !== 401) {
+            return res
+        }
+
+        if (await refreshSession(rawFetch)) {
+            res = await rawFetch(input, withToken(init))
+            if (res.status !== 401) {
+                return res
+            }
+        }
+
+        clearSession()
+        if (router.currentRoute.value.path !== '/login') {
+            router.push('/login')
+        }
+        return res
+    }
+}
@@ -9,6 +9,7 @@ const messages = {
             alerts: '告警中心',
             control: '系统控制',
             users: '用户管理',
+            audit: '审计日志',
             status: '系统状态',
             dashboard: '仪表板'
         },
@@ -85,7 +86,18 @@ const messages = {
             username: '用户名',
             password: '密码',
             login: '立即登录',
-            loginSuccess: '登录成功！'
+            loginSuccess: '登录成功！',
+            loginFailed: '用户名或密码错误',
+            sessionExpired: '登录已过期，请重新登录',
+            changePassword: '修改密码',
+            mustChangePassword: '首次登录请先修改初始密码',
+            currentPassword: '当前密码',
+            newPassword: '新密码',
+            confirmPassword: '确认新密码',
+            passwordTooShort: '新密码至少 8 位',
+            passwordMismatch: '两次输入的新密码不一致',
+            passwordChanged: '密码已修改',
+            passwordChangeFailed: '修改密码失败'
         },
         memory: {
             title: '记忆管理',
@@ -152,6 +164,8 @@ const messages = {
             cacheHitRateTooltip: 'LLM判定结果的复用率 (避免重复调用LLM)，不包含系统内部查询',
             cacheHits: '判定缓存命中次数',
             cacheMisses: '判定缓存未命中次数',
+            embeddingCacheHitRate: '向量化缓存命中率',
+            embeddingCacheHits: '向量化缓存命中 / 总请求',
             promotionTrend: '晋升趋势',
             queueLengthChange: '队列长度变化',
             queueTooltip: '该时段内暂存区积压记忆的平均数量 (计算公式：总积压量 ÷ 记录次数)',
@@ -164,7 +178,16 @@ const messages = {
             lowConfidence: '低信心',
             label: '指标名称',
             value: '数值',
-            time: '时间'
+            time: '时间',
+            llmUsage: 'LLM 用量与成本',
+            llmCalls: '调用次数',
+            llmCost: '估算成本',
+            llmTokens: 'Token 数',
+            llmOperation: '调用类型',
+            llmUser: '用户',
+            promptTokens: '输入 Token',
+            completionTokens: '输出 Token',
+            embeddingTokens: '向量化 Token'
         },
         users: {
             title: '用户管理',
@@ -173,7 +196,98 @@ const messages = {
             sessionCount: '会话数(STM)',
             ltmCount: '长期记忆数',
             actions: '操作',
-            neverActive: '从未活跃'
+            neverActive: '从未活跃',
+            promptLanguage: 'Prompt 语言',
+            languageDefault: '系统默认',
+            languageUpdated: '语言偏好已更新',
+            languageUpdateFailed: '更新语言偏好失败',
+            endUsers: '终端用户',
+            adminAccounts: '后台账号',
+            apiKeys: 'API Key'
+        },
+        tenant: {
+            label: '租户',
+            placeholder: '选择或输入租户 ID',
+            invalid: '租户 ID 只能包含字母、数字、下划线和短横线（最长 64 位）'
+        },
+        apiKeys: {
+            name: '名称',
+            namePlaceholder: '调用方服务名，如 agent-backend',
+            prefix: '密钥前缀',
+            scopes: '授权范围',
+            tenant: '租户',
+            tenantHint: '密钥只能访问该租户的数据，创建后不可修改',
+            createdBy: '创建人',
+            lastUsed: '最近使用',
+            neverUsed: '从未使用',
+            active: '有效',
+            revokedStatus: '已吊销',
+            create: '新建 API Key',
+            revoke: '吊销',
+            revokeConfirm: '吊销后使用该 Key 的调用方将立即无法访问，确认吊销 {name}？',
+            revoked: 'API Key 已吊销',
+            copyOnce: '请立即复制并妥善保存，关闭后将无法再次查看该密钥',
+            copied: '已复制',
+            loadFailed: '加载 API Key 列表失败',
+            operationFailed: '操作失败'
+        },
+        accounts: {
+            username: '用户名',
+            role: '角色',
+            status: '状态',
+            enabled: '启用',
+            disabled: '已停用',
+            createdAt: '创建时间',
+            create: '新建账号',
+            initialPassword: '初始密码',
+            initialPasswordHint: '至少 8 位，首次登录后须修改',
+            mustChangePassword: '待改密',
+            disable: '停用',
+            enable: '启用',
+            disableConfirm: '停用后该账号将无法登录，已登录的会话会立即失效，确认停用 {name}？',
+            created: '账号已创建',
+            updated: '账号已更新',
+            loadFailed: '加载账号列表失败',
+            operationFailed: '操作失败',
+            roles: {
+                admin: '管理员',
+                ops: '运维',
+                support: '客服'
+            },
+            roleDesc: {
+                admin: '全部权限，含账号管理',
+                ops: '记忆维护、告警规则、手动触发任务',
+                support: '查看数据、审核暂存区'
+            }
+        },
+        audit: {
+            title: '审计日志',
+            actor: '操作人',
+            action: '动作',
+            target: '操作对象',
+            userId: '终端用户',
+            detail: '详情',
+            systemLevel: '系统级',
+            actorPlaceholder: '操作人（用户名 / apikey:名称 / system:任务）',
+            userIdPlaceholder: '终端用户 ID',
+            actionPlaceholder: '动作',
+            start: '开始时间',
+            end: '结束时间',
+            loadFailed: '加载审计日志失败（需要 MySQL）',
+            actions: {
+                'memory.update': '编辑记忆',
+                'memory.delete': '删除记忆',
+                'stm.clear': '清空会话',
+                'staging.confirm': '确认暂存',
+                'staging.reject': '拒绝暂存',
+                'ltm.merge': '去重合并',
+                'ltm.evict': '衰减遗忘',
+                'alert_rule.update': '修改告警规则',
+                'account.create': '创建账号',
+                'account.update': '修改账号',
+                'apikey.create': '创建 API Key',
+                'apikey.revoke': '吊销 API Key'
+            }
         },
         control: {
             title: '系统管理控制台',
@@ -199,7 +313,9 @@ const messages = {
             title: '系统状态',
             online: '在线',
             offline: '离线',
-            normal: '正常'
+            normal: '正常',
+            circuitOpen: '已熔断',
+            halfOpen: '探测恢复中'
         }
     },
     en: {
@@ -210,6 +326,7 @@ const messages = {
             alerts: 'Alert Center',
             control: 'Control',
             users: 'Users',
+            audit: 'Audit Log',
             status: 'Status',
             dashboard: 'Dashboard'
         },
@@ -286,7 +403,18 @@ const messages = {
             username: 'Username',
             password: 'Password',
             login: 'Sign In',
-            loginSuccess: 'Login successful!'
+            loginSuccess: 'Login successful!',
+            loginFailed: 'Invalid username or password',
+            sessionExpired: 'Session expired, please sign in again',
+            changePassword: 'Change Password',
+            mustChangePassword: 'Please change the initial password before continuing',
+            currentPassword: 'Current password',
+            newPassword: 'New password',
+            confirmPassword: 'Confirm new password',
+            passwordTooShort: 'New password must be at least 8 characters',
+            passwordMismatch: 'The new passwords do not match',
+            passwordChanged: 'Password changed',
+            passwordChangeFailed: 'Failed to change password'
         },
         memory: {
             title: 'Memory Management',
@@ -353,6 +481,8 @@ const messages = {
             cacheHitRateTooltip: 'Reuse rate of LLM judgment results (avoiding redundant LLM calls), excluding internal system queries',
             cacheHits: 'Judgment Cache Hits',
             cacheMisses: 'Judgment Cache Misses',
+            embeddingCacheHitRate: 'Embedding Cache Hit Rate',
+            embeddingCacheHits: 'Embedding Cache Hits / Requests',
             promotionTrend: 'Promotion Trend',
             queueLengthChange: 'Queue Length Trend',
             queueTooltip: 'Average backlog count in Staging area (Formula: Total Backlog ÷ Record Count)',
@@ -365,7 +495,16 @@ const messages = {
             lowConfidence: 'Low Confidence',
             label: 'Metric',
             value: 'Value',
-            time: 'Time'
+            time: 'Time',
+            llmUsage: 'LLM Usage & Cost',
+            llmCalls: 'Calls',
+            llmCost: 'Estimated Cost',
+            llmTokens: 'Tokens',
+            llmOperation: 'Operation',
+            llmUser: 'User',
+            promptTokens: 'Prompt Tokens',
+            completionTokens: 'Completion Tokens',
+            embeddingTokens: 'Embedding Tokens'
         },
         users: {
             title: 'User Management',
@@ -374,7 +513,98 @@ const messages = {
             sessionCount: 'Sessions (STM)',
             ltmCount: 'LTM Items',
             actions: 'Actions',
-            neverActive: 'Never Active'
+            neverActive: 'Never Active',
+            promptLanguage: 'Prompt Language',
+            languageDefault: 'System Default',
+            languageUpdated: 'Language preference updated',
+            languageUpdateFailed: 'Failed to update language preference',
+            endUsers: 'End Users',
+            adminAccounts: 'Admin Accounts',
+            apiKeys: 'API Keys'
+        },
+        tenant: {
+            label: 'Tenant',
+            placeholder: 'Select or enter a tenant ID',
+            invalid: 'Tenant IDs may only contain letters, digits, underscores and hyphens (max 64)'
+        },
+        apiKeys: {
+            name: 'Name',
+            namePlaceholder: 'Calling service, e.g. agent-backend',
+            prefix: 'Key Prefix',
+            scopes: 'Scopes',
+            tenant: 'Tenant',
+            tenantHint: 'The key can only access data in this tenant. This cannot be changed later.',
+            createdBy: 'Created By',
+            lastUsed: 'Last Used',
+            neverUsed: 'Never used',
+            active: 'Active',
+            revokedStatus: 'Revoked',
+            create: 'New API Key',
+            revoke: 'Revoke',
+            revokeConfirm: 'Clients using this key will lose access immediately. Revoke {name}?',
+            revoked: 'API key revoked',
+            copyOnce: 'Copy and store this key now. It will not be shown again after closing.',
+            copied: 'Copied',
+            loadFailed: 'Failed to load API keys',
+            operationFailed: 'Operation failed'
+        },
+        accounts: {
+            username: 'Username',
+            role: 'Role',
+            status: 'Status',
+            enabled: 'Enabled',
+            disabled: 'Disabled',
+            createdAt: 'Created At',
+            create: 'New Account',
+            initialPassword: 'Initial password',
+            initialPasswordHint: 'At least 8 characters, must be changed on first login',
+            mustChangePassword: 'Pending password change',
+            disable: 'Disable',
+            enable: 'Enable',
+            disableConfirm: 'The account will no longer be able to sign in and its active sessions end immediately. Disable {name}?',
+            created: 'Account created',
+            updated: 'Account updated',
+            loadFailed: 'Failed to load accounts',
+            operationFailed: 'Operation failed',
+            roles: {
+                admin: 'Admin',
+                ops: 'Ops',
+                support: 'Support'
+            },
+            roleDesc: {
+                admin: 'Full access including account management',
+                ops: 'Memory maintenance, alert rules, manual triggers',
+                support: 'Read-only access and staging review'
+            }
+        },
+        audit: {
+            title: 'Audit Log',
+            actor: 'Actor',
+            action: 'Action',
+            target: 'Target',
+            userId: 'End User',
+            detail: 'Detail',
+            systemLevel: 'System',
+            actorPlaceholder: 'Actor (username / apikey:name / system:job)',
+            userIdPlaceholder: 'End user ID',
+            actionPlaceholder: 'Action',
+            start: 'Start',
+            end: 'End',
+            loadFailed: 'Failed to load audit log (MySQL required)',
+            actions: {
+                'memory.update': 'Edit memory',
+                'memory.delete': 'Delete memory',
+                'stm.clear': 'Clear session',
+                'staging.confirm': 'Confirm staging',
+                'staging.reject': 'Reject staging',
+                'ltm.merge': 'Dedup merge',
+                'ltm.evict': 'Decay eviction',
+                'alert_rule.update': 'Update alert rule',
+                'account.create': 'Create account',
+                'account.update': 'Update account',
+                'apikey.create': 'Create API key',
+                'apikey.revoke': 'Revoke API key'
+            }
         },
         control: {
             title: 'System Control Panel',
@@ -400,7 +630,9 @@ const messages = {
             title: 'System Status',
             online: 'Online',
             offline: 'Offline',
-            normal: 'Normal'
+            normal: 'Normal',
+            circuitOpen: 'Circuit Open',
+            halfOpen: 'Recovering'
         }
     }
 }
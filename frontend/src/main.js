@@ -10,6 +10,10 @@ import './styles/element-custom.css'
 import App from './App.vue'
 import router from './router'
 import i18n from './i18n'
+import { installAuthFetch } from './auth'
+
+// 为所有 /api 请求附加登录令牌，过期时自动刷新
+installAuthFetch(router)
 
 const app = createApp(App)
 
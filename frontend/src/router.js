@@ -7,6 +7,7 @@ import Status from './views/Status.vue'
 import StagingReview from './views/StagingReview.vue'
 import MonitoringDashboard from './views/MonitoringDashboard.vue'
 import AdminControl from './views/AdminControl.vue'
+import AuditLog from './views/AuditLog.vue'
 
 const routes = [
     { path: '/login', component: Login, meta: { title: 'AI Memory Admin Login' } },
@@ -21,6 +22,7 @@ const routes = [
             { path: 'alerts', component: () => import('./views/AlertCenter.vue'), meta: { title: 'Alert Center' } },
             { path: 'control', component: AdminControl },
             { path: 'users', component: Users },
+            { path: 'audit', component: AuditLog },
             { path: 'status', component: Status },
             { path: '', redirect: 'memory' }
         ]
@@ -35,7 +37,7 @@ const router = createRouter({
 })
 
 router.beforeEach((to, from, next) => {
-    const isAuthenticated = !!localStorage.getItem('user')
+    const isAuthenticated = !!localStorage.getItem('token')
     if (to.meta.requiresAuth && !isAuthenticated) {
         next('/login')
     } else {
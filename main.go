@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"ai-memory/pkg/api"
@@ -10,8 +13,6 @@ import (
 	"ai-memory/pkg/config"
 	"ai-memory/pkg/logger"
 
-	"ai-memory/pkg/llm"
-
 	"ai-memory/pkg/memory"
 	"ai-memory/pkg/store"
 )
@@ -32,12 +33,27 @@ func main() {
 	ctx := context.Background()
 
 	// 2. Initialize Infrastructure
-	// Redis (STM)
-	redisStore := store.NewRedisStore(cfg)
-	if err := redisStore.Ping(ctx); err != nil {
-		logger.Error("Warning: Redis connection failed. Ensure Redis is running", err)
-	} else {
-		logger.System("Connected to Redis (STM)")
+	// STM & Staging
+	var stmStore memory.ListStore
+	var stagingStore memory.StagingStore
+	var redisStore *store.RedisStore // STM 使用 Redis 时复用连接（判定缓存等）
+
+	switch cfg.STMStoreProvider {
+	case "in_memory":
+		stmStore = store.NewInMemoryListStore()
+		stagingStore = store.NewInMemoryStagingStore(30) // TTL 30天
+		logger.System("In-Memory STM & Staging Store ready")
+	case "redis":
+		redisStore = store.NewRedisStore(cfg)
+		if err := redisStore.Ping(ctx); err != nil {
+			logger.Error("Warning: Redis connection failed. Ensure Redis is running", err)
+		} else {
+			logger.System("Connected to Redis (STM)")
+		}
+		stmStore = redisStore
+		stagingStore = store.NewStagingStore(redisStore.GetClient(), 30) // TTL 30天
+	default:
+		panic("unknown stm store provider: " + cfg.STMStoreProvider)
 	}
 
 	// MySQL (Auth & Admin)
@@ -56,46 +72,92 @@ func main() {
 		authService = auth.NewService(mysqlDB)
 		logger.System("Auth Schema Initialized")
 
-		// Seed Default Admin (if not exists)
-		if err := authService.CreateUser("admin", "admin123"); err == nil {
-			logger.System("Default Admin Created: admin / admin123")
-		}
-	}
+		authService.SetSessionTTL(
+			time.Duration(cfg.AuthSessionTTLMinutes)*time.Minute,
+			time.Duration(cfg.AuthRefreshTTLHours)*time.Hour,
+		)
 
-	// OpenAI (LLM & Embedder)
-	var llmClient llm.LLM
-	var embedderClient memory.Embedder
+		// 首次启动（users 表为空）时创建管理员；未配置密码则生成随机初始密码，仅在此打印一次
+		created, generatedPassword, err := authService.EnsureBootstrapAdmin(cfg.AuthBootstrapAdminUsername, cfg.AuthBootstrapAdminPassword)
+		switch {
+		case err != nil:
+			logger.Error("Failed to create bootstrap admin", err)
+		case created && generatedPassword != "":
+			logger.System("Bootstrap admin created, password must be changed on first login",
+				"username", cfg.AuthBootstrapAdminUsername, "password", generatedPassword)
+		case created:
+			logger.System("Bootstrap admin created", "username", cfg.AuthBootstrapAdminUsername)
+		}
 
-	switch cfg.LLMProvider {
-	case "openai":
-		client := llm.NewOpenAIClient(cfg)
-		llmClient = client
-		embedderClient = client
-	default:
-		// Default to OpenAI for now or error out
-		logger.System("Unknown provider, defaulting to OpenAI", "provider", cfg.LLMProvider)
-		client := llm.NewOpenAIClient(cfg)
-		llmClient = client
-		embedderClient = client
+		// 升级部署：旧版本内置的 admin/admin123 仍然有效时强制下次登录修改密码
+		if usernames, err := authService.FlagLegacyDefaultPasswords(); err != nil {
+			logger.Error("Failed to check legacy default passwords", err)
+		} else if len(usernames) > 0 {
+			logger.Warn("Accounts still use the legacy default password and must change it on next login", "usernames", usernames)
+		}
 	}
 
-	// 3. Initialize Vector Store (Only Qdrant supported now)
-	var vectorStore memory.VectorStore
+	// LLM & Embedder（对话与向量化可分别选择提供商）
+	llmClient := newLLMClient(cfg)
+	embedderClient := newEmbedder(cfg, redisStore)
 
-	// 强制使用 Qdrant
-	logger.System("Initializing Qdrant Vector Store", "addr", cfg.QdrantAddr, "collection", cfg.QdrantCollection)
-	qs, err := store.NewQdrantStore(cfg)
+	// 校验向量维度：主提供商必须与向量库一致，维度不兼容的备用提供商会被禁用
+	probeCtx, cancelProbe := context.WithTimeout(ctx, 30*time.Second)
+	err = embedderClient.VerifyDimensions(probeCtx)
+	cancelProbe()
 	if err != nil {
-		logger.Error("Failed to initialize Qdrant", err)
+		logger.Error("Embedding dimension check failed", err)
 		panic(err)
 	}
-	// Ensure collection exists. Vector size 1024 for BAAI/bge-m3
-	if err := qs.Init(ctx, 1024); err != nil {
-		logger.Error("Failed to init Qdrant collection", err)
-		panic(err)
+	vectorDimension := embedderClient.Dimension()
+
+	// 3. Initialize Vector Store
+	var vectorStore memory.VectorStore
+
+	switch cfg.VectorStoreProvider {
+	case "in_memory":
+		logger.System("Initializing In-Memory Vector Store", "snapshot", cfg.VectorStoreSnapshotPath)
+		ms := store.NewInMemoryVectorStore(cfg.VectorStoreSnapshotPath)
+		if err := ms.Init(ctx, vectorDimension); err != nil {
+			logger.Error("Failed to init in-memory vector store", err)
+			panic(err)
+		}
+		ms.StartSnapshotLoop(time.Duration(cfg.VectorStoreSnapshotIntervalSeconds) * time.Second)
+		defer ms.Close()
+		vectorStore = ms
+		logger.System("In-Memory Vector Store ready (LTM)")
+	case "qdrant":
+		logger.System("Initializing Qdrant Vector Store", "addr", cfg.QdrantAddr, "collection", cfg.QdrantCollection)
+		qs, err := store.NewQdrantStore(cfg)
+		if err != nil {
+			logger.Error("Failed to initialize Qdrant", err)
+			panic(err)
+		}
+		// Ensure collection exists
+		if err := qs.Init(ctx, vectorDimension); err != nil {
+			logger.Error("Failed to init Qdrant collection", err)
+			panic(err)
+		}
+		vectorStore = qs
+		logger.System("Connected to Qdrant (LTM)")
+	case "pgvector":
+		logger.System("Initializing pgvector Store", "table", cfg.PgVectorTable)
+		ps, err := store.NewPgVectorStore(cfg)
+		if err != nil {
+			logger.Error("Failed to initialize pgvector", err)
+			panic(err)
+		}
+		// Ensure table & indexes exist
+		if err := ps.Init(ctx, vectorDimension); err != nil {
+			logger.Error("Failed to init pgvector table", err)
+			panic(err)
+		}
+		defer ps.Close()
+		vectorStore = ps
+		logger.System("Connected to Postgres (LTM)")
+	default:
+		panic("unknown vector store provider: " + cfg.VectorStoreProvider)
 	}
-	vectorStore = qs
-	logger.System("Connected to Qdrant (LTM)")
 
 	// Infrastructure for End Users (MySQL)
 	var endUserStore memory.EndUserStore
@@ -103,7 +165,12 @@ func main() {
 		endUserStore = store.NewMySQLEndUserStore(mysqlDB)
 	}
 
-	memoryManager := memory.NewManager(cfg, vectorStore, redisStore, endUserStore, embedderClient, llmClient, redisStore, mysqlDB)
+	memoryManager := memory.NewManager(cfg, vectorStore, stmStore, endUserStore, embedderClient, llmClient, stagingStore, mysqlDB)
+
+	// 判定结果缓存（默认进程内 LRU，redis 时多副本共享）
+	if judgeCache := newJudgeCache(cfg, redisStore); judgeCache != nil {
+		memoryManager.SetJudgeCache(judgeCache)
+	}
 
 	// 初始化监控指标持久化
 	if mysqlDB != nil {
@@ -190,7 +257,13 @@ func main() {
 	// Keep the server running
 	if authService != nil {
 		logger.System("Server is running at http://localhost:8080")
-		select {} // Block forever
+
+		// 等待退出信号，优雅关闭后台任务（回写召回访问记录、快照等）
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+		logger.System("Shutting down...")
+		memoryManager.Shutdown()
 	} else {
 		logger.System("Server finished (Admin API not started due to missing MySQL connection).")
 	}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"ai-memory/pkg/auth"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/memory"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// handleListAccounts 后台账号列表
+func (s *Server) handleListAccounts(w http.ResponseWriter, r *http.Request) {
+	users, err := s.auth.ListUsers()
+	if err != nil {
+		logger.Error("Failed to list accounts", err)
+		http.Error(w, "Failed to list accounts", http.StatusInternalServerError)
+		return
+	}
+	if users == nil {
+		users = []*auth.User{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"accounts": users,
+		"roles":    auth.Roles(),
+	})
+}
+
+// handleCreateAccount 创建后台账号（首次登录须修改初始密码）
+func (s *Server) handleCreateAccount(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Username string    `json:"username"`
+		Password string    `json:"password"`
+		Role     auth.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.Username == "" {
+		http.Error(w, "username is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.auth.CreateUser(payload.Username, payload.Password, payload.Role)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	operator, _ := auth.UserFrom(r.Context())
+	logger.System("Admin account created", "username", user.Username, "role", user.Role, "by", operator.Username)
+	s.auditAccount(r, memory.AuditAccountCreate, user, map[string]interface{}{"role": user.Role})
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// handleSetAccountRole 分配角色
+func (s *Server) handleSetAccountRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid account id", http.StatusBadRequest)
+		return
+	}
+	var payload struct {
+		Role auth.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.auth.SetUserRole(id, payload.Role)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	operator, _ := auth.UserFrom(r.Context())
+	logger.System("Admin account role changed", "username", user.Username, "role", user.Role, "by", operator.Username)
+	s.auditAccount(r, memory.AuditAccountUpdate, user, map[string]interface{}{"role": user.Role})
+	json.NewEncoder(w).Encode(user)
+}
+
+// handleSetAccountStatus 停用/启用账号（不能停用自己）
+func (s *Server) handleSetAccountStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid account id", http.StatusBadRequest)
+		return
+	}
+	var payload struct {
+		Disabled bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	operator, _ := auth.UserFrom(r.Context())
+	if payload.Disabled && operator.ID == id {
+		http.Error(w, "Cannot disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.auth.SetUserDisabled(id, payload.Disabled)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	logger.System("Admin account status changed", "username", user.Username, "disabled", user.Disabled, "by", operator.Username)
+	s.auditAccount(r, memory.AuditAccountUpdate, user, map[string]interface{}{"disabled": user.Disabled})
+	json.NewEncoder(w).Encode(user)
+}
+
+// auditAccount 记录后台账号变更（系统级，不归属租户）
+func (s *Server) auditAccount(r *http.Request, action string, user *auth.User, detail map[string]interface{}) {
+	detail["username"] = user.Username
+	s.memory.RecordAudit(r.Context(), memory.AuditEntry{
+		Action:   action,
+		TargetID: strconv.Itoa(user.ID),
+		Detail:   detail,
+	})
+}
+
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, auth.ErrUserExists), errors.Is(err, auth.ErrLastAdmin):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, auth.ErrInvalidRole), errors.Is(err, auth.ErrWeakPassword):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.Error("Account operation failed", err)
+		http.Error(w, "Account operation failed", http.StatusInternalServerError)
+	}
+}
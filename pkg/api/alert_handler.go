@@ -1,7 +1,9 @@
 package api
 
 import (
+	"ai-memory/pkg/auth"
 	"ai-memory/pkg/memory"
+	"ai-memory/pkg/types"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,7 +11,14 @@ import (
 	"time"
 )
 
-// handleGetAlerts 获取告警记录（支持过滤）
+// includeSystemAlerts 告警按当前租户隔离：后台会话同时可见系统级告警（tenant_id 为空），
+// API Key 只能看到绑定租户的告警（系统级告警可能带有其他租户的明细）
+func includeSystemAlerts(r *http.Request) bool {
+	user, _ := auth.UserFrom(r.Context())
+	return user != nil && user.APIKeyID == 0
+}
+
+// handleGetAlerts 获取当前租户的告警记录（支持过滤）
 func (s *Server) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
 	// Parse Query Params
 	query := r.URL.Query()
@@ -32,7 +41,7 @@ func (s *Server) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	// Call Manager Query
-	alerts, total, err := s.memory.QueryAlerts(r.Context(), level, rule, limit, offset)
+	alerts, total, err := s.memory.QueryAlerts(r.Context(), level, rule, includeSystemAlerts(r), limit, offset)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to query alerts: %v", err), http.StatusInternalServerError)
 		return
@@ -46,7 +55,7 @@ func (s *Server) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleDeleteAlert 删除告警
+// handleDeleteAlert 删除告警（其他租户的告警按不存在处理）
 func (s *Server) handleDeleteAlert(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -54,8 +63,8 @@ func (s *Server) handleDeleteAlert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.memory.DeleteAlert(r.Context(), id); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete alert: %v", err), http.StatusInternalServerError)
+	if err := s.memory.DeleteAlert(r.Context(), id, includeSystemAlerts(r)); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete alert: %v", err), memoryErrorStatus(err))
 		return
 	}
 
@@ -63,13 +72,16 @@ func (s *Server) handleDeleteAlert(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": id})
 }
 
-// handleCreateAlert 手动创建告警
+// handleCreateAlert 手动创建告警（API Key 创建的告警固定归属其绑定租户）
 func (s *Server) handleCreateAlert(w http.ResponseWriter, r *http.Request) {
 	var alert memory.Alert
 	if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if !includeSystemAlerts(r) {
+		alert.TenantID = types.TenantFrom(r.Context())
+	}
 
 	if alert.ID == "" {
 		alert.ID = fmt.Sprintf("manual_%d", time.Now().UnixNano())
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"ai-memory/pkg/memory"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -33,7 +32,7 @@ func (s *Server) handleToggleAlertRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.memory.ToggleAlertRule(ruleID, req.Enabled); err != nil {
+	if err := s.memory.ToggleAlertRule(r.Context(), ruleID, req.Enabled); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to toggle rule: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -58,7 +57,7 @@ func (s *Server) handleUpdateAlertRuleConfig(w http.ResponseWriter, r *http.Requ
 	}
 
 	cooldown := time.Duration(req.CooldownMinutes) * time.Minute
-	if err := s.memory.UpdateAlertRuleCooldown(ruleID, cooldown); err != nil {
+	if err := s.memory.UpdateAlertRuleCooldown(r.Context(), ruleID, cooldown); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update rule: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -82,7 +81,7 @@ func (s *Server) handleUpdateAlertRuleConfigJSON(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if err := s.memory.UpdateAlertRuleConfigJSON(ruleID, req.ConfigJSON); err != nil {
+	if err := s.memory.UpdateAlertRuleConfigJSON(r.Context(), ruleID, req.ConfigJSON); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update config: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -90,14 +89,28 @@ func (s *Server) handleUpdateAlertRuleConfigJSON(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
-// handleGetAlertStats 获取告警统计信息（带缓存）
+// handleGetAlertStats 获取告警统计信息（带缓存）：by_level 只统计当前租户可见的告警；
+// 检查次数、通知成功率与规则统计是告警引擎的全局计数，API Key 调用时不返回
 func (s *Server) handleGetAlertStats(w http.ResponseWriter, r *http.Request) {
-	stats, levelCounts, err := s.memory.GetAlertStatsWithCache(r.Context())
+	includeSystem := includeSystemAlerts(r)
+	stats, levelCounts, err := s.memory.GetAlertStatsWithCache(r.Context(), includeSystem)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get stats: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	if !includeSystem {
+		totalFired := 0
+		for _, count := range levelCounts {
+			totalFired += count
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"total_fired": totalFired,
+			"by_level":    levelCounts,
+		})
+		return
+	}
+
 	// 计算通知成功率
 	notifySuccessRate := 0.0
 	if stats.NotifySuccess+stats.NotifyFailed > 0 {
@@ -123,7 +136,7 @@ func (s *Server) handleGetAlertTrend(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	trend, err := s.memory.GetAlertTrend(r.Context(), hours)
+	trend, err := s.memory.GetAlertTrend(r.Context(), hours, includeSystemAlerts(r))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get trend: %v", err), http.StatusInternalServerError)
 		return
@@ -132,9 +145,9 @@ func (s *Server) handleGetAlertTrend(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(trend)
 }
 
-// handleGetAggregatedAlerts 获取聚合告警
+// handleGetAggregatedAlerts 获取当前租户的聚合告警
 func (s *Server) handleGetAggregatedAlerts(w http.ResponseWriter, r *http.Request) {
-	alerts := memory.GetAggregatedAlerts()
+	alerts := s.memory.GetAggregatedAlerts(r.Context(), includeSystemAlerts(r))
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"alerts": alerts,
 	})
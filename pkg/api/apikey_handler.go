@@ -0,0 +1,98 @@
+package api
+
+import (
+	"ai-memory/pkg/auth"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/memory"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// handleListAPIKeys API Key 列表（不含密钥明文）
+func (s *Server) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.auth.ListAPIKeys()
+	if err != nil {
+		logger.Error("Failed to list api keys", err)
+		http.Error(w, "Failed to list api keys", http.StatusInternalServerError)
+		return
+	}
+	if keys == nil {
+		keys = []*auth.APIKey{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"api_keys": keys,
+		"scopes":   auth.Scopes(),
+	})
+}
+
+// handleCreateAPIKey 创建 API Key；密钥明文只在此响应中返回一次。tenant_id 为空时绑定默认租户
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name     string       `json:"name"`
+		Scopes   []auth.Scope `json:"scopes"`
+		TenantID string       `json:"tenant_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	operator, _ := auth.UserFrom(r.Context())
+	key, plaintext, err := s.auth.CreateAPIKey(payload.Name, payload.Scopes, payload.TenantID, operator.ID)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidScope) || errors.Is(err, auth.ErrInvalidTenant) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Error("Failed to create api key", err)
+		http.Error(w, "Failed to create api key", http.StatusInternalServerError)
+		return
+	}
+
+	logger.System("API key created", "name", key.Name, "prefix", key.Prefix, "scopes", payload.Scopes, "tenant", key.TenantID, "by", operator.Username)
+	s.memory.RecordAudit(r.Context(), memory.AuditEntry{
+		Action:   memory.AuditAPIKeyCreate,
+		TargetID: strconv.Itoa(key.ID),
+		Detail:   map[string]interface{}{"name": key.Name, "prefix": key.Prefix, "scopes": key.Scopes, "tenant_id": key.TenantID},
+	})
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"api_key": key,
+		"key":     plaintext,
+	})
+}
+
+// handleRevokeAPIKey 吊销 API Key
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid api key id", http.StatusBadRequest)
+		return
+	}
+
+	key, err := s.auth.RevokeAPIKey(id)
+	if err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to revoke api key", err)
+		http.Error(w, "Failed to revoke api key", http.StatusInternalServerError)
+		return
+	}
+
+	operator, _ := auth.UserFrom(r.Context())
+	logger.System("API key revoked", "name", key.Name, "prefix", key.Prefix, "by", operator.Username)
+	s.memory.RecordAudit(r.Context(), memory.AuditEntry{
+		Action:   memory.AuditAPIKeyRevoke,
+		TargetID: strconv.Itoa(id),
+		Detail:   map[string]interface{}{"name": key.Name, "prefix": key.Prefix},
+	})
+	json.NewEncoder(w).Encode(key)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"ai-memory/pkg/memory"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleGetAudit 查询审计日志（当前租户 + 系统级操作），按 actor / user_id / action / 时间范围过滤，
+// start、end 为 RFC3339 时间
+func (s *Server) handleGetAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := memory.AuditFilter{
+		Actor:  query.Get("actor"),
+		UserID: query.Get("user_id"),
+		Action: query.Get("action"),
+	}
+
+	for name, target := range map[string]*time.Time{"start": &filter.Start, "end": &filter.End} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s, expected RFC3339", name), http.StatusBadRequest)
+			return
+		}
+		*target = t
+	}
+
+	limit := 50
+	if lStr := query.Get("limit"); lStr != "" {
+		if l, err := strconv.Atoi(lStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	page := 1
+	if pStr := query.Get("page"); pStr != "" {
+		if p, err := strconv.Atoi(pStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	entries, total, err := s.memory.QueryAudit(r.Context(), filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to query audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
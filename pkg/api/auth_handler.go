@@ -0,0 +1,207 @@
+package api
+
+import (
+	"ai-memory/pkg/auth"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/memory"
+	"ai-memory/pkg/types"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// publicAPIPaths 无需登录即可访问的接口
+var publicAPIPaths = map[string]bool{
+	"/api/login":        true,
+	"/api/auth/refresh": true,
+}
+
+// passwordChangePaths 必须修改初始密码时仍允许访问的接口
+var passwordChangePaths = map[string]bool{
+	"/api/auth/me":       true,
+	"/api/auth/password": true,
+	"/api/logout":        true,
+}
+
+// sessionOnlyPaths 仅限登录会话访问的接口（API Key 无密码、无会话）
+var sessionOnlyPaths = map[string]bool{
+	"/api/auth/password": true,
+	"/api/logout":        true,
+}
+
+// tenantHeader 登录会话通过该请求头选择要操作的租户（缺省为默认租户）
+const tenantHeader = "X-Tenant-ID"
+
+// requireAuth 鉴权中间件：/api/* 请求（登录与刷新令牌除外）必须携带有效的 Bearer 访问令牌或 API Key；
+// 通过后将用户与租户写入 context。静态前端资源不受影响
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || publicAPIPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		var user *auth.User
+		var err error
+		if strings.HasPrefix(token, auth.APIKeyPrefix) {
+			user, err = s.auth.ValidateAPIKey(token, clientIP(r))
+		} else {
+			user, err = s.auth.ValidateToken(token)
+		}
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidToken) {
+				logger.Error("Failed to validate token", err)
+			}
+			writeAuthError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if user.APIKeyID != 0 && sessionOnlyPaths[r.URL.Path] {
+			writeAuthError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		if user.MustChangePassword && !passwordChangePaths[r.URL.Path] {
+			writeAuthError(w, http.StatusForbidden, "password_change_required")
+			return
+		}
+
+		// API Key 只能访问绑定的租户；请求头指定了其他租户时拒绝，避免调用方误以为切换成功
+		tenantID := r.Header.Get(tenantHeader)
+		if user.APIKeyID != 0 {
+			if tenantID != "" && tenantID != user.TenantID {
+				writeAuthError(w, http.StatusForbidden, "tenant_mismatch")
+				return
+			}
+			tenantID = user.TenantID
+		}
+		if tenantID == "" {
+			tenantID = types.DefaultTenant
+		}
+		if !types.ValidTenantID(tenantID) {
+			writeAuthError(w, http.StatusBadRequest, "invalid_tenant")
+			return
+		}
+
+		// 操作人用于审计日志：后台账号为用户名，API Key 为 apikey:<name>
+		ctx := types.WithTenant(auth.WithUser(r.Context(), user), tenantID)
+		ctx = memory.WithActor(ctx, user.Username)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requirePermission 路由级权限校验，须在 requireAuth 之后生效
+func (s *Server) requirePermission(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := auth.UserFrom(r.Context())
+		if !user.Can(perm) {
+			writeAuthError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// clientIP 记录 API Key 来源；服务通常直接暴露给内网调用方，不信任 X-Forwarded-For
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// writeAuthError 鉴权失败统一返回 JSON，前端据 error 字段决定刷新令牌或跳转
+func writeAuthError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func writeSession(w http.ResponseWriter, session *auth.Session) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":            "Login successful",
+		"token":              session.AccessToken,
+		"refresh_token":      session.RefreshToken,
+		"expires_at":         session.ExpiresAt,
+		"refresh_expires_at": session.RefreshExpiresAt,
+		"user":               session.User,
+	})
+}
+
+// handleRefreshSession 用刷新令牌换取新的令牌对
+func (s *Server) handleRefreshSession(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	session, err := s.auth.RefreshSession(payload.RefreshToken)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			logger.Error("Failed to refresh session", err)
+		}
+		writeAuthError(w, http.StatusUnauthorized, "invalid_refresh_token")
+		return
+	}
+	writeSession(w, session)
+}
+
+// handleLogout 注销当前会话
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if err := s.auth.RevokeSession(bearerToken(r)); err != nil {
+		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "logged_out"})
+}
+
+// handleGetCurrentUser 返回当前登录用户
+func (s *Server) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFrom(r.Context())
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": user})
+}
+
+// handleChangePassword 修改当前用户密码，成功后注销该用户的其他会话
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFrom(r.Context())
+	if err := s.auth.ChangePassword(user.ID, payload.OldPassword, payload.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			http.Error(w, "Current password is incorrect", http.StatusBadRequest)
+		case errors.Is(err, auth.ErrWeakPassword):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			logger.Error("Failed to change password", err, "user", user.Username)
+			http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := s.auth.RevokeUserSessions(user.ID, bearerToken(r)); err != nil {
+		logger.Error("Failed to revoke other sessions", err, "user", user.Username)
+	}
+	logger.System("Admin password changed", "user", user.Username)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
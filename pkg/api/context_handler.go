@@ -0,0 +1,41 @@
+package api
+
+import (
+	"ai-memory/pkg/memory"
+	"ai-memory/pkg/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// handleAssembleContext 按 token 预算组装可直接注入 Prompt 的上下文块
+// POST /api/context
+func (s *Server) handleAssembleContext(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		UserID    string `json:"user_id"`
+		SessionID string `json:"session_id"`
+		types.ContextOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.memory.AssembleContext(r.Context(), payload.UserID, payload.SessionID, payload.ContextOptions)
+	if err != nil {
+		if errors.Is(err, memory.ErrInvalidContextOptions) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to assemble context: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -1,10 +1,18 @@
 package api
 
 import (
+	"ai-memory/pkg/auth"
 	"encoding/json"
 	"net/http"
 )
 
+// crossTenantView 跨租户汇总（全部租户的趋势、按租户 / 终端用户的用量排行）仅对管理员会话开放，
+// 其他后台角色与 API Key 只能看到当前租户的数据
+func crossTenantView(r *http.Request) bool {
+	user, _ := auth.UserFrom(r.Context())
+	return user != nil && user.APIKeyID == 0 && user.Role == auth.RoleAdmin
+}
+
 // handleGetDashboardMetrics 获取Dashboard监控指标（包含图表数据）
 func (s *Server) handleGetDashboardMetrics(w http.ResponseWriter, r *http.Request) {
 	// 解析时间范围参数（支持 1h/24h/7d/30d）
@@ -12,6 +20,6 @@ func (s *Server) handleGetDashboardMetrics(w http.ResponseWriter, r *http.Reques
 	if timeRange == "" {
 		timeRange = "24h"
 	}
-	metrics := s.memory.GetDashboardMetrics(r.Context(), timeRange)
+	metrics := s.memory.GetDashboardMetrics(r.Context(), timeRange, crossTenantView(r))
 	json.NewEncoder(w).Encode(metrics)
 }
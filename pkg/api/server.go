@@ -4,7 +4,9 @@ import (
 	"ai-memory/pkg/auth"
 	"ai-memory/pkg/logger"
 	"ai-memory/pkg/memory"
+	"ai-memory/pkg/types"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -29,47 +31,73 @@ func (s *Server) routes() {
 	// API Group
 	s.mux.HandleFunc("/api/login", s.handleLogin)
 
-	// Protected Routes (TODO: Add Middleware)
-	// Protected Routes (TODO: Add Middleware)
-	s.mux.HandleFunc("GET /api/memories", s.handleListMemories)
-	s.mux.HandleFunc("POST /api/memories", s.handleAddMemory)
-	s.mux.HandleFunc("PUT /api/memories/{id}", s.handleUpdateMemory)
-	s.mux.HandleFunc("POST /api/retrieve", s.handleRetrieveMemory)
-	s.mux.HandleFunc("DELETE /api/memories/{id}", s.handleDeleteMemory)
+	// 会话管理（除 login 与 refresh 外均需登录，见 requireAuth）
+	s.mux.HandleFunc("POST /api/auth/refresh", s.handleRefreshSession)
+	s.mux.HandleFunc("POST /api/logout", s.handleLogout)
+	s.mux.HandleFunc("GET /api/auth/me", s.handleGetCurrentUser)
+	s.mux.HandleFunc("POST /api/auth/password", s.handleChangePassword)
+
+	// 后台账号管理
+	s.mux.HandleFunc("GET /api/accounts", s.requirePermission(auth.PermAccountManage, s.handleListAccounts))
+	s.mux.HandleFunc("POST /api/accounts", s.requirePermission(auth.PermAccountManage, s.handleCreateAccount))
+	s.mux.HandleFunc("PUT /api/accounts/{id}/role", s.requirePermission(auth.PermAccountManage, s.handleSetAccountRole))
+	s.mux.HandleFunc("PUT /api/accounts/{id}/status", s.requirePermission(auth.PermAccountManage, s.handleSetAccountStatus))
+
+	// API Key 管理（机器客户端凭证）
+	s.mux.HandleFunc("GET /api/apikeys", s.requirePermission(auth.PermAccountManage, s.handleListAPIKeys))
+	s.mux.HandleFunc("POST /api/apikeys", s.requirePermission(auth.PermAccountManage, s.handleCreateAPIKey))
+	s.mux.HandleFunc("DELETE /api/apikeys/{id}", s.requirePermission(auth.PermAccountManage, s.handleRevokeAPIKey))
+
+	// 租户（管理后台通过 X-Tenant-ID 请求头切换）
+	s.mux.HandleFunc("GET /api/tenants", s.requirePermission(auth.PermConsoleRead, s.handleListTenants))
+
+	// 审计日志
+	s.mux.HandleFunc("GET /api/audit", s.requirePermission(auth.PermAuditRead, s.handleGetAudit))
+
+	// Protected Routes
+	s.mux.HandleFunc("GET /api/memories", s.requirePermission(auth.PermMemoryRead, s.handleListMemories))
+	s.mux.HandleFunc("POST /api/memories", s.requirePermission(auth.PermMemoryWrite, s.handleAddMemory))
+	s.mux.HandleFunc("PUT /api/memories/{id}", s.requirePermission(auth.PermMemoryWrite, s.handleUpdateMemory))
+	s.mux.HandleFunc("POST /api/retrieve", s.requirePermission(auth.PermMemoryRead, s.handleRetrieveMemory))
+	s.mux.HandleFunc("POST /api/recall", s.requirePermission(auth.PermMemoryRead, s.handleRecallMemory))
+	s.mux.HandleFunc("POST /api/context", s.requirePermission(auth.PermMemoryRead, s.handleAssembleContext))
+	s.mux.HandleFunc("DELETE /api/memories/{id}", s.requirePermission(auth.PermMemoryDelete, s.handleDeleteMemory))
 
 	// Admin Endpoints
-	s.mux.HandleFunc("GET /api/users", s.handleGetUsers)
-	s.mux.HandleFunc("GET /api/status", s.handleGetStatus)
+	s.mux.HandleFunc("GET /api/users", s.requirePermission(auth.PermConsoleRead, s.handleGetUsers))
+	s.mux.HandleFunc("PUT /api/users/{id}/language", s.requirePermission(auth.PermMemoryWrite, s.handleSetUserLanguage))
+	s.mux.HandleFunc("GET /api/status", s.requirePermission(auth.PermConsoleRead, s.handleGetStatus))
+	s.mux.HandleFunc("GET /api/prompts", s.requirePermission(auth.PermConsoleRead, s.handleGetPromptTemplates))
 
 	// Staging审核API
-	s.mux.HandleFunc("GET /api/staging", s.handleGetStagingEntries)
-	s.mux.HandleFunc("POST /api/staging/{id}/confirm", s.handleConfirmStaging)
-	s.mux.HandleFunc("POST /api/staging/{id}/reject", s.handleRejectStaging)
-	s.mux.HandleFunc("GET /api/staging/stats", s.handleGetStagingStats)
+	s.mux.HandleFunc("GET /api/staging", s.requirePermission(auth.PermConsoleRead, s.handleGetStagingEntries))
+	s.mux.HandleFunc("POST /api/staging/{id}/confirm", s.requirePermission(auth.PermStagingReview, s.handleConfirmStaging))
+	s.mux.HandleFunc("POST /api/staging/{id}/reject", s.requirePermission(auth.PermStagingReview, s.handleRejectStaging))
+	s.mux.HandleFunc("GET /api/staging/stats", s.requirePermission(auth.PermConsoleRead, s.handleGetStagingStats))
 
 	// 监控指标API
-	s.mux.HandleFunc("GET /api/metrics", s.handleGetMetrics)
-	s.mux.HandleFunc("GET /api/dashboard/metrics", s.handleGetDashboardMetrics)
+	s.mux.HandleFunc("GET /api/metrics", s.requirePermission(auth.PermConsoleRead, s.handleGetMetrics))
+	s.mux.HandleFunc("GET /api/dashboard/metrics", s.requirePermission(auth.PermConsoleRead, s.handleGetDashboardMetrics))
 
 	// 管理触发器API（手动触发漏斗流程）
-	s.mux.HandleFunc("POST /api/admin/trigger-judge", s.handleTriggerJudge)
-	s.mux.HandleFunc("POST /api/admin/trigger-promotion", s.handleTriggerPromotion)
-	s.mux.HandleFunc("POST /api/admin/trigger-decay", s.handleTriggerDecay)
-	s.mux.HandleFunc("POST /api/admin/trigger-dedup", s.handleTriggerDedup)
+	s.mux.HandleFunc("POST /api/admin/trigger-judge", s.requirePermission(auth.PermAdminTrigger, s.handleTriggerJudge))
+	s.mux.HandleFunc("POST /api/admin/trigger-promotion", s.requirePermission(auth.PermAdminTrigger, s.handleTriggerPromotion))
+	s.mux.HandleFunc("POST /api/admin/trigger-decay", s.requirePermission(auth.PermAdminTrigger, s.handleTriggerDecay))
+	s.mux.HandleFunc("POST /api/admin/trigger-dedup", s.requirePermission(auth.PermAdminTrigger, s.handleTriggerDedup))
 
 	// 告警API
-	s.mux.HandleFunc("GET /api/alerts", s.handleGetAlerts)
-	s.mux.HandleFunc("POST /api/alerts", s.handleCreateAlert)
-	s.mux.HandleFunc("DELETE /api/alerts/{id}", s.handleDeleteAlert)
+	s.mux.HandleFunc("GET /api/alerts", s.requirePermission(auth.PermConsoleRead, s.handleGetAlerts))
+	s.mux.HandleFunc("POST /api/alerts", s.requirePermission(auth.PermAlertManage, s.handleCreateAlert))
+	s.mux.HandleFunc("DELETE /api/alerts/{id}", s.requirePermission(auth.PermAlertManage, s.handleDeleteAlert))
 
 	// 告警管理API（新增）
-	s.mux.HandleFunc("GET /api/alerts/rules", s.handleGetAlertRules)
-	s.mux.HandleFunc("PUT /api/alerts/rules/{id}/toggle", s.handleToggleAlertRule)
-	s.mux.HandleFunc("PUT /api/alerts/rules/{id}/config", s.handleUpdateAlertRuleConfig)
-	s.mux.HandleFunc("PUT /api/alerts/rules/{id}/config-json", s.handleUpdateAlertRuleConfigJSON)
-	s.mux.HandleFunc("GET /api/alerts/stats", s.handleGetAlertStats)
-	s.mux.HandleFunc("GET /api/alerts/trend", s.handleGetAlertTrend)
-	s.mux.HandleFunc("GET /api/alerts/aggregated", s.handleGetAggregatedAlerts)
+	s.mux.HandleFunc("GET /api/alerts/rules", s.requirePermission(auth.PermConsoleRead, s.handleGetAlertRules))
+	s.mux.HandleFunc("PUT /api/alerts/rules/{id}/toggle", s.requirePermission(auth.PermAlertManage, s.handleToggleAlertRule))
+	s.mux.HandleFunc("PUT /api/alerts/rules/{id}/config", s.requirePermission(auth.PermAlertManage, s.handleUpdateAlertRuleConfig))
+	s.mux.HandleFunc("PUT /api/alerts/rules/{id}/config-json", s.requirePermission(auth.PermAlertManage, s.handleUpdateAlertRuleConfigJSON))
+	s.mux.HandleFunc("GET /api/alerts/stats", s.requirePermission(auth.PermConsoleRead, s.handleGetAlertStats))
+	s.mux.HandleFunc("GET /api/alerts/trend", s.requirePermission(auth.PermConsoleRead, s.handleGetAlertTrend))
+	s.mux.HandleFunc("GET /api/alerts/aggregated", s.requirePermission(auth.PermConsoleRead, s.handleGetAggregatedAlerts))
 
 	// Static Files (Frontend) - Must be last to avoid catching API routes if not specific
 	fs := http.FileServer(http.Dir("./frontend/dist"))
@@ -92,7 +120,7 @@ func (s *Server) handleUpdateMemory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.memory.Update(r.Context(), id, payload.Content); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to update: %v", err), memoryErrorStatus(err))
 		return
 	}
 
@@ -100,6 +128,14 @@ func (s *Server) handleUpdateMemory(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 }
 
+// memoryErrorStatus 记录不存在或属于其他租户时返回 404，其余按服务端错误处理
+func memoryErrorStatus(err error) int {
+	if errors.Is(err, memory.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 func (s *Server) handleGetUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := s.memory.GetUsers(r.Context())
 	if err != nil {
@@ -109,6 +145,32 @@ func (s *Server) handleGetUsers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
 }
 
+// handleSetUserLanguage 设置用户的 Prompt 语言偏好，language 为空表示恢复默认
+func (s *Server) handleSetUserLanguage(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Language) > 16 {
+		http.Error(w, "Invalid language", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.memory.SetUserLanguage(r.Context(), r.PathValue("id"), payload.Language); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set language: %v", err), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleGetPromptTemplates 列出当前生效的 Prompt 模板及版本
+func (s *Server) handleGetPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"templates": s.memory.ListPromptTemplates()})
+}
+
 func (s *Server) handleGetStatus(w http.ResponseWriter, r *http.Request) {
 	status := s.memory.GetSystemStatus(r.Context())
 	json.NewEncoder(w).Encode(status)
@@ -117,7 +179,7 @@ func (s *Server) handleGetStatus(w http.ResponseWriter, r *http.Request) {
 func (s *Server) Start(addr string) error {
 	server := &http.Server{
 		Addr:    addr,
-		Handler: s.mux,
+		Handler: s.requireAuth(s.mux),
 	}
 	logger.System("Starting Admin API", "addr", addr) // Changed from log.Printf
 	return server.ListenAndServe()
@@ -141,16 +203,22 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user, err := s.auth.Authenticate(creds.Username, creds.Password)
+	if errors.Is(err, auth.ErrUserDisabled) {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	// TODO: Issue JWT or Session. For now, just return OK + User info
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Login successful",
-		"user":    user,
-	})
+	session, err := s.auth.CreateSession(user)
+	if err != nil {
+		logger.Error("Failed to create session", err, "user", user.Username)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+	writeSession(w, session)
 }
 
 func (s *Server) handleListMemories(w http.ResponseWriter, r *http.Request) {
@@ -197,7 +265,7 @@ func (s *Server) handleDeleteMemory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.memory.Delete(r.Context(), id); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete memory: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to delete memory: %v", err), memoryErrorStatus(err))
 		return
 	}
 
@@ -211,13 +279,18 @@ func (s *Server) handleAddMemory(w http.ResponseWriter, r *http.Request) {
 		SessionID string `json:"session_id"`
 		Input     string `json:"input"`
 		Output    string `json:"output"`
+		Language  string `json:"language"` // 可选：判定该条记忆时使用的 Prompt 语言（zh / en）
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	if err := s.memory.Add(r.Context(), payload.UserID, payload.SessionID, payload.Input, payload.Output, nil); err != nil {
+	var metadata map[string]interface{}
+	if payload.Language != "" {
+		metadata = map[string]interface{}{"language": payload.Language}
+	}
+	if err := s.memory.Add(r.Context(), payload.UserID, payload.SessionID, payload.Input, payload.Output, metadata); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to add memory: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -243,3 +316,27 @@ func (s *Server) handleRetrieveMemory(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
 }
+
+// handleRecallMemory 增强召回：支持标签、类别、衰减分数与时间范围过滤
+func (s *Server) handleRecallMemory(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		UserID    string `json:"user_id"`
+		SessionID string `json:"session_id"`
+		types.RecallOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.memory.Recall(r.Context(), payload.UserID, payload.SessionID, payload.RecallOptions)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to recall: %v", err), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
@@ -31,7 +31,7 @@ func (s *Server) handleConfirmStaging(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.memory.ConfirmStagingEntry(r.Context(), entryID); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to confirm: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to confirm: %v", err), memoryErrorStatus(err))
 		return
 	}
 
@@ -50,7 +50,7 @@ func (s *Server) handleRejectStaging(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.memory.RejectStagingEntry(r.Context(), entryID); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to reject: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to reject: %v", err), memoryErrorStatus(err))
 		return
 	}
 
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"ai-memory/pkg/auth"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/types"
+	"encoding/json"
+	"net/http"
+	"slices"
+)
+
+// handleListTenants 可切换的租户列表（默认租户 + 出现过终端用户或绑定过 API Key 的租户）；
+// API Key 只能看到自己绑定的租户
+func (s *Server) handleListTenants(w http.ResponseWriter, r *http.Request) {
+	current := types.TenantFrom(r.Context())
+	user, _ := auth.UserFrom(r.Context())
+	if user.APIKeyID != 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"tenants": []string{current},
+			"current": current,
+		})
+		return
+	}
+
+	tenants := []string{types.DefaultTenant}
+	endUserTenants, err := s.memory.ListTenants(r.Context())
+	if err != nil {
+		logger.Error("Failed to list tenants", err)
+		http.Error(w, "Failed to list tenants", http.StatusInternalServerError)
+		return
+	}
+	tenants = append(tenants, endUserTenants...)
+
+	keys, err := s.auth.ListAPIKeys()
+	if err != nil {
+		logger.Error("Failed to list api keys", err)
+		http.Error(w, "Failed to list tenants", http.StatusInternalServerError)
+		return
+	}
+	for _, key := range keys {
+		tenants = append(tenants, key.TenantID)
+	}
+	tenants = append(tenants, current)
+
+	slices.Sort(tenants)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenants": slices.Compact(tenants),
+		"current": current,
+	})
+}
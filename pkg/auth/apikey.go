@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"ai-memory/pkg/types"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix API Key 明文前缀，鉴权中间件据此区分 API Key 与登录会话令牌
+const APIKeyPrefix = "amk_"
+
+// apiKeyDisplayLen 列表中展示的明文前缀长度，便于识别而不泄露密钥
+const apiKeyDisplayLen = 12
+
+// Scope API Key 授权范围
+type Scope string
+
+const (
+	ScopeMemoryRead  Scope = "memory:read"  // 查询/检索记忆
+	ScopeMemoryWrite Scope = "memory:write" // 写入/更新记忆
+	ScopeAdmin       Scope = "admin"        // 运维权限（不含账号与 API Key 管理）
+)
+
+// scopePermissions 授权范围对应的接口权限
+var scopePermissions = map[Scope][]Permission{
+	ScopeMemoryRead:  {PermMemoryRead},
+	ScopeMemoryWrite: {PermMemoryWrite},
+	ScopeAdmin:       rolePermissions[RoleOps],
+}
+
+// Scopes 全部授权范围
+func Scopes() []Scope {
+	return []Scope{ScopeMemoryRead, ScopeMemoryWrite, ScopeAdmin}
+}
+
+// Valid 是否为已定义的授权范围
+func (sc Scope) Valid() bool {
+	_, ok := scopePermissions[sc]
+	return ok
+}
+
+// APIKey 机器客户端凭证。数据库只保存密钥的 SHA-256，明文只在创建时返回一次。
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	TenantID   string     `json:"tenant_id"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKey 创建绑定到 tenantID（为空时使用默认租户）的 API Key，返回记录与密钥明文
+func (s *Service) CreateAPIKey(name string, scopes []Scope, tenantID string, createdBy int) (*APIKey, string, error) {
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+	if tenantID == "" {
+		tenantID = types.DefaultTenant
+	}
+	if !types.ValidTenantID(tenantID) {
+		return nil, "", fmt.Errorf("%w: %s", ErrInvalidTenant, tenantID)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, sc := range scopes {
+		if !sc.Valid() {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidScope, sc)
+		}
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := APIKeyPrefix + secret
+
+	result, err := s.db.Exec(
+		"INSERT INTO api_keys (name, key_prefix, key_hash, scopes, tenant_id, created_by) VALUES (?, ?, ?, ?, ?, ?)",
+		name, plaintext[:apiKeyDisplayLen], hashToken(plaintext), joinScopes(scopes), tenantID, createdBy,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+	id, _ := result.LastInsertId()
+
+	key, err := s.getAPIKey(int(id))
+	if err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+// ListAPIKeys 列出全部 API Key（含已吊销）
+func (s *Service) ListAPIKeys() ([]*APIKey, error) {
+	rows, err := s.db.Query(apiKeySelect + " ORDER BY k.id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey 吊销 API Key，立即生效（重复吊销保留首次吊销时间）
+func (s *Service) RevokeAPIKey(id int) (*APIKey, error) {
+	if _, err := s.db.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL", id); err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return s.getAPIKey(id)
+}
+
+// ValidateAPIKey 校验 API Key 并记录最近使用时间/来源 IP，返回以 API Key 身份表示的调用方（携带绑定的租户）
+func (s *Service) ValidateAPIKey(token, remoteIP string) (*User, error) {
+	if !strings.HasPrefix(token, APIKeyPrefix) {
+		return nil, ErrInvalidToken
+	}
+
+	var (
+		id       int
+		name     string
+		scopes   string
+		tenantID string
+	)
+	err := s.db.QueryRow(
+		"SELECT id, name, scopes, tenant_id FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL", hashToken(token),
+	).Scan(&id, &name, &scopes, &tenantID)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// 最近使用时间精确到分钟即可，避免每个请求都写库
+	_, err = s.db.Exec(`
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = ?
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at < DATE_SUB(NOW(), INTERVAL 1 MINUTE) OR last_used_ip <> ?)`,
+		remoteIP, id, remoteIP,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return &User{
+		Username:    "apikey:" + name,
+		Permissions: permissionsOf(splitScopes(scopes)),
+		APIKeyID:    id,
+		TenantID:    tenantID,
+	}, nil
+}
+
+const apiKeySelect = `
+	SELECT k.id, k.name, k.key_prefix, k.scopes, k.tenant_id, COALESCE(u.username, ''), k.created_at, k.last_used_at, COALESCE(k.last_used_ip, ''), k.revoked_at
+	FROM api_keys k LEFT JOIN users u ON u.id = k.created_by`
+
+func (s *Service) getAPIKey(id int) (*APIKey, error) {
+	return scanAPIKey(s.db.QueryRow(apiKeySelect+" WHERE k.id = ?", id))
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var (
+		key        APIKey
+		scopes     string
+		lastUsedAt sql.NullTime
+		revokedAt  sql.NullTime
+	)
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.TenantID, &key.CreatedBy, &key.CreatedAt, &lastUsedAt, &key.LastUsedIP, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	key.Scopes = splitScopes(scopes)
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+func joinScopes(scopes []Scope) string {
+	parts := make([]string, len(scopes))
+	for i, sc := range scopes {
+		parts[i] = string(sc)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitScopes(value string) []Scope {
+	var scopes []Scope
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			scopes = append(scopes, Scope(part))
+		}
+	}
+	return scopes
+}
+
+// permissionsOf 合并授权范围对应的权限（去重）
+func permissionsOf(scopes []Scope) []Permission {
+	seen := make(map[Permission]bool)
+	var perms []Permission
+	for _, sc := range scopes {
+		for _, perm := range scopePermissions[sc] {
+			if !seen[perm] {
+				seen[perm] = true
+				perms = append(perms, perm)
+			}
+		}
+	}
+	return perms
+}
@@ -4,36 +4,192 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 var ErrInvalidCredentials = errors.New("invalid credentials")
 
+// ErrUserDisabled 账号已停用
+var ErrUserDisabled = errors.New("user disabled")
+
+// ErrUserExists 用户名已存在
+var ErrUserExists = errors.New("username already exists")
+
+// ErrUserNotFound 账号不存在
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrLastAdmin 操作会导致没有可用的管理员
+var ErrLastAdmin = errors.New("at least one enabled admin is required")
+
+// ErrAPIKeyNotFound API Key 不存在
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// ErrInvalidScope 未知的 API Key 授权范围
+var ErrInvalidScope = errors.New("invalid scope")
+
+// ErrInvalidTenant 租户 ID 格式不合法
+var ErrInvalidTenant = errors.New("invalid tenant id")
+
+// ErrWeakPassword 新密码不满足要求
+var ErrWeakPassword = errors.New("password too weak")
+
+const minPasswordLength = 8
+
 type User struct {
-	ID           int    `json:"id"`
-	Username     string `json:"username"`
-	PasswordHash string `json:"-"`
+	ID                 int          `json:"id"`
+	Username           string       `json:"username"`
+	PasswordHash       string       `json:"-"`
+	MustChangePassword bool         `json:"must_change_password"`
+	Role               Role         `json:"role"`
+	Disabled           bool         `json:"disabled"`
+	Permissions        []Permission `json:"permissions"`
+	CreatedAt          time.Time    `json:"created_at"`
+	APIKeyID           int          `json:"api_key_id,omitempty"` // 非 0 表示通过 API Key 认证的机器客户端
+	TenantID           string       `json:"tenant_id,omitempty"`  // API Key 绑定的租户（登录账号不绑定租户）
 }
 
 type Service struct {
-	db *sql.DB
+	db         *sql.DB
+	accessTTL  time.Duration
+	refreshTTL time.Duration
 }
 
 func NewService(db *sql.DB) *Service {
-	return &Service{db: db}
+	return &Service{
+		db:         db,
+		accessTTL:  defaultAccessTTL,
+		refreshTTL: defaultRefreshTTL,
+	}
 }
 
 // CreateUser creates a new user.
-func (s *Service) CreateUser(username, password string) error {
+// 由管理员设置的初始密码在首次登录后必须修改。
+func (s *Service) CreateUser(username, password string, role Role) (*User, error) {
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if err := validatePassword(password); err != nil {
+		return nil, err
+	}
+
+	var exists int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if exists > 0 {
+		return nil, ErrUserExists
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	result, err := s.db.Exec("INSERT INTO users (username, password_hash, role, must_change_password) VALUES (?, ?, ?, TRUE)", username, hash, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert user: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	return s.GetUser(int(id))
+}
+
+// GetUser 按 ID 查询后台用户
+func (s *Service) GetUser(id int) (*User, error) {
+	var user User
+	err := s.db.QueryRow("SELECT id, username, must_change_password, role, disabled, created_at FROM users WHERE id = ?", id).Scan(
+		&user.ID, &user.Username, &user.MustChangePassword, &user.Role, &user.Disabled, &user.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	user.Permissions = user.Role.Permissions()
+	return &user, nil
+}
+
+// ListUsers 列出全部后台用户
+func (s *Service) ListUsers() ([]*User, error) {
+	rows, err := s.db.Query("SELECT id, username, must_change_password, role, disabled, created_at FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Username, &user.MustChangePassword, &user.Role, &user.Disabled, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		user.Permissions = user.Role.Permissions()
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+// SetUserRole 修改用户角色（不允许撤销最后一个可用管理员）
+func (s *Service) SetUserRole(id int, role Role) (*User, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == RoleAdmin && role != RoleAdmin && !user.Disabled {
+		if err := s.ensureOtherAdmin(id); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.db.Exec("UPDATE users SET role = ? WHERE id = ?", role, id); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	user.Role = role
+	user.Permissions = role.Permissions()
+	return user, nil
+}
+
+// SetUserDisabled 停用/启用用户；停用时同时注销其全部会话
+func (s *Service) SetUserDisabled(id int, disabled bool) (*User, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if disabled && user.Role == RoleAdmin && !user.Disabled {
+		if err := s.ensureOtherAdmin(id); err != nil {
+			return nil, err
+		}
 	}
 
-	_, err = s.db.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, hash)
+	if _, err := s.db.Exec("UPDATE users SET disabled = ? WHERE id = ?", disabled, id); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if disabled {
+		if err := s.RevokeUserSessions(id, ""); err != nil {
+			return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+	}
+	user.Disabled = disabled
+	return user, nil
+}
+
+// ensureOtherAdmin 确认除 id 外还有可用的管理员
+func (s *Service) ensureOtherAdmin(id int) error {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE role = ? AND disabled = FALSE AND id <> ?", RoleAdmin, id).Scan(&count)
 	if err != nil {
-		return fmt.Errorf("failed to insert user: %w", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	if count == 0 {
+		return ErrLastAdmin
 	}
 	return nil
 }
@@ -41,8 +197,8 @@ func (s *Service) CreateUser(username, password string) error {
 // Authenticate verifies username and password.
 func (s *Service) Authenticate(username, password string) (*User, error) {
 	var user User
-	err := s.db.QueryRow("SELECT id, username, password_hash FROM users WHERE username = ?", username).Scan(
-		&user.ID, &user.Username, &user.PasswordHash,
+	err := s.db.QueryRow("SELECT id, username, password_hash, must_change_password, role, disabled, created_at FROM users WHERE username = ?", username).Scan(
+		&user.ID, &user.Username, &user.PasswordHash, &user.MustChangePassword, &user.Role, &user.Disabled, &user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, ErrInvalidCredentials
@@ -54,6 +210,125 @@ func (s *Service) Authenticate(username, password string) (*User, error) {
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		return nil, ErrInvalidCredentials
 	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+	user.Permissions = user.Role.Permissions()
 
 	return &user, nil
 }
+
+// ChangePassword 校验旧密码后设置新密码，并清除"首次登录须修改密码"标记
+func (s *Service) ChangePassword(userID int, oldPassword, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	var hash string
+	err := s.db.QueryRow("SELECT password_hash FROM users WHERE id = ?", userID).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return ErrInvalidCredentials
+	}
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(oldPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+	if oldPassword == newPassword {
+		return fmt.Errorf("%w: new password must differ from the current one", ErrWeakPassword)
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if _, err := s.db.Exec("UPDATE users SET password_hash = ?, must_change_password = FALSE WHERE id = ?", newHash, userID); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+// EnsureBootstrapAdmin 用户表为空时创建初始管理员。
+// password 为空时随机生成（通过返回值告知调用方，仅此一次），并要求首次登录后修改密码。
+func (s *Service) EnsureBootstrapAdmin(username, password string) (created bool, generatedPassword string, err error) {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return false, "", fmt.Errorf("database error: %w", err)
+	}
+	if count > 0 {
+		return false, "", nil
+	}
+
+	mustChange := false
+	if password == "" {
+		if password, err = randomToken(12); err != nil {
+			return false, "", err
+		}
+		generatedPassword = password
+		mustChange = true
+	} else if err := validatePassword(password); err != nil {
+		return false, "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	if _, err := s.db.Exec("INSERT INTO users (username, password_hash, role, must_change_password) VALUES (?, ?, ?, ?)", username, hash, RoleAdmin, mustChange); err != nil {
+		return false, "", fmt.Errorf("failed to insert user: %w", err)
+	}
+	return true, generatedPassword, nil
+}
+
+// legacyDefaultPassword 旧版本 schema.sql / main.go 内置的管理员密码
+const legacyDefaultPassword = "admin123"
+
+// FlagLegacyDefaultPasswords 检查仍在使用旧版内置密码的账号，将其标记为必须修改密码并返回用户名。
+// 升级部署的 users 表非空，EnsureBootstrapAdmin 不会生效，因此每次启动都检查一次。
+func (s *Service) FlagLegacyDefaultPasswords() ([]string, error) {
+	rows, err := s.db.Query("SELECT id, username, password_hash, must_change_password FROM users")
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	type legacyUser struct {
+		id         int
+		username   string
+		mustChange bool
+	}
+	var matched []legacyUser
+	for rows.Next() {
+		var user legacyUser
+		var hash string
+		if err := rows.Scan(&user.id, &user.username, &hash, &user.mustChange); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(legacyDefaultPassword)) == nil {
+			matched = append(matched, user)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	usernames := make([]string, 0, len(matched))
+	for _, user := range matched {
+		if !user.mustChange {
+			if _, err := s.db.Exec("UPDATE users SET must_change_password = TRUE WHERE id = ?", user.id); err != nil {
+				return usernames, fmt.Errorf("failed to flag user %s: %w", user.username, err)
+			}
+		}
+		usernames = append(usernames, user.username)
+	}
+	return usernames, nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrWeakPassword, minPasswordLength)
+	}
+	return nil
+}
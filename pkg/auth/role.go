@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+)
+
+// Role 后台用户角色
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"   // 全部权限，含后台账号管理
+	RoleOps     Role = "ops"     // 运维：记忆维护、告警规则、手动触发后台任务
+	RoleSupport Role = "support" // 客服：查看数据、审核暂存区
+)
+
+// Permission 接口级权限
+type Permission string
+
+const (
+	PermConsoleRead   Permission = "console:read"   // 查看后台数据（状态、监控、告警、暂存区、终端用户等）
+	PermMemoryRead    Permission = "memory:read"    // 查询/检索记忆
+	PermMemoryWrite   Permission = "memory:write"   // 新增/编辑记忆、修改终端用户设置
+	PermMemoryDelete  Permission = "memory:delete"  // 删除长期记忆
+	PermStagingReview Permission = "staging:review" // 确认/拒绝暂存记忆
+	PermAlertManage   Permission = "alert:manage"   // 创建/删除告警、修改告警规则
+	PermAdminTrigger  Permission = "admin:trigger"  // 手动触发判定/晋升/衰减/去重
+	PermAccountManage Permission = "account:manage" // 管理后台账号与角色
+	PermAuditRead     Permission = "audit:read"     // 查看审计日志
+)
+
+// ErrInvalidRole 未知角色
+var ErrInvalidRole = errors.New("invalid role")
+
+// rolePermissions 角色权限表
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermConsoleRead, PermMemoryRead, PermMemoryWrite, PermMemoryDelete, PermStagingReview,
+		PermAlertManage, PermAdminTrigger, PermAccountManage, PermAuditRead,
+	},
+	RoleOps: {
+		PermConsoleRead, PermMemoryRead, PermMemoryWrite, PermMemoryDelete, PermStagingReview,
+		PermAlertManage, PermAdminTrigger, PermAuditRead,
+	},
+	RoleSupport: {
+		PermConsoleRead, PermMemoryRead, PermStagingReview,
+	},
+}
+
+// Roles 全部角色（按权限从高到低）
+func Roles() []Role {
+	return []Role{RoleAdmin, RoleOps, RoleSupport}
+}
+
+// Valid 是否为已定义的角色
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions 角色拥有的权限
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// Can 判断用户（或 API Key）是否拥有指定权限
+func (u *User) Can(perm Permission) bool {
+	return u != nil && slices.Contains(u.Permissions, perm)
+}
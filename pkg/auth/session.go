@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidToken 令牌不存在、已过期或已注销
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+const (
+	defaultAccessTTL  = time.Hour
+	defaultRefreshTTL = 7 * 24 * time.Hour
+)
+
+// Session 登录会话：访问令牌用于请求鉴权，刷新令牌用于换取新的令牌对。
+// 数据库只保存令牌的 SHA-256，令牌明文只在签发时返回一次。
+type Session struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             *User     `json:"user"`
+}
+
+// SetSessionTTL 设置访问令牌与刷新令牌的有效期（<= 0 时保持默认值）
+func (s *Service) SetSessionTTL(access, refresh time.Duration) {
+	if access > 0 {
+		s.accessTTL = access
+	}
+	if refresh > 0 {
+		s.refreshTTL = refresh
+	}
+}
+
+// CreateSession 为已通过认证的用户签发令牌对
+func (s *Service) CreateSession(user *User) (*Session, error) {
+	// 顺便清理刷新令牌已过期的会话
+	if _, err := s.db.Exec("DELETE FROM auth_sessions WHERE refresh_expires_at < NOW()"); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	access, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &Session{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		ExpiresAt:        now.Add(s.accessTTL),
+		RefreshExpiresAt: now.Add(s.refreshTTL),
+		User:             user,
+	}
+	// 过期时间由数据库计算，与查询时的 NOW() 使用同一时区
+	_, err = s.db.Exec(`
+		INSERT INTO auth_sessions (user_id, access_hash, refresh_hash, expires_at, refresh_expires_at)
+		VALUES (?, ?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND), DATE_ADD(NOW(), INTERVAL ? SECOND))`,
+		user.ID, hashToken(access), hashToken(refresh), int64(s.accessTTL.Seconds()), int64(s.refreshTTL.Seconds()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return session, nil
+}
+
+// ValidateToken 校验访问令牌并返回对应用户
+func (s *Service) ValidateToken(token string) (*User, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var user User
+	err := s.db.QueryRow(`
+		SELECT u.id, u.username, u.must_change_password, u.role, u.created_at
+		FROM auth_sessions s JOIN users u ON u.id = s.user_id
+		WHERE s.access_hash = ? AND s.expires_at > NOW() AND u.disabled = FALSE`, hashToken(token)).Scan(
+		&user.ID, &user.Username, &user.MustChangePassword, &user.Role, &user.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	user.Permissions = user.Role.Permissions()
+	return &user, nil
+}
+
+// RefreshSession 用刷新令牌换取新的令牌对（旧会话同时作废，刷新令牌只能使用一次）
+func (s *Service) RefreshSession(refreshToken string) (*Session, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var sessionID int64
+	var user User
+	err := s.db.QueryRow(`
+		SELECT s.id, u.id, u.username, u.must_change_password, u.role, u.created_at
+		FROM auth_sessions s JOIN users u ON u.id = s.user_id
+		WHERE s.refresh_hash = ? AND s.refresh_expires_at > NOW() AND u.disabled = FALSE`, hashToken(refreshToken)).Scan(
+		&sessionID, &user.ID, &user.Username, &user.MustChangePassword, &user.Role, &user.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// 并发刷新时只有一个请求能删除成功
+	result, err := s.db.Exec("DELETE FROM auth_sessions WHERE id = ?", sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrInvalidToken
+	}
+	user.Permissions = user.Role.Permissions()
+	return s.CreateSession(&user)
+}
+
+// RevokeSession 注销访问令牌对应的会话
+func (s *Service) RevokeSession(token string) error {
+	_, err := s.db.Exec("DELETE FROM auth_sessions WHERE access_hash = ?", hashToken(token))
+	return err
+}
+
+// RevokeUserSessions 注销用户的全部会话，keepToken 非空时保留该访问令牌所在的会话（如修改密码的当前会话）
+func (s *Service) RevokeUserSessions(userID int, keepToken string) error {
+	_, err := s.db.Exec("DELETE FROM auth_sessions WHERE user_id = ? AND access_hash <> ?", userID, hashToken(keepToken))
+	return err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken 生成 n 字节随机数的 URL 安全编码
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+type userKey struct{}
+
+// WithUser 将已认证用户写入 context
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom 读取 context 中的已认证用户
+func UserFrom(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userKey{}).(*User)
+	return user, ok
+}
@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"ai-memory/pkg/config"
+	"ai-memory/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// anthropicJSONSystemPrompt Messages API 没有原生 JSON 模式，通过 system 提示约束输出
+const anthropicJSONSystemPrompt = "Respond with a single valid JSON object only. Do not wrap it in markdown code fences or add any other text."
+
+const (
+	anthropicVersion        = "2023-06-01"
+	anthropicRequestTimeout = 2 * time.Minute
+	// defaultAnthropicMaxTokens Messages API 要求显式指定输出上限
+	defaultAnthropicMaxTokens = 4096
+)
+
+// AnthropicClient 基于 Anthropic Messages API 的 LLM 实现
+// Anthropic 不提供 Embedding 接口，向量化需通过 EMBEDDING_PROVIDER 指定其他提供商。
+type AnthropicClient struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	maxTokens  int
+	httpClient *http.Client
+}
+
+func NewAnthropicClient(cfg *config.Config) *AnthropicClient {
+	maxTokens := cfg.AnthropicMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+	return &AnthropicClient{
+		baseURL:    strings.TrimRight(cfg.AnthropicBaseURL, "/"),
+		apiKey:     cfg.AnthropicKey,
+		model:      cfg.AnthropicModel,
+		maxTokens:  maxTokens,
+		httpClient: &http.Client{Timeout: anthropicRequestTimeout},
+	}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	System      string             `json:"system,omitempty"`
+	Temperature *float32           `json:"temperature,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type anthropicErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// GenerateText via Anthropic Messages API
+func (c *AnthropicClient) GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error) {
+	options := ApplyOptions(c.model, opts)
+
+	req := anthropicRequest{
+		Model:       options.Model,
+		MaxTokens:   c.maxTokens,
+		Temperature: options.Temperature,
+		Messages:    []anthropicMessage{{Role: "user", Content: prompt}},
+	}
+	if options.MaxTokens > 0 {
+		req.MaxTokens = options.MaxTokens
+	}
+	if options.JSONMode {
+		req.System = anthropicJSONSystemPrompt
+	}
+
+	start := time.Now()
+	text, err := c.createMessage(ctx, req)
+
+	duration := time.Since(start)
+	logger.LLM(ctx, options.Model, "messages", duration, err)
+
+	return text, err
+}
+
+func (c *AnthropicClient) createMessage(ctx context.Context, payload anthropicRequest) (string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("anthropic request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read anthropic response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := string(data)
+		var apiErr anthropicErrorResponse
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			message = fmt.Sprintf("(%s) %s", apiErr.Error.Type, apiErr.Error.Message)
+		}
+		return "", newAPIError("anthropic", resp, message)
+	}
+
+	var result anthropicResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("failed to decode anthropic response: %w", err)
+	}
+
+	reportUsage(ctx, "anthropic", payload.Model, OperationChat, Usage{
+		PromptTokens:     result.Usage.InputTokens,
+		CompletionTokens: result.Usage.OutputTokens,
+	})
+
+	// 只拼接文本块（忽略 thinking / tool_use 等其他类型）
+	var sb strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("no text content returned (stop_reason: %s)", result.StopReason)
+	}
+	return sb.String(), nil
+}
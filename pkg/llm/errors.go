@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIError 提供商返回的非 2xx 响应，供重试与熔断判断是否为瞬时故障
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // 服务端 Retry-After 提示，0 表示未提供
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Temporary 是否为可重试的瞬时错误（限流、超时、服务端错误）
+func (e *APIError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout, http.StatusConflict:
+		return true
+	}
+	// 529: Anthropic overloaded
+	return e.StatusCode >= 500
+}
+
+// newAPIError 根据 HTTP 响应构造 APIError
+func newAPIError(provider string, resp *http.Response, message string) *APIError {
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(message),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter 解析 Retry-After（秒数或 HTTP 日期）
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// IsRetryable 判断错误是否值得重试；调用方取消或超时不重试
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+
+	// 网络层错误（连接被拒绝、重置、超时等）
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// RetryAfterOf 提取错误中的 Retry-After 提示
+func RetryAfterOf(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// retryAfterKey 用于在请求 context 中传递 Retry-After 捕获器
+type retryAfterKey struct{}
+
+type retryAfterHolder struct {
+	mu    sync.Mutex
+	value time.Duration
+}
+
+// withRetryAfterCapture 为第三方 SDK 发起的请求捕获 Retry-After 响应头
+// （go-openai 的错误类型不包含响应头）
+func withRetryAfterCapture(ctx context.Context) (context.Context, *retryAfterHolder) {
+	holder := &retryAfterHolder{}
+	return context.WithValue(ctx, retryAfterKey{}, holder), holder
+}
+
+func (h *retryAfterHolder) get() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.value
+}
+
+// retryAfterTransport 读取响应的 Retry-After 头并写入请求 context 中的捕获器
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < 400 {
+		return resp, err
+	}
+	if holder, ok := req.Context().Value(retryAfterKey{}).(*retryAfterHolder); ok {
+		if d := parseRetryAfter(resp.Header.Get("Retry-After")); d > 0 {
+			holder.mu.Lock()
+			holder.value = d
+			holder.mu.Unlock()
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"ai-memory/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDimensionMismatch 向量维度与向量库不一致（不同维度的向量不能写入同一集合）
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// NamedLLM 带名称的 LLM（用于日志与错误信息）
+type NamedLLM struct {
+	Name string
+	LLM  LLM
+}
+
+// NamedEmbedder 带名称的 Embedder
+type NamedEmbedder struct {
+	Name     string
+	Embedder Embedder
+}
+
+// FallbackLLM 按顺序尝试多个提供商，前一个失败（含熔断）时切换到下一个
+// 备用提供商忽略调用方指定的模型名，使用各自的默认模型。
+type FallbackLLM struct {
+	providers []NamedLLM
+}
+
+func NewFallbackLLM(providers ...NamedLLM) *FallbackLLM {
+	return &FallbackLLM{providers: providers}
+}
+
+func (f *FallbackLLM) GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error) {
+	var errs []string
+	for i, p := range f.providers {
+		callOpts := opts
+		if i > 0 {
+			callOpts = append(append([]Option{}, opts...), withDefaultModel())
+		}
+
+		text, err := p.LLM.GenerateText(ctx, prompt, callOpts...)
+		if err == nil {
+			if i > 0 {
+				logger.System("⚠️ LLM served by fallback provider", "provider", p.Name)
+			}
+			return text, nil
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", p.Name, err))
+	}
+	return "", fmt.Errorf("all llm providers failed: %s", strings.Join(errs, "; "))
+}
+
+// FallbackEmbedder 按顺序尝试多个向量化提供商
+// 所有提供商的输出维度必须与向量库一致：启动时通过 VerifyDimensions 探测，
+// 调用时也会校验返回向量的维度，不一致的结果视为失败，绝不写入混合维度的向量。
+type FallbackEmbedder struct {
+	dimension int
+	providers []NamedEmbedder
+}
+
+// NewFallbackEmbedder dimension 为向量库维度，0 表示由 VerifyDimensions 按主提供商探测
+func NewFallbackEmbedder(dimension int, providers ...NamedEmbedder) *FallbackEmbedder {
+	return &FallbackEmbedder{dimension: dimension, providers: providers}
+}
+
+// Dimension 返回向量维度（探测前可能为 0）
+func (f *FallbackEmbedder) Dimension() int {
+	return f.dimension
+}
+
+// VerifyDimensions 探测各提供商的输出维度
+// 主提供商维度不一致时返回错误；备用提供商维度不一致时被禁用；探测失败（服务暂不可用）的保留，由调用时校验兜底。
+func (f *FallbackEmbedder) VerifyDimensions(ctx context.Context) error {
+	var kept []NamedEmbedder
+	for i, p := range f.providers {
+		vector, err := p.Embedder.EmbedQuery(ctx, "dimension probe")
+		if err != nil {
+			if i == 0 && f.dimension <= 0 {
+				return fmt.Errorf("无法探测主向量化提供商 %s 的维度，请配置 EMBEDDING_DIMENSION: %w", p.Name, err)
+			}
+			logger.Error("向量维度探测失败，调用时再校验", err)
+			kept = append(kept, p)
+			continue
+		}
+
+		if f.dimension <= 0 {
+			f.dimension = len(vector)
+			logger.System("Embedding dimension detected", "provider", p.Name, "dimension", f.dimension)
+		}
+		if len(vector) != f.dimension {
+			if i == 0 {
+				return fmt.Errorf("%w: 主提供商 %s 输出 %d 维，向量库为 %d 维", ErrDimensionMismatch, p.Name, len(vector), f.dimension)
+			}
+			logger.Error("禁用维度不兼容的备用向量化提供商",
+				fmt.Errorf("%w: %s 输出 %d 维，向量库为 %d 维", ErrDimensionMismatch, p.Name, len(vector), f.dimension))
+			continue
+		}
+		kept = append(kept, p)
+	}
+	f.providers = kept
+	return nil
+}
+
+func (f *FallbackEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	var errs []string
+	for i, p := range f.providers {
+		vector, err := p.Embedder.EmbedQuery(ctx, text)
+		if err == nil {
+			err = f.checkDimension(p.Name, vector)
+		}
+		if err == nil {
+			if i > 0 {
+				logger.System("⚠️ Embedding served by fallback provider", "provider", p.Name)
+			}
+			return vector, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", p.Name, err))
+	}
+	return nil, fmt.Errorf("all embedding providers failed: %s", strings.Join(errs, "; "))
+}
+
+func (f *FallbackEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	var errs []string
+	for i, p := range f.providers {
+		vectors, err := p.Embedder.EmbedDocuments(ctx, texts)
+		for j := 0; err == nil && j < len(vectors); j++ {
+			err = f.checkDimension(p.Name, vectors[j])
+		}
+		if err == nil {
+			if i > 0 {
+				logger.System("⚠️ Embedding served by fallback provider", "provider", p.Name)
+			}
+			return vectors, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", p.Name, err))
+	}
+	return nil, fmt.Errorf("all embedding providers failed: %s", strings.Join(errs, "; "))
+}
+
+func (f *FallbackEmbedder) checkDimension(name string, vector []float32) error {
+	if f.dimension > 0 && len(vector) != f.dimension {
+		return fmt.Errorf("%w: %s 输出 %d 维，向量库为 %d 维", ErrDimensionMismatch, name, len(vector), f.dimension)
+	}
+	return nil
+}
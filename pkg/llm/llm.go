@@ -3,6 +3,68 @@ package llm
 import "context"
 
 // LLM defines the interface for Large Language Model text generation.
+// opts 为单次调用参数，未指定时使用客户端的默认模型与服务端默认参数。
 type LLM interface {
-	GenerateText(ctx context.Context, prompt string) (string, error)
+	GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error)
+}
+
+// Options 单次调用参数
+type Options struct {
+	Model       string   // 为空时使用客户端默认模型
+	Temperature *float32 // nil 表示使用服务端默认值
+	MaxTokens   int      // 0 表示不限制（或使用客户端默认值）
+	JSONMode    bool     // 要求模型输出单个 JSON 对象
+}
+
+// Option 单次调用参数设置函数
+type Option func(*Options)
+
+// WithModel 指定本次调用使用的模型，空字符串表示沿用默认模型
+func WithModel(model string) Option {
+	return func(o *Options) {
+		if model != "" {
+			o.Model = model
+		}
+	}
+}
+
+// WithTemperature 指定采样温度
+func WithTemperature(temperature float32) Option {
+	return func(o *Options) {
+		o.Temperature = &temperature
+	}
+}
+
+// WithMaxTokens 指定输出 token 上限
+func WithMaxTokens(maxTokens int) Option {
+	return func(o *Options) {
+		o.MaxTokens = maxTokens
+	}
+}
+
+// WithJSONMode 要求输出 JSON 对象（提供商支持时启用原生 JSON 模式）
+// 注意：OpenAI 的 JSON 模式只允许顶层为对象，返回数组的 Prompt 不要启用。
+func WithJSONMode() Option {
+	return func(o *Options) {
+		o.JSONMode = true
+	}
+}
+
+// withDefaultModel 清除已指定的模型，使用客户端默认模型（备用提供商不识别主提供商的模型名）
+func withDefaultModel() Option {
+	return func(o *Options) {
+		o.Model = ""
+	}
+}
+
+// ApplyOptions 合并调用参数，defaultModel 在未指定模型时生效
+func ApplyOptions(defaultModel string, opts []Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Model == "" {
+		o.Model = defaultModel
+	}
+	return o
 }
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"ai-memory/pkg/config"
+	"ai-memory/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ollamaRequestTimeout 本地模型推理较慢，超时放宽
+const ollamaRequestTimeout = 5 * time.Minute
+
+// OllamaClient 基于 Ollama HTTP API 的本地模型客户端，同时实现 LLM 与 Embedder
+// 使用 /api/chat（非流式）生成文本，/api/embed 生成向量，可完全离线运行判定、摘要与合并流程。
+type OllamaClient struct {
+	baseURL        string
+	model          string
+	embeddingModel string
+	httpClient     *http.Client
+}
+
+func NewOllamaClient(cfg *config.Config) *OllamaClient {
+	return &OllamaClient{
+		baseURL:        strings.TrimRight(cfg.OllamaBaseURL, "/"),
+		model:          cfg.OllamaModel,
+		embeddingModel: cfg.OllamaEmbeddingModel,
+		httpClient:     &http.Client{Timeout: ollamaRequestTimeout},
+	}
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   string                 `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+// GenerateText via Ollama /api/chat
+func (c *OllamaClient) GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error) {
+	options := ApplyOptions(c.model, opts)
+
+	req := ollamaChatRequest{
+		Model:    options.Model,
+		Messages: []ollamaMessage{{Role: "user", Content: prompt}},
+		Stream:   false,
+	}
+	if options.JSONMode {
+		req.Format = "json"
+	}
+	if options.Temperature != nil || options.MaxTokens > 0 {
+		req.Options = make(map[string]interface{})
+		if options.Temperature != nil {
+			req.Options["temperature"] = *options.Temperature
+		}
+		if options.MaxTokens > 0 {
+			req.Options["num_predict"] = options.MaxTokens
+		}
+	}
+
+	start := time.Now()
+	var resp ollamaChatResponse
+	err := c.post(ctx, "/api/chat", req, &resp)
+
+	duration := time.Since(start)
+	logger.LLM(ctx, options.Model, "chat_completion", duration, err)
+
+	if err != nil {
+		return "", err
+	}
+
+	reportUsage(ctx, "ollama", options.Model, OperationChat, Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+	})
+	return resp.Message.Content, nil
+}
+
+// EmbedQuery generates embedding for a single string
+func (c *OllamaClient) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	start := time.Now()
+	embeddings, err := c.embed(ctx, []string{text})
+
+	duration := time.Since(start)
+	logger.LLM(ctx, c.embeddingModel, "embedding_query", duration, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedDocuments generates embeddings for multiple strings
+func (c *OllamaClient) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+	return c.embed(ctx, texts)
+}
+
+func (c *OllamaClient) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp ollamaEmbedResponse
+	if err := c.post(ctx, "/api/embed", ollamaEmbedRequest{Model: c.embeddingModel, Input: texts}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(resp.Embeddings), len(texts))
+	}
+
+	reportUsage(ctx, "ollama", c.embeddingModel, OperationEmbed, Usage{EmbeddingTokens: resp.PromptEvalCount})
+	return resp.Embeddings, nil
+}
+
+// post 发送 JSON 请求并解析响应，非 2xx 状态码时返回包含响应体的错误
+func (c *OllamaClient) post(ctx context.Context, path string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read ollama response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError("ollama", resp, fmt.Sprintf("%s: %s", path, data))
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode ollama response: %w", err)
+	}
+	return nil
+}
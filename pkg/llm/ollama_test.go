@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"ai-memory/pkg/config"
+	"ai-memory/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+// captureUsage 收集测试期间上报的用量
+type captureUsage struct {
+	mu     sync.Mutex
+	events []UsageEvent
+}
+
+func (c *captureUsage) RecordUsage(event UsageEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+func newTestOllama(t *testing.T, handler http.HandlerFunc) *OllamaClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewOllamaClient(&config.Config{
+		OllamaBaseURL:        srv.URL + "/",
+		OllamaModel:          "qwen2.5:7b",
+		OllamaEmbeddingModel: "bge-m3",
+	})
+}
+
+func TestOllamaGenerateText(t *testing.T) {
+	usage := &captureUsage{}
+	SetUsageRecorder(usage)
+	t.Cleanup(func() { SetUsageRecorder(nil) })
+
+	var got ollamaChatRequest
+	client := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"message":{"role":"assistant","content":"{\"ok\":true}"},"prompt_eval_count":12,"eval_count":5}`))
+	})
+
+	text, err := client.GenerateText(context.Background(), "hello",
+		WithModel("llama3.1:8b"), WithJSONMode(), WithTemperature(0.2), WithMaxTokens(64))
+	if err != nil {
+		t.Fatalf("GenerateText: %v", err)
+	}
+	if text != `{"ok":true}` {
+		t.Errorf("text = %q", text)
+	}
+
+	if got.Model != "llama3.1:8b" || got.Stream || got.Format != "json" {
+		t.Errorf("request = %+v", got)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Role != "user" || got.Messages[0].Content != "hello" {
+		t.Errorf("messages = %+v", got.Messages)
+	}
+	if got.Options["num_predict"] != float64(64) {
+		t.Errorf("options = %v", got.Options)
+	}
+
+	if len(usage.events) != 1 {
+		t.Fatalf("usage events = %d, want 1", len(usage.events))
+	}
+	if e := usage.events[0]; e.Provider != "ollama" || e.Operation != OperationChat || e.Usage.PromptTokens != 12 || e.Usage.CompletionTokens != 5 {
+		t.Errorf("usage = %+v", e)
+	}
+}
+
+func TestOllamaEmbed(t *testing.T) {
+	var got ollamaEmbedRequest
+	client := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		vectors := make([][]float32, len(got.Input))
+		for i := range vectors {
+			vectors[i] = []float32{float32(i), 0.5, 1}
+		}
+		json.NewEncoder(w).Encode(ollamaEmbedResponse{Embeddings: vectors, PromptEvalCount: 7})
+	})
+
+	vector, err := client.EmbedQuery(context.Background(), "query")
+	if err != nil {
+		t.Fatalf("EmbedQuery: %v", err)
+	}
+	if len(vector) != 3 || got.Model != "bge-m3" || len(got.Input) != 1 || got.Input[0] != "query" {
+		t.Errorf("vector = %v, request = %+v", vector, got)
+	}
+
+	vectors, err := client.EmbedDocuments(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("EmbedDocuments: %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+
+	empty, err := client.EmbedDocuments(context.Background(), nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("EmbedDocuments(nil) = %v, %v", empty, err)
+	}
+}
+
+func TestOllamaNon200(t *testing.T) {
+	client := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		http.Error(w, `{"error":"model is loading"}`, http.StatusServiceUnavailable)
+	})
+
+	_, err := client.GenerateText(context.Background(), "hello")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.Provider != "ollama" || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.RetryAfter.Seconds() != 2 {
+		t.Errorf("apiErr = %+v", apiErr)
+	}
+	if !strings.Contains(apiErr.Message, "model is loading") {
+		t.Errorf("message = %q", apiErr.Message)
+	}
+	if !IsRetryable(err) {
+		t.Error("503 should be retryable")
+	}
+
+	client = newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+	})
+	_, err = client.EmbedQuery(context.Background(), "query")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("err = %v, want 404 APIError", err)
+	}
+	if IsRetryable(err) {
+		t.Error("404 should not be retryable")
+	}
+}
+
+func TestOllamaMalformedBody(t *testing.T) {
+	client := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": {"content": `))
+	})
+
+	if _, err := client.GenerateText(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), "failed to decode ollama response") {
+		t.Errorf("GenerateText err = %v", err)
+	}
+	if _, err := client.EmbedQuery(context.Background(), "query"); err == nil || !strings.Contains(err.Error(), "failed to decode ollama response") {
+		t.Errorf("EmbedQuery err = %v", err)
+	}
+}
+
+func TestOllamaEmbeddingDimensionMismatch(t *testing.T) {
+	dimension := 4
+	client := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		vectors := make([][]float32, len(req.Input))
+		for i := range vectors {
+			vectors[i] = make([]float32, dimension)
+		}
+		json.NewEncoder(w).Encode(ollamaEmbedResponse{Embeddings: vectors})
+	})
+
+	// 向量库为 3 维，Ollama 返回 4 维：主提供商维度不一致时启动探测失败
+	embedder := NewFallbackEmbedder(3, NamedEmbedder{Name: "ollama", Embedder: client})
+	if err := embedder.VerifyDimensions(context.Background()); !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatalf("VerifyDimensions err = %v, want ErrDimensionMismatch", err)
+	}
+	if _, err := embedder.EmbedDocuments(context.Background(), []string{"a"}); err == nil || !strings.Contains(err.Error(), ErrDimensionMismatch.Error()) {
+		t.Errorf("EmbedDocuments err = %v, want dimension mismatch", err)
+	}
+
+	// 未配置维度时按主提供商探测
+	detected := NewFallbackEmbedder(0, NamedEmbedder{Name: "ollama", Embedder: client})
+	if err := detected.VerifyDimensions(context.Background()); err != nil || detected.Dimension() != dimension {
+		t.Errorf("detected dimension = %d, err = %v", detected.Dimension(), err)
+	}
+}
+
+func TestOllamaEmbeddingCountMismatch(t *testing.T) {
+	client := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"embeddings":[[0.1,0.2]]}`))
+	})
+
+	if _, err := client.EmbedDocuments(context.Background(), []string{"a", "b"}); err == nil || !strings.Contains(err.Error(), "1 embeddings for 2 inputs") {
+		t.Errorf("err = %v", err)
+	}
+}
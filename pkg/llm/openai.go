@@ -4,7 +4,10 @@ import (
 	"ai-memory/pkg/config"
 	"ai-memory/pkg/logger"
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"net/http"
 	"strings"
 	"time"
 
@@ -22,6 +25,7 @@ func NewOpenAIClient(cfg *config.Config) *OpenAIClient {
 	if cfg.OpenAIBaseURL != "" {
 		openaiConfig.BaseURL = cfg.OpenAIBaseURL
 	}
+	openaiConfig.HTTPClient = &http.Client{Transport: &retryAfterTransport{base: http.DefaultTransport}}
 	// Fallback if empty, though config has default
 	embModel := cfg.OpenAIEmbeddingModel
 	if embModel == "" {
@@ -36,23 +40,37 @@ func NewOpenAIClient(cfg *config.Config) *OpenAIClient {
 }
 
 // GenerateText via OpenAI Chat Completion
-func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string, error) {
-	start := time.Now()
-	resp, err := c.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: c.model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
+func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error) {
+	options := ApplyOptions(c.model, opts)
+
+	req := openai.ChatCompletionRequest{
+		Model: options.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: prompt,
 			},
 		},
-	)
+		MaxTokens: options.MaxTokens,
+	}
+	if options.Temperature != nil {
+		req.Temperature = *options.Temperature
+		if req.Temperature == 0 {
+			// go-openai 会省略零值字段，用最小正数表示"确定性输出"
+			req.Temperature = math.SmallestNonzeroFloat32
+		}
+	}
+	if options.JSONMode {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+
+	start := time.Now()
+	callCtx, retryAfter := withRetryAfterCapture(ctx)
+	resp, err := c.client.CreateChatCompletion(callCtx, req)
+	err = wrapOpenAIError(err, retryAfter)
 
 	duration := time.Since(start)
-	logger.LLM(ctx, c.model, "chat_completion", duration, err)
+	logger.LLM(ctx, options.Model, "chat_completion", duration, err)
 
 	if err != nil {
 		return "", err
@@ -62,6 +80,11 @@ func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string,
 		return "", fmt.Errorf("no choices returned")
 	}
 
+	reportUsage(ctx, "openai", options.Model, OperationChat, Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	})
+
 	return resp.Choices[0].Message.Content, nil
 }
 
@@ -71,13 +94,15 @@ func (c *OpenAIClient) EmbedQuery(ctx context.Context, text string) ([]float32,
 	// Normalize newlines as per OpenAI recommendation for some models, though less critical for ada-002
 	text = strings.ReplaceAll(text, "\n", " ")
 
+	callCtx, retryAfter := withRetryAfterCapture(ctx)
 	resp, err := c.client.CreateEmbeddings(
-		ctx,
+		callCtx,
 		openai.EmbeddingRequest{
 			Input: []string{text},
 			Model: openai.EmbeddingModel(c.embeddingModel),
 		},
 	)
+	err = wrapOpenAIError(err, retryAfter)
 
 	duration := time.Since(start)
 	logger.LLM(ctx, c.embeddingModel, "embedding_query", duration, err)
@@ -90,6 +115,8 @@ func (c *OpenAIClient) EmbedQuery(ctx context.Context, text string) ([]float32,
 		return nil, fmt.Errorf("no embeddings returned")
 	}
 
+	reportUsage(ctx, "openai", c.embeddingModel, OperationEmbed, Usage{EmbeddingTokens: resp.Usage.PromptTokens})
+
 	return resp.Data[0].Embedding, nil
 }
 
@@ -100,18 +127,22 @@ func (c *OpenAIClient) EmbedDocuments(ctx context.Context, texts []string) ([][]
 		texts[i] = strings.ReplaceAll(texts[i], "\n", " ")
 	}
 
+	callCtx, retryAfter := withRetryAfterCapture(ctx)
 	resp, err := c.client.CreateEmbeddings(
-		ctx,
+		callCtx,
 		openai.EmbeddingRequest{
 			Input: texts,
 			Model: openai.EmbeddingModel(c.embeddingModel),
 		},
 	)
+	err = wrapOpenAIError(err, retryAfter)
 
 	if err != nil {
 		return nil, err
 	}
 
+	reportUsage(ctx, "openai", c.embeddingModel, OperationEmbed, Usage{EmbeddingTokens: resp.Usage.PromptTokens})
+
 	results := make([][]float32, len(resp.Data))
 	for i, data := range resp.Data {
 		results[i] = data.Embedding
@@ -119,3 +150,20 @@ func (c *OpenAIClient) EmbedDocuments(ctx context.Context, texts []string) ([][]
 
 	return results, nil
 }
+
+// wrapOpenAIError 将 go-openai 的 HTTP 错误转换为 APIError，附带捕获到的 Retry-After
+func wrapOpenAIError(err error, retryAfter *retryAfterHolder) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode > 0 {
+		return &APIError{Provider: "openai", StatusCode: apiErr.HTTPStatusCode, Message: apiErr.Message, RetryAfter: retryAfter.get()}
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode > 0 {
+		return &APIError{Provider: "openai", StatusCode: reqErr.HTTPStatusCode, Message: reqErr.Error(), RetryAfter: retryAfter.get()}
+	}
+	return err
+}
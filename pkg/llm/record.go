@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"ai-memory/pkg/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RecordingLLM 透传调用到真实客户端，并把成功的 Prompt→响应 / 文本→向量 追加写入 JSONL 录制文件，
+// 录制结果可由 ReplayLLM 回放。embedder 为 nil 时向量化调用直接返回错误。
+type RecordingLLM struct {
+	llm      LLM
+	embedder Embedder
+
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewRecordingLLM 打开（追加）录制文件
+func NewRecordingLLM(path string, llm LLM, embedder Embedder) (*RecordingLLM, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(file)
+	enc.SetEscapeHTML(false)
+	return &RecordingLLM{
+		llm:      llm,
+		embedder: embedder,
+		file:     file,
+		enc:      enc,
+	}, nil
+}
+
+// GenerateText 调用真实模型并录制响应
+func (r *RecordingLLM) GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error) {
+	if r.llm == nil {
+		return "", fmt.Errorf("recording llm: no llm configured")
+	}
+	response, err := r.llm.GenerateText(ctx, prompt, opts...)
+	if err != nil {
+		return "", err
+	}
+	r.write(Fixture{Kind: FixtureKindGenerate, Model: ApplyOptions("", opts).Model, Prompt: prompt, Response: response})
+	return response, nil
+}
+
+// EmbedQuery 调用真实向量化并录制结果
+func (r *RecordingLLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	if r.embedder == nil {
+		return nil, fmt.Errorf("recording llm: no embedder configured")
+	}
+	vector, err := r.embedder.EmbedQuery(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	r.write(Fixture{Kind: FixtureKindEmbed, Prompt: text, Embedding: vector})
+	return vector, nil
+}
+
+// EmbedDocuments 调用真实向量化并逐条录制结果
+func (r *RecordingLLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	if r.embedder == nil {
+		return nil, fmt.Errorf("recording llm: no embedder configured")
+	}
+	vectors, err := r.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	for i, vector := range vectors {
+		if i < len(texts) {
+			r.write(Fixture{Kind: FixtureKindEmbed, Prompt: texts[i], Embedding: vector})
+		}
+	}
+	return vectors, nil
+}
+
+func (r *RecordingLLM) write(fixture Fixture) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// 录制失败不影响调用本身，回放时会以未命中的形式暴露
+	if err := r.enc.Encode(fixture); err != nil {
+		logger.Error("写入录制文件失败", err, "kind", fixture.Kind)
+	}
+}
+
+// Close 关闭录制文件
+func (r *RecordingLLM) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
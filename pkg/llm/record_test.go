@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// countingLLM 每次调用返回带序号的响应，便于验证同一 Prompt 多次录制的回放顺序
+type countingLLM struct {
+	calls int
+}
+
+func (c *countingLLM) GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error) {
+	c.calls++
+	return fmt.Sprintf("%s#%d", prompt, c.calls), nil
+}
+
+func (c *countingLLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return []float32{float32(len(text)), 1, 0}, nil
+}
+
+func (c *countingLLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = c.EmbedQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+func TestRecordReplayRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fixtures.jsonl")
+	client := &countingLLM{}
+
+	recorder, err := NewRecordingLLM(path, client, client)
+	if err != nil {
+		t.Fatalf("NewRecordingLLM: %v", err)
+	}
+	for _, prompt := range []string{"judge", "judge", "summarize"} {
+		if _, err := recorder.GenerateText(ctx, prompt, WithModel("gpt-4o-mini")); err != nil {
+			t.Fatalf("GenerateText: %v", err)
+		}
+	}
+	if _, err := recorder.EmbedQuery(ctx, "咖啡"); err != nil {
+		t.Fatalf("EmbedQuery: %v", err)
+	}
+	if _, err := recorder.EmbedDocuments(ctx, []string{"a", "bb"}); err != nil {
+		t.Fatalf("EmbedDocuments: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	if len(fixtures) != 6 {
+		t.Fatalf("fixtures = %d, want 6", len(fixtures))
+	}
+	if f := fixtures[0]; f.Kind != FixtureKindGenerate || f.Model != "gpt-4o-mini" || f.Prompt != "judge" || f.Response != "judge#1" {
+		t.Errorf("fixtures[0] = %+v", f)
+	}
+	if f := fixtures[3]; f.Kind != FixtureKindEmbed || f.Prompt != "咖啡" || len(f.Embedding) != 3 {
+		t.Errorf("fixtures[3] = %+v", f)
+	}
+
+	replay, err := LoadReplayLLM(path)
+	if err != nil {
+		t.Fatalf("LoadReplayLLM: %v", err)
+	}
+
+	// 同一 Prompt 按录制顺序返回，用完后重复最后一条；回放不区分模型
+	for _, want := range []string{"judge#1", "judge#2", "judge#2"} {
+		if got, err := replay.GenerateText(ctx, "judge", WithModel("other-model")); err != nil || got != want {
+			t.Errorf("GenerateText(judge) = %q, %v, want %q", got, err, want)
+		}
+	}
+	if got, _ := replay.GenerateText(ctx, "summarize"); got != "summarize#3" {
+		t.Errorf("GenerateText(summarize) = %q", got)
+	}
+
+	vector, err := replay.EmbedQuery(ctx, "咖啡")
+	if err != nil || len(vector) != 3 || vector[0] != float32(len("咖啡")) {
+		t.Errorf("EmbedQuery = %v, %v", vector, err)
+	}
+	vector[0] = -1
+	if again, _ := replay.EmbedQuery(ctx, "咖啡"); again[0] == -1 {
+		t.Error("EmbedQuery should return a copy of the recorded vector")
+	}
+	vectors, err := replay.EmbedDocuments(ctx, []string{"bb", "a"})
+	if err != nil || len(vectors) != 2 || vectors[0][0] != 2 || vectors[1][0] != 1 {
+		t.Errorf("EmbedDocuments = %v, %v", vectors, err)
+	}
+	if replay.Dimension() != 3 {
+		t.Errorf("Dimension = %d, want 3", replay.Dimension())
+	}
+	if replay.Misses() != 0 {
+		t.Fatalf("Misses = %d before unrecorded calls", replay.Misses())
+	}
+
+	if _, err := replay.GenerateText(ctx, "unrecorded"); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("GenerateText(unrecorded) err = %v, want ErrFixtureNotFound", err)
+	}
+	if _, err := replay.EmbedDocuments(ctx, []string{"a", "missing"}); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("EmbedDocuments(missing) err = %v, want ErrFixtureNotFound", err)
+	}
+	if replay.Misses() != 2 {
+		t.Errorf("Misses = %d, want 2", replay.Misses())
+	}
+}
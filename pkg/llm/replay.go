@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 录制文件中的调用类型
+const (
+	FixtureKindGenerate = "generate"
+	FixtureKindEmbed    = "embed"
+)
+
+// ErrFixtureNotFound 回放时没有找到对应 Prompt 的录制结果
+var ErrFixtureNotFound = errors.New("no recorded response for prompt")
+
+// Fixture 一次录制的模型调用（JSONL 文件每行一条）
+type Fixture struct {
+	Kind      string    `json:"kind"`            // generate / embed
+	Model     string    `json:"model,omitempty"` // 仅供查阅，回放时不参与匹配
+	Prompt    string    `json:"prompt"`          // generate 的 Prompt 或 embed 的文本
+	Response  string    `json:"response,omitempty"`
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// LoadFixtures 读取 JSONL 录制文件
+func LoadFixtures(path string) ([]Fixture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var fixtures []Fixture
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var fixture Fixture
+		if err := json.Unmarshal([]byte(text), &fixture); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, scanner.Err()
+}
+
+// fixtureKey 按调用类型 + 文本内容匹配录制结果（不含模型名，切换模型配置后仍可回放）
+func fixtureKey(kind, prompt string) string {
+	sum := sha256.Sum256([]byte(kind + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReplayLLM 按录制文件回放模型响应的 LLM/Embedder，不发起任何网络请求。
+// 同一 Prompt 录制了多次时按录制顺序依次返回，用完后重复最后一条；未录制的 Prompt 返回 ErrFixtureNotFound。
+type ReplayLLM struct {
+	mu         sync.Mutex
+	responses  map[string][]string
+	cursor     map[string]int
+	embeddings map[string][]float32
+	misses     int
+}
+
+// NewReplayLLM 使用内存中的录制结果创建回放客户端
+func NewReplayLLM(fixtures []Fixture) *ReplayLLM {
+	r := &ReplayLLM{
+		responses:  make(map[string][]string),
+		cursor:     make(map[string]int),
+		embeddings: make(map[string][]float32),
+	}
+	for _, fixture := range fixtures {
+		key := fixtureKey(fixture.Kind, fixture.Prompt)
+		switch fixture.Kind {
+		case FixtureKindGenerate:
+			r.responses[key] = append(r.responses[key], fixture.Response)
+		case FixtureKindEmbed:
+			r.embeddings[key] = fixture.Embedding
+		}
+	}
+	return r
+}
+
+// LoadReplayLLM 从 JSONL 录制文件创建回放客户端
+func LoadReplayLLM(path string) (*ReplayLLM, error) {
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayLLM(fixtures), nil
+}
+
+// GenerateText 返回录制的响应
+func (r *ReplayLLM) GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := fixtureKey(FixtureKindGenerate, prompt)
+	responses := r.responses[key]
+	if len(responses) == 0 {
+		r.misses++
+		return "", fmt.Errorf("%w (generate %s)", ErrFixtureNotFound, key[:12])
+	}
+	idx := r.cursor[key]
+	if idx >= len(responses) {
+		idx = len(responses) - 1
+	}
+	r.cursor[key] = idx + 1
+	return responses[idx], nil
+}
+
+// EmbedQuery 返回录制的向量（副本）
+func (r *ReplayLLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := fixtureKey(FixtureKindEmbed, text)
+	vector, ok := r.embeddings[key]
+	if !ok {
+		r.misses++
+		return nil, fmt.Errorf("%w (embed %s)", ErrFixtureNotFound, key[:12])
+	}
+	return append([]float32(nil), vector...), nil
+}
+
+// EmbedDocuments 逐条返回录制的向量，任一条未录制即失败
+func (r *ReplayLLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector, err := r.EmbedQuery(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// Misses 回放未命中的调用次数
+func (r *ReplayLLM) Misses() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.misses
+}
+
+// Dimension 录制向量的维度（没有向量时为 0）
+func (r *ReplayLLM) Dimension() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, vector := range r.embeddings {
+		return len(vector)
+	}
+	return 0
+}
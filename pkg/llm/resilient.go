@@ -0,0 +1,355 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Embedder 向量化接口（与 memory.Embedder 方法集一致，避免循环依赖）
+type Embedder interface {
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// ErrCircuitOpen 熔断器打开期间直接拒绝调用
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ResilienceConfig 重试、并发与熔断参数
+type ResilienceConfig struct {
+	MaxRetries       int           // 失败后的最大重试次数（不含首次调用）
+	BaseDelay        time.Duration // 指数退避的初始间隔
+	MaxDelay         time.Duration // 单次退避上限；Retry-After 超过上限时不再重试，熔断至 Retry-After 到期
+	FailureThreshold int           // 连续失败多少次后熔断
+	OpenDuration     time.Duration // 熔断持续时间，之后放行一次探测请求
+}
+
+// ========== 熔断器 ==========
+
+// BreakerState 熔断器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerStatus 熔断器状态快照（供 /api/status 与告警使用）
+type BreakerStatus struct {
+	Name                string       `json:"name"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            time.Time    `json:"opened_at,omitempty"`
+	LastError           string       `json:"last_error,omitempty"`
+}
+
+// CircuitBreaker 连续失败计数型熔断器
+// closed：正常放行；open：拒绝调用直到 OpenDuration 过去；half_open：仅放行一个探测请求，成功则恢复。
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	name             string
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	openUntil        time.Time // open 状态持续到此时间（通常为 openedAt + openDuration）
+	lastError        string
+	probing          bool
+	failureThreshold int
+	openDuration     time.Duration
+}
+
+var (
+	breakerRegistryMu sync.RWMutex
+	breakerRegistry   = make(map[string]*CircuitBreaker)
+)
+
+// NewCircuitBreaker 创建熔断器并注册到全局，同名熔断器会被替换
+func NewCircuitBreaker(name string, failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = 5
+	}
+	if openDuration <= 0 {
+		openDuration = 30 * time.Second
+	}
+	b := &CircuitBreaker{
+		name:             name,
+		state:            BreakerClosed,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+	}
+
+	breakerRegistryMu.Lock()
+	breakerRegistry[name] = b
+	breakerRegistryMu.Unlock()
+	return b
+}
+
+// GetBreakerStatuses 返回所有已注册熔断器的状态（按名称排序）
+func GetBreakerStatuses() []BreakerStatus {
+	breakerRegistryMu.RLock()
+	breakers := make([]*CircuitBreaker, 0, len(breakerRegistry))
+	for _, b := range breakerRegistry {
+		breakers = append(breakers, b)
+	}
+	breakerRegistryMu.RUnlock()
+
+	statuses := make([]BreakerStatus, 0, len(breakers))
+	for _, b := range breakers {
+		statuses = append(statuses, b.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Allow 判断是否放行本次调用
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return fmt.Errorf("%s: %w", b.name, ErrCircuitOpen)
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%s: %w", b.name, ErrCircuitOpen)
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess 调用成功，恢复为 closed
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+	b.lastError = ""
+}
+
+// RecordFailure 调用失败（仅统计瞬时故障），达到阈值或探测失败时熔断
+func (b *CircuitBreaker) RecordFailure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		b.open(b.openDuration)
+	}
+}
+
+// tripFor 服务端要求等待的时间超过退避上限时立即熔断，至少持续 d
+func (b *CircuitBreaker) tripFor(err error, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	b.lastError = err.Error()
+	b.open(max(d, b.openDuration))
+}
+
+// open 切换为 open 状态（调用方需持有锁）
+func (b *CircuitBreaker) open(d time.Duration) {
+	b.state = BreakerOpen
+	b.openedAt = time.Now()
+	b.openUntil = b.openedAt.Add(d)
+}
+
+// releaseProbe 探测请求未产生结论（如调用方取消）时释放探测名额
+func (b *CircuitBreaker) releaseProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Status 返回状态快照；open 已超过持续时间时报告为 half_open
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state
+	if state == BreakerOpen && !time.Now().Before(b.openUntil) {
+		state = BreakerHalfOpen
+	}
+	status := BreakerStatus{
+		Name:                b.name,
+		State:               state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if state != BreakerClosed {
+		status.OpenedAt = b.openedAt
+	}
+	return status
+}
+
+// ========== 重试 / 并发 / 熔断 组合执行器 ==========
+
+// Resilience 为单个提供商调用链提供退避重试、并发限制与熔断
+type Resilience struct {
+	cfg     ResilienceConfig
+	limiter chan struct{}
+	breaker *CircuitBreaker
+}
+
+// NewConcurrencyLimiter 创建并发限制器，同一提供商的多个调用链可共享；n<=0 表示不限制
+func NewConcurrencyLimiter(n int) chan struct{} {
+	if n <= 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// NewResilience 创建执行器，limiter 可为 nil（不限制并发）
+func NewResilience(name string, cfg ResilienceConfig, limiter chan struct{}) *Resilience {
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 500 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 30 * time.Second
+	}
+	return &Resilience{
+		cfg:     cfg,
+		limiter: limiter,
+		breaker: NewCircuitBreaker(name, cfg.FailureThreshold, cfg.OpenDuration),
+	}
+}
+
+// Breaker 返回内部熔断器
+func (r *Resilience) Breaker() *CircuitBreaker {
+	return r.breaker
+}
+
+// Do 执行调用：熔断检查 -> 获取并发名额 -> 调用，瞬时错误按指数退避（优先 Retry-After）重试
+func (r *Resilience) Do(ctx context.Context, call func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = r.breaker.Allow(); err != nil {
+			return err
+		}
+
+		err = r.callWithLimit(ctx, call)
+		switch {
+		case err == nil:
+			r.breaker.RecordSuccess()
+			return nil
+		case IsRetryable(err) && RetryAfterOf(err) > r.cfg.MaxDelay:
+			// 服务端要求等待过久：不占用调用方等待，直接熔断到 Retry-After 到期，由备用提供商接管
+			r.breaker.tripFor(err, RetryAfterOf(err))
+			return err
+		case IsRetryable(err):
+			r.breaker.RecordFailure(err)
+		default:
+			// 非瞬时错误（参数错误、鉴权失败、调用方取消）不计入熔断
+			r.breaker.releaseProbe()
+			return err
+		}
+
+		if attempt >= r.cfg.MaxRetries {
+			return err
+		}
+
+		delay := r.backoff(attempt, RetryAfterOf(err))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *Resilience) callWithLimit(ctx context.Context, call func(ctx context.Context) error) error {
+	if r.limiter != nil {
+		select {
+		case r.limiter <- struct{}{}:
+			defer func() { <-r.limiter }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return call(ctx)
+}
+
+// backoff 指数退避（带抖动）；服务端给出 Retry-After（不超过 MaxDelay）时以其为准
+func (r *Resilience) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	delay := r.cfg.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > r.cfg.MaxDelay {
+		delay = r.cfg.MaxDelay
+	}
+	// 抖动范围 [delay/2, delay)，避免多个请求同时重试
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// ========== 装饰器 ==========
+
+// ResilientLLM 为 LLM 增加重试、并发限制与熔断
+type ResilientLLM struct {
+	inner      LLM
+	resilience *Resilience
+}
+
+func NewResilientLLM(inner LLM, resilience *Resilience) *ResilientLLM {
+	return &ResilientLLM{inner: inner, resilience: resilience}
+}
+
+func (l *ResilientLLM) GenerateText(ctx context.Context, prompt string, opts ...Option) (string, error) {
+	var text string
+	err := l.resilience.Do(ctx, func(ctx context.Context) error {
+		var err error
+		text, err = l.inner.GenerateText(ctx, prompt, opts...)
+		return err
+	})
+	return text, err
+}
+
+// ResilientEmbedder 为 Embedder 增加重试、并发限制与熔断
+type ResilientEmbedder struct {
+	inner      Embedder
+	resilience *Resilience
+}
+
+func NewResilientEmbedder(inner Embedder, resilience *Resilience) *ResilientEmbedder {
+	return &ResilientEmbedder{inner: inner, resilience: resilience}
+}
+
+func (e *ResilientEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	var vector []float32
+	err := e.resilience.Do(ctx, func(ctx context.Context) error {
+		var err error
+		vector, err = e.inner.EmbedQuery(ctx, text)
+		return err
+	})
+	return vector, err
+}
+
+func (e *ResilientEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := e.resilience.Do(ctx, func(ctx context.Context) error {
+		var err error
+		vectors, err = e.inner.EmbedDocuments(ctx, texts)
+		return err
+	})
+	return vectors, err
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResilienceRetryAfterOverMaxDelay(t *testing.T) {
+	r := NewResilience("test-retry-after", ResilienceConfig{
+		MaxRetries:       3,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         10 * time.Millisecond,
+		FailureThreshold: 5,
+		OpenDuration:     time.Second,
+	}, NewConcurrencyLimiter(1))
+
+	calls := 0
+	rateLimited := &APIError{Provider: "test", StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}
+	start := time.Now()
+	err := r.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return rateLimited
+	})
+
+	// 不等待一小时，也不重试
+	if !errors.Is(err, rateLimited) || calls != 1 {
+		t.Fatalf("err = %v, calls = %d", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do blocked for %v", elapsed)
+	}
+
+	// 熔断持续到 Retry-After 到期（长于 OpenDuration）
+	status := r.Breaker().Status()
+	if status.State != BreakerOpen {
+		t.Fatalf("breaker state = %s, want open", status.State)
+	}
+	if err := r.Do(context.Background(), func(ctx context.Context) error { calls++; return nil }); !errors.Is(err, ErrCircuitOpen) || calls != 1 {
+		t.Errorf("second call err = %v, calls = %d", err, calls)
+	}
+}
+
+func TestResilienceRetryAfterWithinMaxDelay(t *testing.T) {
+	r := NewResilience("test-retry-after-short", ResilienceConfig{
+		MaxRetries: 2,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   50 * time.Millisecond,
+	}, nil)
+
+	calls := 0
+	err := r.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &APIError{Provider: "test", StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Millisecond}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("err = %v, calls = %d", err, calls)
+	}
+	if state := r.Breaker().Status().State; state != BreakerClosed {
+		t.Errorf("breaker state = %s, want closed", state)
+	}
+}
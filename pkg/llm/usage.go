@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"ai-memory/pkg/types"
+	"context"
+	"sync"
+	"time"
+)
+
+// 调用类型（用于用量归因）
+const (
+	OperationChat          = "chat"
+	OperationJudge         = "judge"
+	OperationJudgeBatch    = "judge_batch"
+	OperationSummarize     = "summarize"
+	OperationExtractTags   = "extract_tags"
+	OperationMergeStrategy = "merge_strategy"
+	OperationEmbed         = "embed"
+)
+
+// Usage 单次调用的 token 用量（取自提供商响应）
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	EmbeddingTokens  int `json:"embedding_tokens"`
+}
+
+// UsageEvent 一次调用的用量记录
+type UsageEvent struct {
+	Provider  string
+	Model     string
+	Operation string
+	TenantID  string
+	UserID    string
+	Usage     Usage
+	Timestamp time.Time
+}
+
+// UsageRecorder 用量接收方（由 memory 包的指标收集器实现）
+type UsageRecorder interface {
+	RecordUsage(event UsageEvent)
+}
+
+var (
+	usageRecorderMu sync.RWMutex
+	usageRecorder   UsageRecorder
+)
+
+// SetUsageRecorder 设置全局用量接收方，nil 表示不记录
+func SetUsageRecorder(recorder UsageRecorder) {
+	usageRecorderMu.Lock()
+	defer usageRecorderMu.Unlock()
+	usageRecorder = recorder
+}
+
+type operationKey struct{}
+type userIDKey struct{}
+
+// WithOperation 标记后续 LLM 调用的调用类型
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// WithUserID 标记后续 LLM 调用所属的终端用户
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// OperationFrom 读取 context 中的调用类型
+func OperationFrom(ctx context.Context) string {
+	op, _ := ctx.Value(operationKey{}).(string)
+	return op
+}
+
+// UserIDFrom 读取 context 中的终端用户
+func UserIDFrom(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+// reportUsage 上报用量；context 未标记调用类型时使用 defaultOperation
+func reportUsage(ctx context.Context, provider, model, defaultOperation string, usage Usage) {
+	usageRecorderMu.RLock()
+	recorder := usageRecorder
+	usageRecorderMu.RUnlock()
+	if recorder == nil {
+		return
+	}
+
+	operation := OperationFrom(ctx)
+	if operation == "" || defaultOperation == OperationEmbed {
+		// 向量化统一归为 embed，不沿用外层的判定类调用类型
+		operation = defaultOperation
+	}
+	recorder.RecordUsage(UsageEvent{
+		Provider:  provider,
+		Model:     model,
+		Operation: operation,
+		TenantID:  types.TenantFrom(ctx),
+		UserID:    UserIDFrom(ctx),
+		Usage:     usage,
+		Timestamp: time.Now(),
+	})
+}
@@ -194,3 +194,8 @@ func Error(msg string, err error, args ...any) {
 func Info(msg string, args ...any) {
 	Log.Info(msg, args...)
 }
+
+// Warn 简单包装
+func Warn(msg string, args ...any) {
+	Log.Warn(msg, args...)
+}
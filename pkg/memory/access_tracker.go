@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AccessTracker 召回强化：记录 LTM 命中，并定期批量回写 access_count / last_access_at / decay_score
+// 同一条记忆在一个刷新周期内的多次命中会合并为一次写入，避免对向量库造成写放大。
+type AccessTracker struct {
+	mu      sync.Mutex
+	pending map[string]*accessHit
+
+	vectorStore VectorStore
+	decay       *DecayCalculator
+	maxPending  int
+	flushChan   chan struct{}
+}
+
+// accessHit 刷新周期内累计的访问
+type accessHit struct {
+	count  int
+	lastAt time.Time
+}
+
+// NewAccessTracker 创建访问追踪器，maxPending 为触发提前刷新的待写条数
+func NewAccessTracker(vectorStore VectorStore, decay *DecayCalculator, maxPending int) *AccessTracker {
+	return &AccessTracker{
+		pending:     make(map[string]*accessHit),
+		vectorStore: vectorStore,
+		decay:       decay,
+		maxPending:  maxPending,
+		flushChan:   make(chan struct{}, 1),
+	}
+}
+
+// Track 记录一次召回命中（仅 LTM 记录），不阻塞召回请求
+func (t *AccessTracker) Track(records []types.Record) {
+	now := time.Now()
+
+	t.mu.Lock()
+	for _, rec := range records {
+		if rec.Type != types.LongTerm || rec.ID == "" {
+			continue
+		}
+		hit, ok := t.pending[rec.ID]
+		if !ok {
+			hit = &accessHit{}
+			t.pending[rec.ID] = hit
+		}
+		hit.count++
+		hit.lastAt = now
+	}
+	full := t.maxPending > 0 && len(t.pending) >= t.maxPending
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.flushChan <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush 将累计的访问回写到向量库
+// 只通过 UpdateMetadata 更新 access_count / last_access_at / decay_score 三个字段，
+// 不整条覆盖，避免把期间被修改的内容写回或让已删除的记录复活；写入失败的命中放回待写队列。
+func (t *AccessTracker) Flush(ctx context.Context) {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = make(map[string]*accessHit)
+	t.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	updated := 0
+	for id, hit := range batch {
+		rec, err := t.vectorStore.Get(ctx, id)
+		if errors.Is(err, store.ErrRecordNotFound) {
+			// 记录已被遗忘/合并删除，丢弃
+			continue
+		}
+		if err != nil {
+			logger.Error("读取记忆访问记录失败", err, "id", id)
+			t.requeue(id, hit)
+			continue
+		}
+
+		accessCount := metaInt(rec.Metadata["access_count"]) + hit.count
+		err = t.vectorStore.UpdateMetadata(ctx, id, map[string]interface{}{
+			"access_count":   accessCount,
+			"last_access_at": hit.lastAt,
+			"decay_score":    t.decay.CalculateDecayScore(hit.lastAt, accessCount),
+		})
+		if errors.Is(err, store.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			logger.Error("回写记忆访问记录失败", err, "id", id)
+			t.requeue(id, hit)
+			continue
+		}
+		updated++
+	}
+
+	logger.System("🔁 Access tracking flushed", "hits", len(batch), "updated", updated)
+}
+
+// requeue 写入失败的命中放回待写队列，与期间新增的命中合并，下次刷新重试
+func (t *AccessTracker) requeue(id string, hit *accessHit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending, ok := t.pending[id]
+	if !ok {
+		t.pending[id] = hit
+		return
+	}
+	pending.count += hit.count
+	if hit.lastAt.After(pending.lastAt) {
+		pending.lastAt = hit.lastAt
+	}
+}
+
+// startAccessTracker 启动定期刷新协程；Manager 关闭时做最后一次刷新，避免丢失累计的访问
+func (m *Manager) startAccessTracker() {
+	interval := time.Duration(m.cfg.AccessFlushIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.accessTracker.Flush(m.ctx)
+			case <-m.accessTracker.flushChan:
+				m.accessTracker.Flush(m.ctx)
+			case <-m.ctx.Done():
+				m.accessTracker.Flush(context.Background())
+				return
+			}
+		}
+	}()
+}
+
+// trackAccess 召回命中时登记访问（未启用时为空操作）
+func (m *Manager) trackAccess(records []types.Record) {
+	if m.accessTracker != nil {
+		m.accessTracker.Track(records)
+	}
+}
+
+// metaInt 读取 metadata 中的整数（不同存储返回 int / int64 / float64 / 字符串）
+func metaInt(v interface{}) int {
+	switch val := v.(type) {
+	case int:
+		return val
+	case int64:
+		return int(val)
+	case int32:
+		return int(val)
+	case float64:
+		return int(val)
+	case float32:
+		return int(val)
+	case string:
+		n, _ := strconv.Atoi(val)
+		return n
+	default:
+		return 0
+	}
+}
+
+// metaFloat 读取 metadata 中的浮点数
+func metaFloat(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// metaTime 读取 metadata 中的时间（写入时为 time.Time，从存储读出时为 RFC3339 字符串）
+func metaTime(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, !val.IsZero()
+	case string:
+		t, err := time.Parse(time.RFC3339, val)
+		return t, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
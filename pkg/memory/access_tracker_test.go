@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyMetadataStore UpdateMetadata 前 failures 次返回错误
+type flakyMetadataStore struct {
+	*store.InMemoryVectorStore
+	failures int
+}
+
+func (s *flakyMetadataStore) UpdateMetadata(ctx context.Context, id string, fields map[string]interface{}) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("vector store unavailable")
+	}
+	return s.InMemoryVectorStore.UpdateMetadata(ctx, id, fields)
+}
+
+func TestAccessTrackerFlush(t *testing.T) {
+	ctx := context.Background()
+	vectorStore := store.NewInMemoryVectorStore("")
+	if err := vectorStore.Add(ctx, []types.Record{
+		{ID: "kept", Content: "旧内容", Embedding: []float32{1, 0}, Type: types.LongTerm,
+			Metadata: map[string]interface{}{"user_id": "u1", "access_count": 2}},
+		{ID: "deleted", Content: "会被删除", Embedding: []float32{0, 1}, Type: types.LongTerm,
+			Metadata: map[string]interface{}{"user_id": "u1"}},
+	}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	flaky := &flakyMetadataStore{InMemoryVectorStore: vectorStore, failures: 1}
+	tracker := NewAccessTracker(flaky, NewDecayCalculator(30, 0.1), 0)
+	hits, _ := vectorStore.List(ctx, nil, 0, 0)
+	tracker.Track(hits)
+
+	// 刷新前记录被修改、另一条被删除：回写只能更新访问字段，不能覆盖内容或让记录复活
+	if err := vectorStore.Update(ctx, types.Record{ID: "kept", Content: "新内容", Embedding: []float32{1, 1}, Type: types.LongTerm,
+		Metadata: map[string]interface{}{"user_id": "u1", "access_count": 2}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := vectorStore.Delete(ctx, []string{"deleted"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	// 第一次写入失败：命中放回队列，与之后的新命中合并
+	tracker.Flush(ctx)
+	if hit := tracker.pending["kept"]; hit == nil || hit.count != 1 {
+		t.Fatalf("pending after failed flush = %+v, want kept requeued", tracker.pending)
+	}
+	if _, ok := tracker.pending["deleted"]; ok {
+		t.Error("hits on deleted records should be dropped")
+	}
+	tracker.Track([]types.Record{{ID: "kept", Type: types.LongTerm}})
+	tracker.Flush(ctx)
+	if len(tracker.pending) != 0 {
+		t.Errorf("pending after successful flush = %+v", tracker.pending)
+	}
+
+	rec, err := vectorStore.Get(ctx, "kept")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if rec.Content != "新内容" || rec.Embedding[1] != 1 {
+		t.Errorf("flush overwrote the record: %+v", rec)
+	}
+	if got := metaInt(rec.Metadata["access_count"]); got != 4 {
+		t.Errorf("access_count = %d, want 4", got)
+	}
+	if _, ok := metaFloat(rec.Metadata["decay_score"]); !ok {
+		t.Errorf("decay_score missing: %+v", rec.Metadata)
+	}
+	if _, err := vectorStore.Get(ctx, "deleted"); !errors.Is(err, store.ErrRecordNotFound) {
+		t.Errorf("deleted record err = %v, want ErrRecordNotFound", err)
+	}
+}
+
+// TestRetrieveTracksReturnedLTMOnly MaxRecentMemories 截掉的 LTM 记录不登记访问
+func TestRetrieveTracksReturnedLTMOnly(t *testing.T) {
+	ctx := context.Background()
+	m, vectorStore := newFunnelTestManager(t, scriptedLLM{})
+	m.accessTracker = NewAccessTracker(vectorStore, m.decayCalculator, 0)
+
+	m.Add(ctx, "u1", "s1", "今天天气怎么样", "晴天", nil)
+	m.Add(ctx, "u1", "s1", "明天呢", "多云", nil)
+	vector, _ := m.embedder.EmbedQuery(ctx, coffeeFact)
+	if err := vectorStore.Add(ctx, []types.Record{{ID: "ltm", Content: coffeeFact, Embedding: vector,
+		Timestamp: time.Now(), Type: types.LongTerm,
+		Metadata: map[string]interface{}{metaTenantID: types.DefaultTenant, "user_id": "u1"}}}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	for _, tt := range []struct {
+		maxRecent int
+		tracked   int
+	}{
+		{maxRecent: 2, tracked: 0}, // 两条 STM 占满名额，LTM 被截掉
+		{maxRecent: 3, tracked: 1},
+	} {
+		m.cfg.MaxRecentMemories = tt.maxRecent
+		m.accessTracker.pending = make(map[string]*accessHit)
+		result, err := m.Retrieve(ctx, "u1", "s1", coffeeFact, 5)
+		if err != nil {
+			t.Fatalf("Retrieve: %v", err)
+		}
+		if len(result) != tt.maxRecent {
+			t.Errorf("MaxRecentMemories=%d: Retrieve returned %d records", tt.maxRecent, len(result))
+		}
+		if len(m.accessTracker.pending) != tt.tracked {
+			t.Errorf("MaxRecentMemories=%d: tracked %v, want %d", tt.maxRecent, m.accessTracker.pending, tt.tracked)
+		}
+	}
+}
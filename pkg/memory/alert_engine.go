@@ -1,8 +1,9 @@
 package memory
 
 import (
+	"ai-memory/pkg/llm"
 	"ai-memory/pkg/logger"
-	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -29,6 +30,30 @@ type Alert struct {
 	Message   string                 `json:"message"`
 	Timestamp time.Time              `json:"timestamp"`
 	Metadata  map[string]interface{} `json:"metadata"`
+	TenantID  string                 `json:"tenant_id,omitempty"` // 可归属到单个租户的告警，系统级告警为空
+}
+
+// AlertScope 告警的可见范围：TenantID 租户自己的告警；IncludeSystem 时同时包含系统级告警（tenant_id 为空），
+// 系统级告警描述共享基础设施且可能带有跨租户的明细（如预算用尽的用户列表），只对后台会话开放
+type AlertScope struct {
+	TenantID      string
+	IncludeSystem bool
+}
+
+// Visible 告警是否在可见范围内
+func (s AlertScope) Visible(alert *Alert) bool {
+	if alert.TenantID == "" {
+		return s.IncludeSystem
+	}
+	return alert.TenantID == s.TenantID
+}
+
+// sqlCondition alerts 表的租户过滤条件
+func (s AlertScope) sqlCondition() (string, []interface{}) {
+	if s.IncludeSystem {
+		return " AND (tenant_id = ? OR tenant_id IS NULL)", []interface{}{s.TenantID}
+	}
+	return " AND tenant_id = ?", []interface{}{s.TenantID}
 }
 
 // AlertRule 告警规则
@@ -36,7 +61,7 @@ type AlertRule struct {
 	ID          string
 	Name        string
 	Description string
-	CheckFunc   func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert
+	CheckFunc   func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert
 	Enabled     bool
 	Cooldown    time.Duration
 
@@ -68,7 +93,7 @@ type AlertEngine struct {
 	checkInterval    time.Duration
 	notifyFunc       func(alert *Alert)
 	metricsCollector *MetricsCollector
-	stagingStore     *store.StagingStore
+	stagingStore     StagingStore
 	stopChan         chan struct{}
 
 	// 存储层（依赖注入）
@@ -143,7 +168,7 @@ type AlertConfig struct {
 }
 
 // NewAlertEngine 创建告警引擎
-func NewAlertEngine(repository AlertRepository, collector *MetricsCollector, stagingStore *store.StagingStore, config *AlertConfig) *AlertEngine {
+func NewAlertEngine(repository AlertRepository, collector *MetricsCollector, stagingStore StagingStore, config *AlertConfig) *AlertEngine {
 	engine := &AlertEngine{
 		rules:            make([]*AlertRule, 0),
 		recentAlerts:     make([]Alert, 0, config.HistoryMaxSize),
@@ -217,6 +242,26 @@ func (ae *AlertEngine) registerDefaultRules(config *AlertConfig) {
 		Enabled:     true,
 		Cooldown:    time.Duration(config.DecaySpikeCooldownMinutes) * time.Minute,
 	})
+
+	// 规则5: LLM / Embedding 熔断
+	ae.AddRule(&AlertRule{
+		ID:          "llm_circuit_open",
+		Name:        "LLM调用熔断",
+		Description: "LLM或Embedding提供商连续失败触发熔断",
+		CheckFunc:   ae.makeLLMCircuitOpenCheck(),
+		Enabled:     true,
+		Cooldown:    5 * time.Minute,
+	})
+
+	// 规则6: LLM 每日预算用尽
+	ae.AddRule(&AlertRule{
+		ID:          "llm_budget_exhausted",
+		Name:        "LLM预算用尽",
+		Description: "终端用户或全局的LLM每日预算已用尽",
+		CheckFunc:   ae.makeLLMBudgetExhaustedCheck(),
+		Enabled:     true,
+		Cooldown:    time.Hour,
+	})
 }
 
 // AddRule 添加自定义规则
@@ -371,18 +416,18 @@ func (ae *AlertEngine) GetRecentAlerts(limit int) []Alert {
 }
 
 // QueryAlerts 查询告警（完整数据库查询）
-func (ae *AlertEngine) QueryAlerts(ctx context.Context, level, rule string, limit, offset int) ([]Alert, int, error) {
+func (ae *AlertEngine) QueryAlerts(ctx context.Context, level, rule string, scope AlertScope, limit, offset int) ([]Alert, int, error) {
 	if ae.repository == nil {
 		return nil, 0, fmt.Errorf("alert repository not initialized")
 	}
-	return ae.repository.QueryFiltered(ctx, level, rule, limit, offset)
+	return ae.repository.QueryFiltered(ctx, level, rule, scope, limit, offset)
 }
 
-// DeleteAlert 删除告警
-func (ae *AlertEngine) DeleteAlert(ctx context.Context, id string) error {
+// DeleteAlert 删除告警（只能删除可见范围内的告警，否则返回 ErrRecordNotFound）
+func (ae *AlertEngine) DeleteAlert(ctx context.Context, id string, scope AlertScope) error {
 	// 从数据库删除
 	if ae.repository != nil {
-		if err := ae.repository.Delete(ctx, id); err != nil {
+		if err := ae.repository.Delete(ctx, id, scope); err != nil {
 			return err
 		}
 	}
@@ -390,13 +435,20 @@ func (ae *AlertEngine) DeleteAlert(ctx context.Context, id string) error {
 	// 从内存缓存删除
 	ae.mu.Lock()
 	defer ae.mu.Unlock()
+	deleted := false
 	newAlerts := make([]Alert, 0, len(ae.recentAlerts))
 	for _, a := range ae.recentAlerts {
-		if a.ID != id {
-			newAlerts = append(newAlerts, a)
+		if a.ID == id && scope.Visible(&a) {
+			deleted = true
+			continue
 		}
+		newAlerts = append(newAlerts, a)
 	}
 	ae.recentAlerts = newAlerts
+	if ae.repository == nil && !deleted {
+		return ErrRecordNotFound
+	}
+	ae.InvalidateStatsCache()
 	return nil
 }
 
@@ -412,8 +464,8 @@ func (ae *AlertEngine) CreateAlert(ctx context.Context, alert Alert) error {
 // ========== 告警规则实现（使用闭包支持配置化）==========
 
 // makeQueueBacklogCheck 创建队列积压检查函数（动态读取配置）
-func (ae *AlertEngine) makeQueueBacklogCheck(defaultThreshold int) func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert {
-	return func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert {
+func (ae *AlertEngine) makeQueueBacklogCheck(defaultThreshold int) func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
+	return func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
 		// 动态读取阈值配置
 		threshold := defaultThreshold
 		if ae.configPersistence != nil {
@@ -455,8 +507,8 @@ func (ae *AlertEngine) makeQueueBacklogCheck(defaultThreshold int) func(ctx cont
 }
 
 // makeLowSuccessRateCheck 创建成功率检查函数
-func (ae *AlertEngine) makeLowSuccessRateCheck(threshold float64) func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert {
-	return func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert {
+func (ae *AlertEngine) makeLowSuccessRateCheck(threshold float64) func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
+	return func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
 		metrics.mu.RLock()
 		totalAttempts := metrics.TotalPromotions + metrics.TotalRejections
 		promotions := metrics.TotalPromotions
@@ -487,12 +539,12 @@ func (ae *AlertEngine) makeLowSuccessRateCheck(threshold float64) func(ctx conte
 }
 
 // makeCacheAnomalyCheckSmart 创建智能缓存异常检查函数
-func (ae *AlertEngine) makeCacheAnomalyCheckSmart() func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert {
+func (ae *AlertEngine) makeCacheAnomalyCheckSmart() func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
 	// 存储历史命中率（用于趋势检测）
 	var historyRates []float64
 	var historyMu sync.Mutex
 
-	return func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert {
+	return func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
 		metrics.mu.RLock()
 		totalAccess := metrics.CacheHits + metrics.CacheMisses
 		hits := metrics.CacheHits
@@ -574,8 +626,8 @@ func (ae *AlertEngine) makeCacheAnomalyCheckSmart() func(ctx context.Context, me
 }
 
 // makeDecaySpikeCheck 创建衰减突增检查函数
-func (ae *AlertEngine) makeDecaySpikeCheck(threshold int) func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert {
-	return func(ctx context.Context, metrics *MetricsCollector, stagingStore *store.StagingStore) *Alert {
+func (ae *AlertEngine) makeDecaySpikeCheck(threshold int) func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
+	return func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
 		metrics.mu.RLock()
 		forgotten := metrics.TotalForgotten
 		metrics.mu.RUnlock()
@@ -597,3 +649,93 @@ func (ae *AlertEngine) makeDecaySpikeCheck(threshold int) func(ctx context.Conte
 		return nil
 	}
 }
+
+// makeLLMCircuitOpenCheck 创建熔断检查函数：任一提供商熔断时告警
+func (ae *AlertEngine) makeLLMCircuitOpenCheck() func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
+	return func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
+		var open []llm.BreakerStatus
+		for _, status := range llm.GetBreakerStatuses() {
+			if status.State != llm.BreakerClosed {
+				open = append(open, status)
+			}
+		}
+		if len(open) == 0 {
+			return nil
+		}
+
+		names := make([]string, 0, len(open))
+		for _, status := range open {
+			names = append(names, status.Name)
+		}
+		return &Alert{
+			ID:        fmt.Sprintf("llm_circuit_open_%s", uuid.New().String()[:8]),
+			Level:     AlertLevelError,
+			Rule:      "llm_circuit_open",
+			Message:   "LLM调用已熔断，判定与向量化暂停，请检查提供商状态与配额",
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				"breakers":   names,
+				"last_error": open[0].LastError,
+				"opened_at":  open[0].OpenedAt,
+			},
+		}
+	}
+}
+
+// makeLLMBudgetExhaustedCheck 创建预算检查函数：全局或任一终端用户当日预算用尽时告警
+func (ae *AlertEngine) makeLLMBudgetExhaustedCheck() func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
+	return func(ctx context.Context, metrics *MetricsCollector, stagingStore StagingStore) *Alert {
+		budget := metrics.budgetManager()
+		if !budget.Enabled() {
+			return nil
+		}
+
+		status := budget.Status()
+		if !status.GlobalExhausted && len(status.ExhaustedUsers) == 0 {
+			return nil
+		}
+
+		level := AlertLevelWarning
+		message := fmt.Sprintf("%d 个用户的LLM每日预算已用尽，其STM判定已降级", len(status.ExhaustedUsers))
+		if status.GlobalExhausted {
+			level = AlertLevelError
+			message = "全局LLM每日预算已用尽，所有用户的STM判定已降级"
+		}
+
+		users := status.ExhaustedUsers
+		if len(users) > 20 {
+			users = users[:20]
+		}
+
+		// 用尽预算的用户都属于同一租户时，告警归属该租户
+		tenantID := ""
+		if !status.GlobalExhausted {
+			for i, key := range status.ExhaustedUsers {
+				userTenant, _ := types.SplitTenantKey(key)
+				if i == 0 {
+					tenantID = userTenant
+				} else if userTenant != tenantID {
+					tenantID = ""
+					break
+				}
+			}
+		}
+		return &Alert{
+			ID:        fmt.Sprintf("llm_budget_exhausted_%s", uuid.New().String()[:8]),
+			Level:     level,
+			Rule:      "llm_budget_exhausted",
+			Message:   message,
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				"day":                  status.Day,
+				"global_exhausted":     status.GlobalExhausted,
+				"global_usage":         status.GlobalUsage,
+				"exhausted_users":      users,
+				"exhausted_user_count": len(status.ExhaustedUsers),
+				"deferred":             status.Deferred,
+				"heuristic":            status.Heuristic,
+			},
+			TenantID: tenantID,
+		}
+	}
+}
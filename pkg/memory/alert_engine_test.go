@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAlertScope(t *testing.T) {
+	ctx := context.Background()
+	engine := NewAlertEngine(nil, GetGlobalMetrics(), nil, &AlertConfig{HistoryMaxSize: 10})
+	for _, alert := range []Alert{
+		{ID: "scope-a", Level: AlertLevelWarning, Rule: "scope_test", TenantID: "tenant-a"},
+		{ID: "scope-b", Level: AlertLevelWarning, Rule: "scope_test", TenantID: "tenant-b"},
+		{ID: "scope-system", Level: AlertLevelWarning, Rule: "scope_test"},
+	} {
+		engine.CreateAlert(ctx, alert)
+	}
+
+	aggregated := func(scope AlertScope) map[string]bool {
+		ids := make(map[string]bool)
+		for _, agg := range GetAggregatedAlerts(scope) {
+			if agg.Rule == "scope_test" {
+				ids[agg.ID] = true
+			}
+		}
+		return ids
+	}
+	if got := aggregated(AlertScope{TenantID: "tenant-a"}); len(got) != 1 || !got["scope-a"] {
+		t.Errorf("tenant-only aggregation = %v, want [scope-a]", got)
+	}
+	if got := aggregated(AlertScope{TenantID: "tenant-a", IncludeSystem: true}); len(got) != 2 || !got["scope-a"] || !got["scope-system"] {
+		t.Errorf("tenant+system aggregation = %v, want [scope-a scope-system]", got)
+	}
+
+	// 其他租户与系统级告警对仅限租户的调用方不可见，删除按不存在处理
+	for _, id := range []string{"scope-b", "scope-system"} {
+		if err := engine.DeleteAlert(ctx, id, AlertScope{TenantID: "tenant-a"}); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("DeleteAlert(%s) err = %v, want ErrRecordNotFound", id, err)
+		}
+	}
+	if err := engine.DeleteAlert(ctx, "scope-a", AlertScope{TenantID: "tenant-a"}); err != nil {
+		t.Errorf("DeleteAlert(scope-a) err = %v", err)
+	}
+	if err := engine.DeleteAlert(ctx, "scope-system", AlertScope{TenantID: "tenant-a", IncludeSystem: true}); err != nil {
+		t.Errorf("DeleteAlert(scope-system) err = %v", err)
+	}
+
+	remaining := engine.GetRecentAlerts(0)
+	if len(remaining) != 1 || remaining[0].ID != "scope-b" {
+		t.Errorf("remaining alerts = %+v, want [scope-b]", remaining)
+	}
+}
@@ -241,14 +241,14 @@ func (ae *AlertEngine) recordNotifyResult(success bool) {
 	}
 }
 
-// GetAlertTrend 获取告警趋势数据
-func (ae *AlertEngine) GetAlertTrend(ctx context.Context, hours int) (map[string]interface{}, error) {
+// GetAlertTrend 获取可见范围内的告警趋势数据
+func (ae *AlertEngine) GetAlertTrend(ctx context.Context, hours int, scope AlertScope) (map[string]interface{}, error) {
 	if ae.repository == nil {
 		return nil, fmt.Errorf("repository not initialized")
 	}
 
 	// 从数据库查询最近N小时的告警
-	alerts, err := ae.repository.QueryRecent(ctx, hours*100) // 粗略估算
+	alerts, err := ae.repository.QueryRecent(ctx, scope, hours*100) // 粗略估算
 	if err != nil {
 		return nil, err
 	}
@@ -310,8 +310,8 @@ func (ae *AlertEngine) GetAlertTrend(ctx context.Context, hours int) (map[string
 	}, nil
 }
 
-// GetAlertsByLevel 按级别统计告警数量（从DB读取）
-func (ae *AlertEngine) GetAlertsByLevel(ctx context.Context) (map[AlertLevel]int, error) {
+// GetAlertsByLevel 按级别统计可见范围内的告警数量（从DB读取）
+func (ae *AlertEngine) GetAlertsByLevel(ctx context.Context, scope AlertScope) (map[AlertLevel]int, error) {
 	if ae.repository == nil {
 		return nil, fmt.Errorf("repository not initialized")
 	}
@@ -324,7 +324,7 @@ func (ae *AlertEngine) GetAlertsByLevel(ctx context.Context) (map[AlertLevel]int
 
 	// 分别统计各级别的告警数量
 	for level := range counts {
-		count, err := ae.repository.Count(ctx, string(level), "")
+		count, err := ae.repository.Count(ctx, string(level), "", scope)
 		if err != nil {
 			logger.Error("Failed to count alerts", err)
 			continue
@@ -349,9 +349,9 @@ var (
 	aggregationMutex sync.RWMutex
 )
 
-// getAggregationKey 生成聚合键
+// getAggregationKey 生成聚合键（不同租户的同类告警分开聚合）
 func getAggregationKey(alert *Alert) string {
-	return fmt.Sprintf("%s:%s", alert.Rule, alert.Level)
+	return fmt.Sprintf("%s:%s:%s", alert.TenantID, alert.Rule, alert.Level)
 }
 
 // aggregateAlert 聚合告警（在冷却期内的相同规则告警会被聚合）
@@ -392,13 +392,16 @@ func cleanOldAggregations() {
 	}
 }
 
-// GetAggregatedAlerts 获取聚合后的告警
-func GetAggregatedAlerts() []*AggregatedAlert {
+// GetAggregatedAlerts 获取可见范围内聚合后的告警
+func GetAggregatedAlerts(scope AlertScope) []*AggregatedAlert {
 	aggregationMutex.RLock()
 	defer aggregationMutex.RUnlock()
 
 	result := make([]*AggregatedAlert, 0, len(aggregationMap))
 	for _, agg := range aggregationMap {
+		if !scope.Visible(&agg.Alert) {
+			continue
+		}
 		// 深拷贝
 		copy := &AggregatedAlert{
 			Alert:     agg.Alert,
@@ -12,14 +12,14 @@ import (
 type AlertRepository interface {
 	// Save 保存告警到数据库
 	Save(ctx context.Context, alert *Alert) error
-	// QueryRecent 查询最近的N条告警
-	QueryRecent(ctx context.Context, limit int) ([]Alert, error)
-	// QueryFiltered 带过滤条件查询告警
-	QueryFiltered(ctx context.Context, level, rule string, limit, offset int) ([]Alert, int, error)
-	// Delete 删除告警
-	Delete(ctx context.Context, id string) error
-	// Count 统计告警总数
-	Count(ctx context.Context, level, rule string) (int, error)
+	// QueryRecent 查询可见范围内最近的N条告警
+	QueryRecent(ctx context.Context, scope AlertScope, limit int) ([]Alert, error)
+	// QueryFiltered 带过滤条件查询可见范围内的告警
+	QueryFiltered(ctx context.Context, level, rule string, scope AlertScope, limit, offset int) ([]Alert, int, error)
+	// Delete 删除可见范围内的告警，不存在或不可见时返回 ErrRecordNotFound
+	Delete(ctx context.Context, id string, scope AlertScope) error
+	// Count 统计可见范围内的告警总数
+	Count(ctx context.Context, level, rule string, scope AlertScope) (int, error)
 }
 
 // MySQLAlertRepository MySQL实现
@@ -40,8 +40,9 @@ func (r *MySQLAlertRepository) Save(ctx context.Context, alert *Alert) error {
 
 	metaBytes, _ := json.Marshal(alert.Metadata)
 	_, err := r.db.ExecContext(ctx,
-		"INSERT INTO alerts (id, level, rule, message, timestamp, metadata) VALUES (?, ?, ?, ?, ?, ?)",
-		alert.ID, alert.Level, alert.Rule, alert.Message, alert.Timestamp, string(metaBytes))
+		"INSERT INTO alerts (id, level, rule, message, timestamp, metadata, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		alert.ID, alert.Level, alert.Rule, alert.Message, alert.Timestamp, string(metaBytes),
+		sql.NullString{String: alert.TenantID, Valid: alert.TenantID != ""})
 
 	if err != nil {
 		logger.Error("Failed to save alert to database", err)
@@ -51,14 +52,15 @@ func (r *MySQLAlertRepository) Save(ctx context.Context, alert *Alert) error {
 }
 
 // QueryRecent 查询最近的告警
-func (r *MySQLAlertRepository) QueryRecent(ctx context.Context, limit int) ([]Alert, error) {
+func (r *MySQLAlertRepository) QueryRecent(ctx context.Context, scope AlertScope, limit int) ([]Alert, error) {
 	if r.db == nil {
 		return nil, fmt.Errorf("database not initialized")
 	}
 
+	condition, args := scope.sqlCondition()
 	rows, err := r.db.QueryContext(ctx,
-		"SELECT id, level, rule, message, timestamp, metadata FROM alerts ORDER BY timestamp DESC LIMIT ?",
-		limit)
+		"SELECT id, level, rule, message, timestamp, metadata, tenant_id FROM alerts WHERE 1=1"+condition+" ORDER BY timestamp DESC LIMIT ?",
+		append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -68,13 +70,13 @@ func (r *MySQLAlertRepository) QueryRecent(ctx context.Context, limit int) ([]Al
 }
 
 // QueryFiltered 带过滤条件查询
-func (r *MySQLAlertRepository) QueryFiltered(ctx context.Context, level, rule string, limit, offset int) ([]Alert, int, error) {
+func (r *MySQLAlertRepository) QueryFiltered(ctx context.Context, level, rule string, scope AlertScope, limit, offset int) ([]Alert, int, error) {
 	if r.db == nil {
 		return nil, 0, fmt.Errorf("database not initialized")
 	}
 
 	// 构建查询条件
-	query := "SELECT id, level, rule, message, timestamp, metadata FROM alerts WHERE 1=1"
+	query := "SELECT id, level, rule, message, timestamp, metadata, tenant_id FROM alerts WHERE 1=1"
 	countQuery := "SELECT COUNT(*) FROM alerts WHERE 1=1"
 	var args []interface{}
 
@@ -88,6 +90,10 @@ func (r *MySQLAlertRepository) QueryFiltered(ctx context.Context, level, rule st
 		countQuery += " AND rule = ?"
 		args = append(args, rule)
 	}
+	condition, tenantArgs := scope.sqlCondition()
+	query += condition
+	countQuery += condition
+	args = append(args, tenantArgs...)
 
 	query += " ORDER BY timestamp DESC LIMIT ? OFFSET ?"
 
@@ -113,17 +119,24 @@ func (r *MySQLAlertRepository) QueryFiltered(ctx context.Context, level, rule st
 }
 
 // Delete 删除告警
-func (r *MySQLAlertRepository) Delete(ctx context.Context, id string) error {
+func (r *MySQLAlertRepository) Delete(ctx context.Context, id string, scope AlertScope) error {
 	if r.db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	_, err := r.db.ExecContext(ctx, "DELETE FROM alerts WHERE id = ?", id)
-	return err
+	condition, args := scope.sqlCondition()
+	result, err := r.db.ExecContext(ctx, "DELETE FROM alerts WHERE id = ?"+condition, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
 }
 
 // Count 统计告警数量
-func (r *MySQLAlertRepository) Count(ctx context.Context, level, rule string) (int, error) {
+func (r *MySQLAlertRepository) Count(ctx context.Context, level, rule string, scope AlertScope) (int, error) {
 	if r.db == nil {
 		return 0, fmt.Errorf("database not initialized")
 	}
@@ -139,6 +152,9 @@ func (r *MySQLAlertRepository) Count(ctx context.Context, level, rule string) (i
 		query += " AND rule = ?"
 		args = append(args, rule)
 	}
+	condition, tenantArgs := scope.sqlCondition()
+	query += condition
+	args = append(args, tenantArgs...)
 
 	var count int
 	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
@@ -151,9 +167,11 @@ func (r *MySQLAlertRepository) scanAlerts(rows *sql.Rows) ([]Alert, error) {
 	for rows.Next() {
 		var a Alert
 		var metaStr string
-		if err := rows.Scan(&a.ID, &a.Level, &a.Rule, &a.Message, &a.Timestamp, &metaStr); err != nil {
+		var tenantID sql.NullString
+		if err := rows.Scan(&a.ID, &a.Level, &a.Rule, &a.Message, &a.Timestamp, &metaStr, &tenantID); err != nil {
 			continue
 		}
+		a.TenantID = tenantID.String
 		json.Unmarshal([]byte(metaStr), &a.Metadata)
 		alerts = append(alerts, a)
 	}
@@ -161,6 +161,22 @@ func (p *RuleConfigPersistence) SeedDefaultConfigs(ctx context.Context) error {
 			CooldownSeconds: 3600,
 			ConfigJSON:      `{"threshold": 1000}`,
 		},
+		{
+			ID:              "llm_circuit_open",
+			Name:            "LLM调用熔断",
+			Description:     "LLM或Embedding提供商连续失败触发熔断",
+			Enabled:         true,
+			CooldownSeconds: 300,
+			ConfigJSON:      `{}`,
+		},
+		{
+			ID:              "llm_budget_exhausted",
+			Name:            "LLM预算用尽",
+			Description:     "终端用户或全局的LLM每日预算已用尽",
+			Enabled:         true,
+			CooldownSeconds: 3600,
+			ConfigJSON:      `{}`,
+		},
 	}
 
 	query := `
@@ -5,42 +5,50 @@ import (
 	"time"
 )
 
-// StatsCache 统计信息缓存
+// StatsCache 统计信息缓存（按级别计数随可见范围不同，按范围分别缓存）
 type StatsCache struct {
-	mu          sync.RWMutex
+	mu      sync.RWMutex
+	entries map[AlertScope]*statsCacheEntry
+	ttl     time.Duration
+}
+
+type statsCacheEntry struct {
 	stats       *AlertEngineStats
 	levelCounts map[AlertLevel]int
 	cachedAt    time.Time
-	ttl         time.Duration
 }
 
 // NewStatsCache 创建统计缓存
 func NewStatsCache(ttl time.Duration) *StatsCache {
 	return &StatsCache{
-		ttl: ttl,
+		entries: make(map[AlertScope]*statsCacheEntry),
+		ttl:     ttl,
 	}
 }
 
 // GetStats 获取缓存的统计信息
-func (sc *StatsCache) GetStats() (*AlertEngineStats, map[AlertLevel]int, bool) {
+func (sc *StatsCache) GetStats(scope AlertScope) (*AlertEngineStats, map[AlertLevel]int, bool) {
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
 
-	if sc.stats == nil || time.Since(sc.cachedAt) > sc.ttl {
+	entry, ok := sc.entries[scope]
+	if !ok || time.Since(entry.cachedAt) > sc.ttl {
 		return nil, nil, false
 	}
 
-	return sc.stats, sc.levelCounts, true
+	return entry.stats, entry.levelCounts, true
 }
 
 // SetStats 设置统计缓存
-func (sc *StatsCache) SetStats(stats *AlertEngineStats, levelCounts map[AlertLevel]int) {
+func (sc *StatsCache) SetStats(scope AlertScope, stats *AlertEngineStats, levelCounts map[AlertLevel]int) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 
-	sc.stats = stats
-	sc.levelCounts = levelCounts
-	sc.cachedAt = time.Now()
+	sc.entries[scope] = &statsCacheEntry{
+		stats:       stats,
+		levelCounts: levelCounts,
+		cachedAt:    time.Now(),
+	}
 }
 
 // Invalidate 使缓存失效
@@ -48,6 +56,5 @@ func (sc *StatsCache) Invalidate() {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 
-	sc.stats = nil
-	sc.levelCounts = nil
+	sc.entries = make(map[AlertScope]*statsCacheEntry)
 }
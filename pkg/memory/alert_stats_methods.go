@@ -4,22 +4,22 @@ import (
 	"context"
 )
 
-// GetStatsWithCache 获取统计信息（带缓存）
-func (ae *AlertEngine) GetStatsWithCache(ctx context.Context) (*AlertEngineStats, map[AlertLevel]int, error) {
+// GetStatsWithCache 获取统计信息（带缓存），按级别计数只统计可见范围内的告警
+func (ae *AlertEngine) GetStatsWithCache(ctx context.Context, scope AlertScope) (*AlertEngineStats, map[AlertLevel]int, error) {
 	// 尝试从缓存获取
-	if stats, levelCounts, ok := ae.statsCache.GetStats(); ok {
+	if stats, levelCounts, ok := ae.statsCache.GetStats(scope); ok {
 		return stats, levelCounts, nil
 	}
 
 	// 缓存未命中，重新计算
 	stats := ae.GetStats()
-	levelCounts, err := ae.GetAlertsByLevel(ctx)
+	levelCounts, err := ae.GetAlertsByLevel(ctx, scope)
 	if err != nil {
 		return stats, nil, err
 	}
 
 	// 更新缓存
-	ae.statsCache.SetStats(stats, levelCounts)
+	ae.statsCache.SetStats(scope, stats, levelCounts)
 
 	return stats, levelCounts, nil
 }
@@ -0,0 +1,225 @@
+package memory
+
+import (
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/types"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 审计动作
+const (
+	AuditMemoryUpdate    = "memory.update"     // 编辑记忆（LTM / STM）
+	AuditMemoryDelete    = "memory.delete"     // 删除长期记忆
+	AuditSTMClear        = "stm.clear"         // 清空会话 STM
+	AuditStagingConfirm  = "staging.confirm"   // 确认暂存记忆并晋升
+	AuditStagingReject   = "staging.reject"    // 拒绝（删除）暂存记忆
+	AuditLTMMerge        = "ltm.merge"         // 去重合并（被合并掉的记录为 target）
+	AuditLTMEvict        = "ltm.evict"         // 衰减遗忘
+	AuditAlertRuleUpdate = "alert_rule.update" // 启停告警规则、修改冷却时间或阈值
+	AuditAccountCreate   = "account.create"
+	AuditAccountUpdate   = "account.update" // 修改角色、停用/启用
+	AuditAPIKeyCreate    = "apikey.create"
+	AuditAPIKeyRevoke    = "apikey.revoke"
+)
+
+// 后台任务的操作人（手动触发时记录触发人）
+const (
+	ActorSystem           = "system"
+	actorStagingPromotion = "system:staging_promotion"
+	actorDecayEviction    = "system:decay_eviction"
+	actorLTMDeduplication = "system:ltm_dedup"
+	actorRecallRepair     = "system:recall_repair" // 检索命中近似重复时的自愈合并
+)
+
+// auditQueryDefaultLimit 审计查询默认每页条数
+const auditQueryDefaultLimit = 50
+
+// AuditEntry 一条审计记录
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	TenantID  string                 `json:"tenant_id,omitempty"` // 系统级操作（告警规则、账号）为空
+	Actor     string                 `json:"actor"`
+	Action    string                 `json:"action"`
+	TargetID  string                 `json:"target_id,omitempty"`
+	UserID    string                 `json:"user_id,omitempty"` // 被操作记忆所属的终端用户
+	Detail    map[string]interface{} `json:"detail,omitempty"`
+}
+
+// AuditFilter 审计查询条件（空值表示不限）
+type AuditFilter struct {
+	TenantID string // 非空时返回该租户与系统级的记录
+	Actor    string
+	UserID   string
+	Action   string
+	Start    time.Time
+	End      time.Time
+	Limit    int
+	Offset   int
+}
+
+type actorKey struct{}
+
+// WithActor 标记后续变更操作的操作人（后台账号用户名或 apikey:<name>）
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom 读取 context 中的操作人，未设置时为 system
+func ActorFrom(ctx context.Context) string {
+	if actor, _ := ctx.Value(actorKey{}).(string); actor != "" {
+		return actor
+	}
+	return ActorSystem
+}
+
+// withJobActor 后台任务以任务名作为操作人；通过管理接口手动触发时保留触发人
+func withJobActor(ctx context.Context, job string) context.Context {
+	if _, ok := ctx.Value(actorKey{}).(string); ok {
+		return ctx
+	}
+	return WithActor(ctx, job)
+}
+
+// AuditRepository 审计日志存储接口（只追加，不提供修改与删除）
+type AuditRepository interface {
+	Append(ctx context.Context, entry *AuditEntry) error
+	Query(ctx context.Context, filter AuditFilter) ([]AuditEntry, int, error)
+}
+
+// MySQLAuditRepository MySQL实现（audit_log 表）
+type MySQLAuditRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLAuditRepository 创建MySQL审计存储
+func NewMySQLAuditRepository(db *sql.DB) *MySQLAuditRepository {
+	return &MySQLAuditRepository{db: db}
+}
+
+// Append 写入一条审计记录
+func (r *MySQLAuditRepository) Append(ctx context.Context, entry *AuditEntry) error {
+	detail, _ := json.Marshal(entry.Detail)
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_log (timestamp, tenant_id, actor, action, target_id, user_id, detail)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.Timestamp, sql.NullString{String: entry.TenantID, Valid: entry.TenantID != ""},
+		entry.Actor, entry.Action, entry.TargetID, entry.UserID, string(detail),
+	)
+	if err != nil {
+		return err
+	}
+	entry.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// Query 按条件分页查询（按时间倒序）
+func (r *MySQLAuditRepository) Query(ctx context.Context, filter AuditFilter) ([]AuditEntry, int, error) {
+	where := " WHERE 1=1"
+	var args []interface{}
+
+	if filter.TenantID != "" {
+		where += " AND (tenant_id = ? OR tenant_id IS NULL)"
+		args = append(args, filter.TenantID)
+	}
+	if filter.Actor != "" {
+		where += " AND actor = ?"
+		args = append(args, filter.Actor)
+	}
+	if filter.UserID != "" {
+		where += " AND user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.Action != "" {
+		where += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if !filter.Start.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, filter.Start)
+	}
+	if !filter.End.IsZero() {
+		where += " AND timestamp <= ?"
+		args = append(args, filter.End)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT id, timestamp, tenant_id, actor, action, target_id, user_id, detail FROM audit_log" +
+		where + " ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?"
+	rows, err := r.db.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var e AuditEntry
+		var tenantID sql.NullString
+		var detail string
+		if err := rows.Scan(&e.ID, &e.Timestamp, &tenantID, &e.Actor, &e.Action, &e.TargetID, &e.UserID, &detail); err != nil {
+			return nil, 0, err
+		}
+		e.TenantID = tenantID.String
+		json.Unmarshal([]byte(detail), &e.Detail)
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// RecordAudit 追加审计记录（操作人、时间未填写时从 context / 当前时间补全）。
+// 审计失败只记录日志，不影响已完成的变更；未配置 MySQL 时仅写入日志。
+func (m *Manager) RecordAudit(ctx context.Context, entry AuditEntry) {
+	if entry.Actor == "" {
+		entry.Actor = ActorFrom(ctx)
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	if m.auditRepo == nil {
+		logger.Info("audit", "actor", entry.Actor, "action", entry.Action, "tenant", entry.TenantID,
+			"target_id", entry.TargetID, "user_id", entry.UserID)
+		return
+	}
+	if err := m.auditRepo.Append(ctx, &entry); err != nil {
+		logger.Error("写入审计日志失败", err, "action", entry.Action, "target_id", entry.TargetID)
+	}
+}
+
+// auditMemory 记录当前租户下的记忆变更
+func (m *Manager) auditMemory(ctx context.Context, action, targetID, userID string, detail map[string]interface{}) {
+	m.RecordAudit(ctx, AuditEntry{
+		TenantID: types.TenantFrom(ctx),
+		Action:   action,
+		TargetID: targetID,
+		UserID:   userID,
+		Detail:   detail,
+	})
+}
+
+// QueryAudit 查询当前租户（含系统级操作）的审计记录
+func (m *Manager) QueryAudit(ctx context.Context, filter AuditFilter) ([]AuditEntry, int, error) {
+	if m.auditRepo == nil {
+		return nil, 0, fmt.Errorf("audit log requires MySQL")
+	}
+	filter.TenantID = types.TenantFrom(ctx)
+	if filter.Limit <= 0 {
+		filter.Limit = auditQueryDefaultLimit
+	}
+	return m.auditRepo.Query(ctx, filter)
+}
+
+// recordUserID LTM / STM 记录所属的终端用户
+func recordUserID(rec *types.Record) string {
+	userID, _ := rec.Metadata["user_id"].(string)
+	return userID
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"ai-memory/pkg/llm"
 	"ai-memory/pkg/logger"
 	"ai-memory/pkg/types"
 	"context"
@@ -20,15 +21,25 @@ func (m *Manager) BatchPromoteToLTM(ctx context.Context, entries []*types.Stagin
 	var successIDs []string
 
 	for _, entry := range entries {
+		entryCtx := llm.WithUserID(types.WithTenant(ctx, entry.Tenant()), entry.UserID)
+		language := promptLanguageOf(entry.PromptVersions)
+		if language == "" {
+			language = m.resolvePromptLanguage(entryCtx, entry.UserID, nil)
+		}
+		entryCtx = WithPromptLanguage(entryCtx, language)
+		entryCtx, trace := withPromptTrace(entryCtx)
+
 		// 提取结构化标签
-		tags, entities, err := m.judge.ExtractStructuredTags(ctx, entry.Content, entry.Category)
+		tags, entities, err := m.judge.ExtractStructuredTags(entryCtx, entry.Content, entry.Category)
+		versions := mergePromptVersions(entry.PromptVersions, trace.Versions())
 		if err != nil {
 			tags = entry.ExtractedTags
 			entities = entry.ExtractedEntities
+			delete(versions, PromptExtractTags)
 		}
 
 		// 生成Embedding
-		vector, err := m.embedder.EmbedQuery(ctx, entry.Content)
+		vector, err := m.embedder.EmbedQuery(entryCtx, entry.Content)
 		if err != nil {
 			logger.Error("生成embedding失败", err, "entry_id", entry.ID)
 			continue
@@ -36,6 +47,7 @@ func (m *Manager) BatchPromoteToLTM(ctx context.Context, entries []*types.Stagin
 
 		// 构建记录
 		metadata := map[string]interface{}{
+			metaTenantID:        entry.Tenant(),
 			"user_id":           entry.UserID,
 			"created_at":        entry.FirstSeenAt,
 			"tags":              tags,
@@ -46,6 +58,7 @@ func (m *Manager) BatchPromoteToLTM(ctx context.Context, entries []*types.Stagin
 			"decay_score":       1.0,
 			"source_type":       "staging",
 			"confidence_origin": entry.ConfidenceScore,
+			"prompt_versions":   versions,
 		}
 
 		ltmRecord := types.Record{
@@ -81,7 +94,8 @@ func (m *Manager) BatchPromoteToLTM(ctx context.Context, entries []*types.Stagin
 
 // JudgeAndStageFromSTM的缓存优化版本
 func (m *Manager) JudgeAndStageFromSTMCached(ctx context.Context, userID, sessionID string) error {
-	key := fmt.Sprintf("memory:stm:%s:%s", userID, sessionID)
+	ctx = llm.WithUserID(ctx, userID)
+	key := stmKey(ctx, userID, sessionID)
 
 	stmData, err := m.stmStore.LRange(ctx, key, 0, -1)
 	if err != nil {
@@ -94,6 +108,13 @@ func (m *Manager) JudgeAndStageFromSTMCached(ctx context.Context, userID, sessio
 
 	batchSize := m.cfg.STMBatchJudgeSize
 	for i := 0; i < len(stmData); i += batchSize {
+		// 预算用尽时推迟剩余批次
+		if err := m.budget.Check(ctx, userID); err != nil {
+			m.budget.recordDegraded(BudgetActionDefer, len(stmData)-i)
+			logger.System("⏸️ LLM预算已用尽，推迟判定", "user", userID, "session", sessionID, "pending", len(stmData)-i, "reason", err.Error())
+			return nil
+		}
+
 		end := i + batchSize
 		if end > len(stmData) {
 			end = len(stmData)
@@ -102,6 +123,7 @@ func (m *Manager) JudgeAndStageFromSTMCached(ctx context.Context, userID, sessio
 		batch := stmData[i:end]
 		var needsJudgment []string
 		var cachedResults []*types.JudgeResult
+		var records []types.Record
 
 		for _, data := range batch {
 			var rec types.Record
@@ -109,12 +131,14 @@ func (m *Manager) JudgeAndStageFromSTMCached(ctx context.Context, userID, sessio
 				// 尝试从缓存获取（如果Manager有monitor）
 				// 这里简化处理，直接判定
 				needsJudgment = append(needsJudgment, rec.Content)
+				records = append(records, rec)
 			}
 		}
 
 		// 批量判定
 		if len(needsJudgment) > 0 {
-			results, err := m.judge.JudgeBatch(ctx, needsJudgment)
+			judgeCtx := WithPromptLanguage(ctx, m.resolvePromptLanguage(ctx, userID, records))
+			results, err := m.judge.JudgeBatch(judgeCtx, needsJudgment)
 			if err != nil {
 				logger.Error("批量判定失败", err)
 				continue
@@ -124,6 +148,9 @@ func (m *Manager) JudgeAndStageFromSTMCached(ctx context.Context, userID, sessio
 
 		// 添加到Staging
 		for i, result := range cachedResults {
+			if result == nil {
+				continue
+			}
 			if result.ShouldStage && result.ValueScore >= m.cfg.StagingValueThreshold {
 				if err := m.stagingStore.AddOrIncrement(ctx, userID, sessionID, needsJudgment[i], result, m.embedder); err != nil {
 					logger.Error("添加到暂存区失败", err)
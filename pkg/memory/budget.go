@@ -0,0 +1,323 @@
+package memory
+
+import (
+	"ai-memory/pkg/llm"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/types"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted 当日 LLM 预算已用尽
+var ErrBudgetExhausted = errors.New("llm budget exhausted")
+
+// 预算用尽时的降级方式
+const (
+	BudgetActionDefer     = "defer"     // 推迟判定，记录留在 STM
+	BudgetActionHeuristic = "heuristic" // 启发式评分，不调用 LLM
+)
+
+// BudgetLimits 每日预算上限（0 表示不限制）
+type BudgetLimits struct {
+	Tokens int64 `json:"tokens"`
+	Calls  int64 `json:"calls"`
+}
+
+func (l BudgetLimits) enabled() bool {
+	return l.Tokens > 0 || l.Calls > 0
+}
+
+func (l BudgetLimits) exceededBy(u *budgetUsage) bool {
+	return (l.Tokens > 0 && u.Tokens >= l.Tokens) || (l.Calls > 0 && u.Calls >= l.Calls)
+}
+
+type budgetUsage struct {
+	Tokens int64 `json:"tokens"`
+	Calls  int64 `json:"calls"`
+}
+
+// BudgetStatus 当日预算状态（用于告警与状态展示）
+type BudgetStatus struct {
+	Day             string       `json:"day"`
+	UserLimits      BudgetLimits `json:"user_limits"`
+	GlobalLimits    BudgetLimits `json:"global_limits"`
+	GlobalUsage     budgetUsage  `json:"global_usage"`
+	GlobalExhausted bool         `json:"global_exhausted"`
+	ExhaustedUsers  []string     `json:"exhausted_users"` // 非默认租户的用户带 tenant:<id>: 前缀
+	Deferred        int64        `json:"deferred"`        // 因预算推迟判定的次数
+	Heuristic       int64        `json:"heuristic"`       // 使用启发式评分的记录数
+}
+
+// BudgetManager 按自然日统计 LLM 用量（对话类调用，向量化不计入），
+// 在 STM 判定前检查终端用户与全局预算
+type BudgetManager struct {
+	mu           sync.Mutex
+	userLimits   BudgetLimits
+	globalLimits BudgetLimits
+
+	day       string
+	global    budgetUsage
+	users     map[string]*budgetUsage
+	deferred  int64
+	heuristic int64
+}
+
+// NewBudgetManager 创建预算管理器
+func NewBudgetManager(userLimits, globalLimits BudgetLimits) *BudgetManager {
+	return &BudgetManager{
+		userLimits:   userLimits,
+		globalLimits: globalLimits,
+		day:          budgetDay(time.Now()),
+		users:        make(map[string]*budgetUsage),
+	}
+}
+
+func budgetDay(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// budgetDayStart 预算日的零点（与 budgetDay 同一时区，不依赖数据库会话时区）
+func budgetDayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Enabled 是否配置了任一预算
+func (b *BudgetManager) Enabled() bool {
+	return b != nil && (b.userLimits.enabled() || b.globalLimits.enabled())
+}
+
+// rollover 跨天时清零（调用方持有锁）
+func (b *BudgetManager) rollover(now time.Time) {
+	if day := budgetDay(now); day != b.day {
+		b.day = day
+		b.global = budgetUsage{}
+		b.users = make(map[string]*budgetUsage)
+		b.deferred = 0
+		b.heuristic = 0
+	}
+}
+
+// RecordUsage 累计一次调用的用量（由 MetricsCollector 转发）
+func (b *BudgetManager) RecordUsage(event llm.UsageEvent) {
+	if !b.Enabled() || event.Operation == llm.OperationEmbed {
+		return
+	}
+	tokens := int64(event.Usage.PromptTokens + event.Usage.CompletionTokens)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(event.Timestamp)
+
+	b.global.Tokens += tokens
+	b.global.Calls++
+	if event.UserID != "" {
+		key := budgetUserKey(event.TenantID, event.UserID)
+		u, ok := b.users[key]
+		if !ok {
+			u = &budgetUsage{}
+			b.users[key] = u
+		}
+		u.Tokens += tokens
+		u.Calls++
+	}
+}
+
+// budgetUserKey 终端用户预算按租户区分（默认租户与升级前一致，直接使用 userID）
+func budgetUserKey(tenantID, userID string) string {
+	return types.TenantKey(tenantOrDefault(tenantID), userID)
+}
+
+// Check 检查 context 所属租户下终端用户的当日预算，用尽时返回 ErrBudgetExhausted
+func (b *BudgetManager) Check(ctx context.Context, userID string) error {
+	if !b.Enabled() {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(time.Now())
+
+	if b.globalLimits.exceededBy(&b.global) {
+		return fmt.Errorf("%w: global (tokens=%d, calls=%d)", ErrBudgetExhausted, b.global.Tokens, b.global.Calls)
+	}
+	if u, ok := b.users[budgetUserKey(types.TenantFrom(ctx), userID)]; ok && b.userLimits.exceededBy(u) {
+		return fmt.Errorf("%w: user %s (tokens=%d, calls=%d)", ErrBudgetExhausted, userID, u.Tokens, u.Calls)
+	}
+	return nil
+}
+
+// recordDegraded 记录一次降级（推迟或启发式评分）
+func (b *BudgetManager) recordDegraded(action string, count int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(time.Now())
+	if action == BudgetActionHeuristic {
+		b.heuristic += int64(count)
+	} else {
+		b.deferred += int64(count)
+	}
+}
+
+// Status 返回当日预算状态
+func (b *BudgetManager) Status() BudgetStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(time.Now())
+
+	status := BudgetStatus{
+		Day:             b.day,
+		UserLimits:      b.userLimits,
+		GlobalLimits:    b.globalLimits,
+		GlobalUsage:     b.global,
+		GlobalExhausted: b.globalLimits.exceededBy(&b.global),
+		ExhaustedUsers:  make([]string, 0),
+		Deferred:        b.deferred,
+		Heuristic:       b.heuristic,
+	}
+	if b.userLimits.enabled() {
+		for userID, u := range b.users {
+			if b.userLimits.exceededBy(u) {
+				status.ExhaustedUsers = append(status.ExhaustedUsers, userID)
+			}
+		}
+		sort.Strings(status.ExhaustedUsers)
+	}
+	return status
+}
+
+// forTenant 单租户视角的预算状态：只保留该租户预算用尽的用户，不返回全局用量与降级计数
+func (s BudgetStatus) forTenant(tenantID string) BudgetStatus {
+	users := make([]string, 0)
+	for _, key := range s.ExhaustedUsers {
+		if userTenant, _ := types.SplitTenantKey(key); userTenant == tenantID {
+			users = append(users, key)
+		}
+	}
+	s.ExhaustedUsers = users
+	s.GlobalUsage = budgetUsage{}
+	s.Deferred, s.Heuristic = 0, 0
+	return s
+}
+
+// LoadTodayUsage 从 llm_usage 表恢复当日已用量（重启后预算不清零）
+func (b *BudgetManager) LoadTodayUsage(ctx context.Context, db *sql.DB) error {
+	if !b.Enabled() || db == nil {
+		return nil
+	}
+
+	query := `
+		SELECT tenant_id, user_id, SUM(calls), SUM(prompt_tokens + completion_tokens)
+		FROM llm_usage
+		WHERE timestamp >= ? AND operation <> ?
+		GROUP BY tenant_id, user_id
+	`
+	now := time.Now()
+	rows, err := db.QueryContext(ctx, query, budgetDayStart(now), llm.OperationEmbed)
+	if err != nil {
+		return fmt.Errorf("查询当日LLM用量失败: %w", err)
+	}
+	defer rows.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(now)
+
+	for rows.Next() {
+		var tenantID, userID string
+		var usage budgetUsage
+		if err := rows.Scan(&tenantID, &userID, &usage.Calls, &usage.Tokens); err != nil {
+			continue
+		}
+		b.global.Tokens += usage.Tokens
+		b.global.Calls += usage.Calls
+		if userID != "" {
+			key := budgetUserKey(tenantID, userID)
+			u, ok := b.users[key]
+			if !ok {
+				u = &budgetUsage{}
+				b.users[key] = u
+			}
+			u.Tokens += usage.Tokens
+			u.Calls += usage.Calls
+		}
+	}
+	logger.System("LLM budget usage restored", "day", b.day, "tokens", b.global.Tokens, "calls", b.global.Calls)
+	return rows.Err()
+}
+
+// ========== 启发式评分（预算用尽时的降级判定） ==========
+
+var (
+	heuristicPreferenceWords = []string{"喜欢", "讨厌", "爱吃", "偏好", "习惯", "不想", "受不了", "prefer", "favorite", "i like", "i love", "i hate", "i don't like"}
+	heuristicGoalWords       = []string{"打算", "计划", "目标", "准备", "想要", "希望", "梦想", "plan to", "my goal", "i want to", "going to", "hope to"}
+	heuristicFactWords       = []string{"我是", "我叫", "我的", "我在", "住在", "工作", "生日", "岁", "过敏", "my name", "i am", "i'm", "i live", "i work", "my birthday", "allergic"}
+	heuristicNoiseWords      = []string{"你好", "谢谢", "好的", "嗯", "哈哈", "再见", "hello", "hi", "thanks", "thank you", "ok", "bye"}
+)
+
+// heuristicJudge 不调用 LLM 的粗略判定：只看用户发言中的自我描述类关键词
+// 信心固定为 confidence（通常取 STAGING_CONFIDENCE_LOW），结果需要人工确认后才会晋升。
+func heuristicJudge(content string, confidence float64) *types.JudgeResult {
+	text := strings.ToLower(strings.TrimSpace(heuristicUserText(content)))
+	result := &types.JudgeResult{
+		ValueScore:      0.1,
+		ConfidenceScore: confidence,
+		Category:        types.CategoryNoise,
+		Reason:          "heuristic: LLM budget exhausted",
+		Tags:            []string{},
+		Entities:        map[string]string{},
+		PromptVersions:  map[string]string{PromptJudgeBatch: "heuristic"},
+	}
+
+	if len([]rune(text)) < 6 {
+		return result
+	}
+	for _, w := range heuristicNoiseWords {
+		if text == w {
+			return result
+		}
+	}
+
+	switch {
+	case containsAny(text, heuristicPreferenceWords):
+		result.Category = types.CategoryPreference
+	case containsAny(text, heuristicGoalWords):
+		result.Category = types.CategoryGoal
+	case containsAny(text, heuristicFactWords):
+		result.Category = types.CategoryFact
+	default:
+		result.ValueScore = 0.3
+		return result
+	}
+
+	result.ValueScore = 0.6
+	if len([]rune(text)) >= 20 {
+		result.ValueScore = 0.7
+	}
+	result.ShouldStage = true
+	result.Tags = []string{string(result.Category)}
+	return result
+}
+
+// heuristicUserText 取出 "User: ...\nAI: ..." 中的用户发言
+func heuristicUserText(content string) string {
+	text := strings.TrimPrefix(content, "User: ")
+	if idx := strings.Index(text, "\nAI: "); idx >= 0 {
+		text = text[:idx]
+	}
+	return text
+}
+
+func containsAny(text string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(text, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"ai-memory/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer 计算文本的 token 数，可替换为与目标模型一致的精确实现（如 tiktoken）
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenizer 无依赖的估算实现：中日韩字符按 1 token/字，其他连续字符按 4 字符/token
+type HeuristicTokenizer struct{}
+
+func (HeuristicTokenizer) CountTokens(text string) int {
+	tokens := 0
+	run := 0
+	flush := func() {
+		tokens += (run + 3) / 4
+		run = 0
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			run++
+		}
+	}
+	flush()
+	return tokens
+}
+
+const (
+	// defaultContextMaxTokens 未指定预算时的默认总预算
+	defaultContextMaxTokens = 2000
+	// minTruncateTokens 剩余预算低于该值时不再截断放入半条内容
+	minTruncateTokens = 16
+	truncationMarker  = "…"
+)
+
+// ErrInvalidContextOptions 上下文组装参数错误（API 层返回 400）
+var ErrInvalidContextOptions = errors.New("invalid context options")
+
+// defaultContextPriority 预算分配的默认优先级：当前对话最重要，其次是已确认的长期事实
+var defaultContextPriority = []string{types.ContextSectionSTM, types.ContextSectionLTM, types.ContextSectionStaging}
+
+// contextRenderOrder 输出顺序：背景事实在前，最近对话紧贴用户问题
+var contextRenderOrder = []string{types.ContextSectionLTM, types.ContextSectionStaging, types.ContextSectionSTM}
+
+var contextSectionTitles = map[string]string{
+	types.ContextSectionLTM:     "## Long-term memory",
+	types.ContextSectionStaging: "## Recently observed facts (unconfirmed)",
+	types.ContextSectionSTM:     "## Recent conversation",
+}
+
+// SetTokenizer 替换上下文组装使用的 Tokenizer
+func (m *Manager) SetTokenizer(tokenizer Tokenizer) {
+	m.tokenizer = tokenizer
+}
+
+// AssembleContext 在 token 预算内组装可直接注入 Prompt 的上下文
+// 各分区按优先级依次分配剩余预算（可再受分区预算限制）；分区内按重要性取条目，
+// 超出预算的条目被丢弃，最后一条在剩余预算足够时截断保留。
+func (m *Manager) AssembleContext(ctx context.Context, userID, sessionID string, opts types.ContextOptions) (*types.ContextResult, error) {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = m.cfg.ContextDefaultMaxTokens
+		if opts.MaxTokens <= 0 {
+			opts.MaxTokens = defaultContextMaxTokens
+		}
+	}
+	if opts.TopK <= 0 {
+		opts.TopK = defaultRecallTopK
+	}
+
+	priority, err := normalizeContextPriority(opts.Priority)
+	if err != nil {
+		return nil, err
+	}
+	for name := range opts.SectionBudgets {
+		if _, ok := contextSectionTitles[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown section %q in section_budgets", ErrInvalidContextOptions, name)
+		}
+	}
+
+	candidates, err := m.collectContextCandidates(ctx, userID, sessionID, opts.RecallOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenizer := m.tokenizer
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+
+	remaining := opts.MaxTokens
+	sections := make(map[string]*types.ContextSection, len(priority))
+	lines := make(map[string][]string, len(priority))
+	for _, name := range priority {
+		budget := remaining
+		if limit, ok := opts.SectionBudgets[name]; ok && limit >= 0 && limit < budget {
+			budget = limit
+		}
+
+		section, sectionLines := fillContextSection(tokenizer, name, candidates[name], budget)
+		remaining -= section.Tokens
+		sections[name] = section
+		lines[name] = sectionLines
+	}
+
+	// 只登记预算内实际注入上下文的 LTM 记录
+	m.trackAccess(sections[types.ContextSectionLTM].Records)
+
+	result := &types.ContextResult{MaxTokens: opts.MaxTokens}
+	var blocks []string
+	for _, name := range contextRenderOrder {
+		section := sections[name]
+		result.Sections = append(result.Sections, *section)
+		result.Tokens += section.Tokens
+		if section.Included == 0 {
+			continue
+		}
+
+		sectionLines := lines[name]
+		if name == types.ContextSectionSTM {
+			// 按重要性（由新到旧）选取，按时间顺序输出
+			for i, j := 0, len(sectionLines)-1; i < j; i, j = i+1, j-1 {
+				sectionLines[i], sectionLines[j] = sectionLines[j], sectionLines[i]
+			}
+		}
+		blocks = append(blocks, contextSectionTitles[name]+"\n"+strings.Join(sectionLines, "\n"))
+	}
+	result.Context = strings.Join(blocks, "\n\n")
+	return result, nil
+}
+
+// collectContextCandidates 读取各分区的候选条目，已按重要性降序排列
+func (m *Manager) collectContextCandidates(ctx context.Context, userID, sessionID string, opts types.RecallOptions) (map[string][]types.Record, error) {
+	candidates := make(map[string][]types.Record, 3)
+
+	// STM：由新到旧
+	stm := m.fetchSTM(ctx, userID, sessionID, m.cfg.STMWindowSize)
+	for i, j := 0, len(stm)-1; i < j; i, j = i+1, j-1 {
+		stm[i], stm[j] = stm[j], stm[i]
+	}
+	candidates[types.ContextSectionSTM] = stm
+
+	// Staging：信心分数高、出现次数多者优先
+	staging := m.recallStaging(ctx, userID, sessionID, opts)
+	sort.SliceStable(staging, func(i, j int) bool {
+		ci, _ := metaFloat(staging[i].Metadata["confidence_score"])
+		cj, _ := metaFloat(staging[j].Metadata["confidence_score"])
+		if ci != cj {
+			return ci > cj
+		}
+		return metaInt(staging[i].Metadata["occurrence_count"]) > metaInt(staging[j].Metadata["occurrence_count"])
+	})
+	candidates[types.ContextSectionStaging] = staging
+
+	// LTM：保持检索的相关性顺序
+	ltm, err := m.recallLTM(ctx, userID, opts)
+	if err != nil {
+		return nil, err
+	}
+	candidates[types.ContextSectionLTM] = ltm
+
+	return candidates, nil
+}
+
+// fillContextSection 在预算内为单个分区选取条目，返回分区统计与渲染后的行
+func fillContextSection(tokenizer Tokenizer, name string, records []types.Record, budget int) (*types.ContextSection, []string) {
+	section := &types.ContextSection{Name: name, Budget: budget, Records: []types.Record{}}
+	if len(records) == 0 || budget <= 0 {
+		section.Dropped = len(records)
+		return section, nil
+	}
+
+	headerTokens := tokenizer.CountTokens(contextSectionTitles[name])
+	used := headerTokens
+
+	var lines []string
+	for _, rec := range records {
+		line := formatContextLine(name, rec)
+		cost := tokenizer.CountTokens(line)
+
+		if used+cost <= budget {
+			lines = append(lines, line)
+			section.Records = append(section.Records, rec)
+			used += cost
+			continue
+		}
+
+		// 预算不足：剩余空间足够时截断保留当前条目，之后的条目全部丢弃
+		if avail := budget - used; avail >= minTruncateTokens {
+			if truncated := truncateToTokens(tokenizer, line, avail); truncated != "" {
+				lines = append(lines, truncated)
+				section.Records = append(section.Records, rec)
+				section.Truncated = true
+				used += tokenizer.CountTokens(truncated)
+			}
+		}
+		break
+	}
+
+	section.Included = len(lines)
+	section.Dropped = len(records) - section.Included
+	if section.Included > 0 {
+		section.Tokens = used
+	}
+	return section, lines
+}
+
+// formatContextLine 渲染单条记忆
+func formatContextLine(section string, rec types.Record) string {
+	content := strings.TrimSpace(rec.Content)
+	switch section {
+	case types.ContextSectionSTM:
+		return content
+	default:
+		if category, ok := rec.Metadata["category"].(string); ok && category != "" {
+			return fmt.Sprintf("- [%s] %s", category, content)
+		}
+		return "- " + content
+	}
+}
+
+// truncateToTokens 截取不超过 maxTokens 的最长前缀（附加省略号），按字符二分查找
+func truncateToTokens(tokenizer Tokenizer, text string, maxTokens int) string {
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if tokenizer.CountTokens(string(runes[:mid])+truncationMarker) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo == 0 {
+		return ""
+	}
+	return strings.TrimRightFunc(string(runes[:lo]), unicode.IsSpace) + truncationMarker
+}
+
+// normalizeContextPriority 校验优先级，未列出的分区按默认顺序追加在后
+func normalizeContextPriority(priority []string) ([]string, error) {
+	seen := make(map[string]bool, len(defaultContextPriority))
+	var result []string
+	for _, name := range priority {
+		if _, ok := contextSectionTitles[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown section %q", ErrInvalidContextOptions, name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	for _, name := range defaultContextPriority {
+		if !seen[name] {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"ai-memory/pkg/logger"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// CachingEmbedder 向量化缓存装饰器：key 为 模型 + 内容 SHA-256，
+// 按顺序查询多级缓存（如 进程内 LRU → Redis），下级命中时回填上级，全部未命中才调用底层 Embedder。
+// 缓存读写失败只记录日志，不影响向量化本身。
+type CachingEmbedder struct {
+	embedder Embedder
+	model    string
+	caches   []EmbeddingCache
+}
+
+// NewCachingEmbedder model 用于区分不同模型的向量（如 "openai/text-embedding-3-small"）
+func NewCachingEmbedder(embedder Embedder, model string, caches ...EmbeddingCache) *CachingEmbedder {
+	return &CachingEmbedder{
+		embedder: embedder,
+		model:    model,
+		caches:   caches,
+	}
+}
+
+func (c *CachingEmbedder) cacheKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return c.model + ":" + hex.EncodeToString(sum[:])
+}
+
+// lookup 按顺序查询各级缓存，命中下级时回填上级
+func (c *CachingEmbedder) lookup(ctx context.Context, key string) ([]float32, bool) {
+	for i, cache := range c.caches {
+		vector, ok, err := cache.Get(ctx, key)
+		if err != nil {
+			logger.Error("读取向量缓存失败", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		for _, upper := range c.caches[:i] {
+			if err := upper.Set(ctx, key, vector); err != nil {
+				logger.Error("回填向量缓存失败", err)
+			}
+		}
+		return vector, true
+	}
+	return nil, false
+}
+
+func (c *CachingEmbedder) store(ctx context.Context, key string, vector []float32) {
+	for _, cache := range c.caches {
+		if err := cache.Set(ctx, key, vector); err != nil {
+			logger.Error("写入向量缓存失败", err)
+		}
+	}
+}
+
+func (c *CachingEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	key := c.cacheKey(text)
+	if vector, ok := c.lookup(ctx, key); ok {
+		GetGlobalMetrics().RecordEmbeddingCache(1, 0)
+		return vector, nil
+	}
+	GetGlobalMetrics().RecordEmbeddingCache(0, 1)
+
+	vector, err := c.embedder.EmbedQuery(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, key, vector)
+	return vector, nil
+}
+
+// EmbedDocuments 只对未命中缓存的文本调用底层 Embedder（保持原有顺序）
+func (c *CachingEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+	var missTexts []string
+	var missIndices []int
+	for i, text := range texts {
+		keys[i] = c.cacheKey(text)
+		if vector, ok := c.lookup(ctx, keys[i]); ok {
+			vectors[i] = vector
+			continue
+		}
+		missTexts = append(missTexts, text)
+		missIndices = append(missIndices, i)
+	}
+	GetGlobalMetrics().RecordEmbeddingCache(len(texts)-len(missTexts), len(missTexts))
+
+	if len(missTexts) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := c.embedder.EmbedDocuments(ctx, missTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missTexts) {
+		return nil, fmt.Errorf("embedding count mismatch: got %d, want %d", len(embedded), len(missTexts))
+	}
+	for k, idx := range missIndices {
+		vectors[idx] = embedded[k]
+		c.store(ctx, keys[idx], embedded[k])
+	}
+	return vectors, nil
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"ai-memory/pkg/llm"
 	"ai-memory/pkg/logger"
 	"ai-memory/pkg/types"
 	"context"
@@ -16,7 +17,8 @@ import (
 // JudgeAndStageFromSTM 从 STM判定并添加到Staging
 // 这个方法在Add()后可以调用，批量处理STM中的新记忆
 func (m *Manager) JudgeAndStageFromSTM(ctx context.Context, userID, sessionID string) error {
-	key := fmt.Sprintf("memory:stm:%s:%s", userID, sessionID)
+	ctx = llm.WithUserID(ctx, userID)
+	key := stmKey(ctx, userID, sessionID)
 
 	// 获取STM数据
 	stmData, err := m.stmStore.LRange(ctx, key, 0, -1)
@@ -61,6 +63,7 @@ func (m *Manager) JudgeAndStageFromSTM(ctx context.Context, userID, sessionID st
 	}
 
 	logger.System("STM判定开始", "total", len(stmData), "new", len(toJudge), "user", userID, "session", sessionID)
+	ctx = WithPromptLanguage(ctx, m.resolvePromptLanguage(ctx, userID, toJudge))
 
 	// 批量判定（每批最多10条）
 	batchSize := m.cfg.STMBatchJudgeSize
@@ -70,6 +73,17 @@ func (m *Manager) JudgeAndStageFromSTM(ctx context.Context, userID, sessionID st
 			end = len(toJudge)
 		}
 
+		// 0. 预算检查：用尽时推迟判定（记录留在 STM），或降级为启发式评分
+		degraded := false
+		if err := m.budget.Check(ctx, userID); err != nil {
+			if m.cfg.LLMBudgetExhaustedAction != BudgetActionHeuristic {
+				m.budget.recordDegraded(BudgetActionDefer, len(toJudge)-i)
+				logger.System("⏸️ LLM预算已用尽，推迟判定", "user", userID, "session", sessionID, "pending", len(toJudge)-i, "reason", err.Error())
+				return nil
+			}
+			degraded = true
+		}
+
 		batch := toJudge[i:end]
 		contents := make([]string, 0, len(batch))
 		results := make([]*types.JudgeResult, len(batch))
@@ -78,7 +92,7 @@ func (m *Manager) JudgeAndStageFromSTM(ctx context.Context, userID, sessionID st
 		// 1. 尝试从缓存获取
 		if m.monitor != nil {
 			for j, rec := range batch {
-				if cached, ok := m.monitor.GetJudgeResultFromCache(rec.Content); ok {
+				if cached, ok := m.monitor.GetJudgeResultFromCache(ctx, rec.Content); ok {
 					results[j] = cached
 				} else {
 					contents = append(contents, rec.Content)
@@ -94,8 +108,14 @@ func (m *Manager) JudgeAndStageFromSTM(ctx context.Context, userID, sessionID st
 			}
 		}
 
-		// 2. 对于缓存未命中的，调用判定模型
-		if len(contents) > 0 {
+		// 2. 对于缓存未命中的，调用判定模型（预算用尽时使用启发式评分，结果不写入缓存）
+		if len(contents) > 0 && degraded {
+			for k, content := range contents {
+				results[toLLMIndices[k]] = heuristicJudge(content, m.cfg.StagingConfidenceLow)
+			}
+			m.budget.recordDegraded(BudgetActionHeuristic, len(contents))
+			logger.System("⚠️ LLM预算已用尽，使用启发式评分", "user", userID, "count", len(contents))
+		} else if len(contents) > 0 {
 			llmResults, err := m.judge.JudgeBatch(ctx, contents)
 			if err != nil {
 				logger.Error("批量判定失败", err)
@@ -103,11 +123,15 @@ func (m *Manager) JudgeAndStageFromSTM(ctx context.Context, userID, sessionID st
 				continue
 			}
 			for k, res := range llmResults {
+				if res == nil {
+					// 单条判定未通过校验，留在 STM 中等待下次判定
+					continue
+				}
 				idx := toLLMIndices[k]
 				results[idx] = res
 				// 存入缓存
 				if m.monitor != nil {
-					m.monitor.SetJudgeResultCache(batch[idx].Content, res)
+					m.monitor.SetJudgeResultCache(ctx, batch[idx].Content, res)
 				}
 			}
 		}
@@ -122,28 +146,44 @@ func (m *Manager) JudgeAndStageFromSTM(ctx context.Context, userID, sessionID st
 			// 日志：打印判定结果，方便排查
 			logger.System("STM判定结果", "index", j, "score", result.ValueScore, "stage", result.ShouldStage, "critical", result.IsCritical, "cat", result.Category)
 
-			if result.IsCritical || (result.ShouldStage && result.ValueScore >= m.cfg.StagingValueThreshold) {
-				// 【优化】先总结重构，存储精炼后的内容到Staging
-				summary, err := m.judge.SummarizeAndRestructure(ctx, content, result.Category)
-				if err != nil {
-					logger.Error("总结重构失败，使用原文", err)
-					summary = content // 降级：使用原始内容
+			staged := result.IsCritical || (result.ShouldStage && result.ValueScore >= m.cfg.StagingValueThreshold)
+			if m.judgeObserver != nil {
+				m.judgeObserver(batch[j], result, staged)
+			}
+
+			if staged {
+				// 【优化】先总结重构，存储精炼后的内容到Staging（预算用尽时直接使用原文）
+				summary := content
+				versions := result.PromptVersions
+				if !degraded {
+					sctx, trace := withPromptTrace(ctx)
+					var err error
+					summary, err = m.judge.SummarizeAndRestructure(sctx, content, result.Category)
+					if err != nil {
+						logger.Error("总结重构失败，使用原文", err)
+						summary = content // 降级：使用原始内容
+					} else {
+						versions = mergePromptVersions(result.PromptVersions, trace.Versions())
+					}
 				}
+				// 暂存条目记录判定与总结所用的模板版本（不修改缓存中的判定结果）
+				stagedResult := *result
+				stagedResult.PromptVersions = versions
 
 				// 存储总结后的内容（原始内容已在STM中，无需重复存储）
-				if result.IsCritical {
+				if result.IsCritical && !degraded {
 					// 【绿色通道】跳过暂存区，直接尝试晋升 LTM
 					logger.System("🚀 [Fast-Track] 发现关键事实/强烈意图，直连 LTM", "user", userID, "category", result.Category)
-					if err := m.promoteToLTMCorrelator(ctx, userID, summary, result.Category, result.ConfidenceScore, result.Tags, result.Entities, "fast-track"); err != nil {
+					if err := m.promoteToLTMCorrelator(ctx, userID, summary, result.Category, result.ConfidenceScore, result.Tags, result.Entities, versions, "fast-track"); err != nil {
 						logger.Error("绿色通道晋升失败", err)
 						// 降级：如果直连失败，依然存入 Staging 兜底
-						if err := m.stagingStore.AddOrIncrement(ctx, userID, sessionID, summary, result, m.embedder); err != nil {
+						if err := m.stagingStore.AddOrIncrement(ctx, userID, sessionID, summary, &stagedResult, m.embedder); err != nil {
 							logger.Error("降级存入暂存区失败", err)
 						}
 					}
 				} else {
 					// 正常流程：进入暂存区
-					if err := m.stagingStore.AddOrIncrement(ctx, userID, sessionID, summary, result, m.embedder); err != nil {
+					if err := m.stagingStore.AddOrIncrement(ctx, userID, sessionID, summary, &stagedResult, m.embedder); err != nil {
 						logger.Error("添加到暂存区失败", err)
 					}
 				}
@@ -164,6 +204,8 @@ func (m *Manager) JudgeAndStageFromSTM(ctx context.Context, userID, sessionID st
 // PromoteStagingToLTM 晋升Staging中的记忆到LTM
 // 后台调度器会定期调用此方法
 func (m *Manager) PromoteStagingToLTM(ctx context.Context) error {
+	ctx = withJobActor(ctx, actorStagingPromotion)
+
 	// 获取待晋升条目
 	entries, err := m.stagingStore.GetPendingEntries(
 		ctx,
@@ -175,10 +217,13 @@ func (m *Manager) PromoteStagingToLTM(ctx context.Context) error {
 	}
 
 	for _, entry := range entries {
+		// 待晋升条目跨全部租户，按条目所属租户写入 LTM
+		entryCtx := types.WithTenant(ctx, entry.Tenant())
+
 		// 判断信心水平
 		if entry.ConfidenceScore >= m.cfg.StagingConfidenceHigh {
 			// 高信心：自动晋升
-			if err := m.promoteToLTMCorrelator(ctx, entry.UserID, entry.Content, entry.Category, entry.ConfidenceScore, entry.ExtractedTags, entry.ExtractedEntities, "auto"); err != nil {
+			if err := m.promoteToLTMCorrelator(entryCtx, entry.UserID, entry.Content, entry.Category, entry.ConfidenceScore, entry.ExtractedTags, entry.ExtractedEntities, entry.PromptVersions, "auto"); err != nil {
 				logger.Error("自动晋升失败", err)
 			} else {
 				// 晋升成功后删除 Staging 条目
@@ -191,7 +236,7 @@ func (m *Manager) PromoteStagingToLTM(ctx context.Context) error {
 		} else {
 			// 低信心：直接删除
 			m.stagingStore.Delete(ctx, entry.ID)
-			GetGlobalMetrics().RecordPromotion(string(entry.Category), false)
+			GetGlobalMetrics().RecordPromotion(entry.Tenant(), string(entry.Category), false)
 		}
 	}
 
@@ -200,14 +245,23 @@ func (m *Manager) PromoteStagingToLTM(ctx context.Context) error {
 
 // promoteSingleEntry 保持 API 兼容性（可选）
 func (m *Manager) promoteSingleEntry(ctx context.Context, entry *types.StagingEntry, confirmedBy string) error {
-	if err := m.promoteToLTMCorrelator(ctx, entry.UserID, entry.Content, entry.Category, entry.ConfidenceScore, entry.ExtractedTags, entry.ExtractedEntities, confirmedBy); err != nil {
+	if err := m.promoteToLTMCorrelator(types.WithTenant(ctx, entry.Tenant()), entry.UserID, entry.Content, entry.Category, entry.ConfidenceScore, entry.ExtractedTags, entry.ExtractedEntities, entry.PromptVersions, confirmedBy); err != nil {
 		return err
 	}
 	return m.stagingStore.Delete(ctx, entry.ID)
 }
 
 // promoteToLTMCorrelator 核心晋升关联器：处理 LTM 写入前的去重、合并与结构化提取
-func (m *Manager) promoteToLTMCorrelator(ctx context.Context, userID, summary string, category types.MemoryCategory, confidence float64, fallbackTags []string, fallbackEntities map[string]string, confirmedBy string) error {
+func (m *Manager) promoteToLTMCorrelator(ctx context.Context, userID, summary string, category types.MemoryCategory, confidence float64, fallbackTags []string, fallbackEntities map[string]string, promptVersions map[string]string, confirmedBy string) error {
+	ctx = llm.WithUserID(ctx, userID)
+	if PromptLanguageFrom(ctx) == "" {
+		language := promptLanguageOf(promptVersions)
+		if language == "" {
+			language = m.resolvePromptLanguage(ctx, userID, nil)
+		}
+		ctx = WithPromptLanguage(ctx, language)
+	}
+	ctx, trace := withPromptTrace(ctx)
 	// 1. 生成 Embedding
 	vector, err := m.embedder.EmbedQuery(ctx, summary)
 	if err != nil {
@@ -215,7 +269,7 @@ func (m *Manager) promoteToLTMCorrelator(ctx context.Context, userID, summary st
 	}
 
 	// 2. 在 LTM 中搜索相似记忆进行去重/合并
-	filters := map[string]interface{}{"user_id": userID}
+	filters := userFilters(ctx, userID)
 	similarRecords, _ := m.vectorStore.Search(ctx, vector, 1, 0.95, filters)
 
 	if len(similarRecords) > 0 {
@@ -229,13 +283,10 @@ func (m *Manager) promoteToLTMCorrelator(ctx context.Context, userID, summary st
 
 		switch strategy {
 		case "update_existing":
-			if count, ok := existing.Metadata["access_count"].(int); ok {
-				existing.Metadata["access_count"] = count + 1
-			} else {
-				existing.Metadata["access_count"] = 1
-			}
+			existing.Metadata["access_count"] = metaInt(existing.Metadata["access_count"]) + 1
 			existing.Metadata["decay_score"] = 1.0
 			existing.Metadata["last_access_at"] = time.Now()
+			existing.Metadata["prompt_versions"] = mergePromptVersions(promptVersions, trace.Versions())
 			m.vectorStore.Update(ctx, existing)
 			logger.System("LTM去重：更新计数", "strategy", strategy, "existing_id", existing.ID)
 
@@ -245,35 +296,45 @@ func (m *Manager) promoteToLTMCorrelator(ctx context.Context, userID, summary st
 			if newVector != nil {
 				existing.Embedding = newVector
 			}
-			if count, ok := existing.Metadata["access_count"].(int); ok {
-				existing.Metadata["access_count"] = count + 1
-			}
+			existing.Metadata["access_count"] = metaInt(existing.Metadata["access_count"]) + 1
 			existing.Metadata["decay_score"] = 1.0
+			existing.Metadata["prompt_versions"] = mergePromptVersions(promptVersions, trace.Versions())
 			m.vectorStore.Update(ctx, existing)
 			logger.System("LTM去重：合并内容", "strategy", strategy, "existing_id", existing.ID)
+			m.auditMemory(ctx, AuditLTMMerge, existing.ID, userID, map[string]interface{}{
+				"strategy":    strategy,
+				"new_content": mergedContent,
+			})
 
 		case "keep_newer":
 			m.vectorStore.Delete(ctx, []string{existing.ID})
+			m.auditMemory(ctx, AuditLTMMerge, existing.ID, userID, map[string]interface{}{
+				"strategy": strategy,
+				"content":  existing.Content,
+			})
 			goto createNew
 
 		case "keep_both":
 			goto createNew
 		}
 
-		GetGlobalMetrics().RecordPromotion(string(category), true)
+		GetGlobalMetrics().RecordPromotion(types.TenantFrom(ctx), string(category), true)
 		return nil
 	}
 
 createNew:
 	// 正常创建或 keep_both/keep_newer 后的创建
 	tags, entities, err := m.judge.ExtractStructuredTags(ctx, summary, category)
+	versions := mergePromptVersions(promptVersions, trace.Versions())
 	if err != nil {
 		tags = fallbackTags
 		entities = fallbackEntities
+		delete(versions, PromptExtractTags)
 	}
 
 	now := time.Now()
 	metadataMap := map[string]interface{}{
+		metaTenantID:        types.TenantFrom(ctx),
 		"user_id":           userID,
 		"created_at":        now,
 		"tags":              tags,
@@ -284,6 +345,7 @@ createNew:
 		"decay_score":       1.0,
 		"source_type":       confirmedBy,
 		"confidence_origin": confidence,
+		"prompt_versions":   versions,
 	}
 
 	ltmRecord := types.Record{
@@ -299,13 +361,15 @@ createNew:
 		return fmt.Errorf("写入LTM失败: %w", err)
 	}
 
-	GetGlobalMetrics().RecordPromotion(string(category), true)
+	GetGlobalMetrics().RecordPromotion(types.TenantFrom(ctx), string(category), true)
 	logger.MemoryPromotion(string(category), confirmedBy, confidence, summary)
 	return nil
 }
 
 // ScanAndEvictDecayedMemories 扫描并删除衰减的记忆
 func (m *Manager) ScanAndEvictDecayedMemories(ctx context.Context) error {
+	ctx = withJobActor(ctx, actorDecayEviction)
+
 	// 获取所有LTM记录
 	allMemories, err := m.vectorStore.List(ctx, map[string]interface{}{}, 1000, 0)
 	if err != nil {
@@ -313,6 +377,7 @@ func (m *Manager) ScanAndEvictDecayedMemories(ctx context.Context) error {
 	}
 
 	var toDelete []string
+	var evicted []types.Record
 	var toUpdate []types.Record
 
 	for _, record := range allMemories {
@@ -328,6 +393,8 @@ func (m *Manager) ScanAndEvictDecayedMemories(ctx context.Context) error {
 		if m.decayCalculator.ShouldEvict(metadata.DecayScore) {
 			// 标记删除
 			toDelete = append(toDelete, record.ID)
+			record.Metadata["decay_score"] = metadata.DecayScore
+			evicted = append(evicted, record)
 			logger.System("🗑️ Evicting Memory", "decay", metadata.DecayScore, "content", record.Content[:50])
 		} else {
 			// 更新衰减分数
@@ -341,6 +408,19 @@ func (m *Manager) ScanAndEvictDecayedMemories(ctx context.Context) error {
 	if len(toDelete) > 0 {
 		if err := m.vectorStore.Delete(ctx, toDelete); err != nil {
 			logger.Error("批量删除失败", err)
+		} else {
+			for _, rec := range evicted {
+				m.RecordAudit(ctx, AuditEntry{
+					TenantID: recordTenant(&rec),
+					Action:   AuditLTMEvict,
+					TargetID: rec.ID,
+					UserID:   recordUserID(&rec),
+					Detail: map[string]interface{}{
+						"content":     rec.Content,
+						"decay_score": rec.Metadata["decay_score"],
+					},
+				})
+			}
 		}
 	}
 
@@ -359,18 +439,20 @@ func (m *Manager) ScanAndEvictDecayedMemories(ctx context.Context) error {
 func extractLTMMetadata(metaMap map[string]interface{}) (*types.LTMMetadata, error) {
 	metadata := &types.LTMMetadata{}
 
+	if v, ok := metaMap[metaTenantID].(string); ok {
+		metadata.TenantID = v
+	}
 	if v, ok := metaMap["user_id"].(string); ok {
 		metadata.UserID = v
 	}
-	if v, ok := metaMap["last_access_at"].(time.Time); ok {
+	// 存储读出的时间为 RFC3339 字符串、整数为 int64，需兼容多种类型
+	if v, ok := metaTime(metaMap["last_access_at"]); ok {
 		metadata.LastAccessAt = v
 	} else {
 		metadata.LastAccessAt = time.Now().Add(-time.Hour * 24 * 30) // 默认30天前
 	}
-	if v, ok := metaMap["access_count"].(int); ok {
-		metadata.AccessCount = v
-	}
-	if v, ok := metaMap["decay_score"].(float64); ok {
+	metadata.AccessCount = metaInt(metaMap["access_count"])
+	if v, ok := metaFloat(metaMap["decay_score"]); ok {
 		metadata.DecayScore = v
 	} else {
 		metadata.DecayScore = 1.0
@@ -424,28 +506,34 @@ func (m *Manager) startBackgroundTasks() {
 		for {
 			select {
 			case <-ticker.C:
-				// 方案：遍历所有 stm Key
-				keys, err := m.stmStore.ScanKeys(m.ctx, "memory:stm:*:*")
-				if err != nil {
-					logger.Error("STM Scanner Failed", err)
-					continue
+				// 方案：遍历所有租户的 stm Key
+				var keys []string
+				for _, pattern := range types.TenantKeyPatterns("memory:stm:*:*") {
+					matched, err := m.stmStore.ScanKeys(m.ctx, pattern)
+					if err != nil {
+						logger.Error("STM Scanner Failed", err)
+						continue
+					}
+					keys = append(keys, matched...)
 				}
 
 				processedUsers := make(map[string]bool)
 				for _, key := range keys {
-					// key format: memory:stm:<userID>:<sessionID>
-					var userID, sessionID string
-					if n, _ := fmt.Sscanf(key, "memory:stm:%s:%s", &userID, &sessionID); n == 2 {
-						// 避免同一个用户重复频繁调用 (可选优化)
-						if processedUsers[userID] {
-							continue
-						}
+					// key format: [tenant:<tenantID>:]memory:stm:<userID>:<sessionID>
+					tenantID, userID, sessionID, ok := parseSTMKey(key)
+					if !ok {
+						continue
+					}
+					// 避免同一个用户重复频繁调用 (可选优化)
+					userKey := tenantID + ":" + userID
+					if processedUsers[userKey] {
+						continue
+					}
 
-						if err := m.JudgeAndStageFromSTM(m.ctx, userID, sessionID); err != nil {
-							logger.Error("Auto Judge Failed", err)
-						} else {
-							processedUsers[userID] = true
-						}
+					if err := m.JudgeAndStageFromSTM(types.WithTenant(m.ctx, tenantID), userID, sessionID); err != nil {
+						logger.Error("Auto Judge Failed", err)
+					} else {
+						processedUsers[userKey] = true
 					}
 				}
 
@@ -493,6 +581,43 @@ func (m *Manager) startBackgroundTasks() {
 		}
 	}()
 
+	// 任务5：召回强化，定期批量回写访问记录
+	if m.accessTracker != nil {
+		m.startAccessTracker()
+	}
+
+	// 任务6：为历史 LTM 补写租户，完成后构建关键词索引（启动时一次 + 定期全量重建）
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := m.BackfillTenantIDs(m.ctx); err != nil {
+			logger.Error("历史LTM补写租户失败", err)
+		}
+		if m.lexicalIndex == nil {
+			return
+		}
+		if err := m.RebuildLexicalIndex(m.ctx); err != nil {
+			logger.Error("关键词索引构建失败", err)
+		}
+		if m.cfg.LexicalIndexRebuildMinutes <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(time.Duration(m.cfg.LexicalIndexRebuildMinutes) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.RebuildLexicalIndex(m.ctx); err != nil {
+					logger.Error("关键词索引重建失败", err)
+				}
+			case <-m.ctx.Done():
+				return
+			}
+		}
+	}()
+
 	logger.System("✅ 后台调度器已启动: STM清洗 + Staging晋升 + 记忆衰减 + LTM去重")
 }
 
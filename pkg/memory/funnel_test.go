@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"ai-memory/pkg/config"
+	"ai-memory/pkg/llm"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+const (
+	testEmbeddingDim = 256
+	coffeeFact       = "用户每天早上喝黑咖啡"
+	coffeeMerged     = "用户每天早上喝黑咖啡，不加糖"
+)
+
+// scriptedLLM 按调用类型返回固定响应的假模型，用于录制回放夹具：
+// 提到咖啡的对话判定为高价值事实，其余判定为噪声
+type scriptedLLM struct{}
+
+var batchItemPattern = regexp.MustCompile(`【记忆(\d+)】\nUser: ([^\n]*)`)
+
+func (scriptedLLM) GenerateText(ctx context.Context, prompt string, opts ...llm.Option) (string, error) {
+	switch llm.OperationFrom(ctx) {
+	case llm.OperationJudgeBatch:
+		var items []map[string]interface{}
+		for _, match := range batchItemPattern.FindAllStringSubmatch(prompt, -1) {
+			index, _ := strconv.Atoi(match[1])
+			item := map[string]interface{}{
+				"index": index, "value_score": 0.1, "confidence_score": 0.9,
+				"category": "noise", "reason": "闲聊", "should_stage": false,
+			}
+			if strings.Contains(match[2], "咖啡") {
+				item["value_score"], item["confidence_score"] = 0.9, 0.95
+				item["category"], item["reason"], item["should_stage"] = "preference", "饮食偏好", true
+				item["tags"] = []string{"咖啡"}
+			}
+			items = append(items, item)
+		}
+		data, err := json.Marshal(items)
+		return string(data), err
+	case llm.OperationSummarize:
+		return coffeeFact, nil
+	case llm.OperationMergeStrategy:
+		return `{"strategy":"merge","reason":"同一偏好的补充","merged_content":"` + coffeeMerged + `"}`, nil
+	case llm.OperationExtractTags:
+		return `{"tags":["饮食","咖啡"],"entities":{"饮品":"黑咖啡"}}`, nil
+	}
+	return "", nil
+}
+
+func (scriptedLLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return nil, nil
+}
+
+func (scriptedLLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, nil
+}
+
+// newFunnelTestManager 使用内存存储与哈希向量化的 Manager，阈值调整为单条消息即可走完漏斗
+func newFunnelTestManager(t *testing.T, client llm.LLM) (*Manager, *store.InMemoryVectorStore) {
+	t.Helper()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load config: %v", err)
+	}
+	cfg.STMJudgeMinMessages = 1
+	cfg.STMBatchJudgeSize = 10
+	cfg.StagingMinOccurrences = 1
+	cfg.StagingMinWaitHours = 0
+	cfg.StagingValueThreshold = 0.6
+	cfg.StagingConfidenceHigh = 0.8
+	cfg.StagingConfidenceLow = 0.5
+	cfg.LLMBudgetUserDailyTokens, cfg.LLMBudgetUserDailyCalls = 0, 0
+	cfg.LLMBudgetGlobalDailyTokens, cfg.LLMBudgetGlobalDailyCalls = 0, 0
+	cfg.PromptDefaultLanguage = "zh"
+	cfg.JudgeCacheProvider = "memory"
+	cfg.LexicalIndexEnabled = false
+	cfg.AccessTrackingEnabled = false
+
+	vectorStore := store.NewInMemoryVectorStore("")
+	if err := vectorStore.Init(context.Background(), testEmbeddingDim); err != nil {
+		t.Fatalf("Init vector store: %v", err)
+	}
+	m := NewManager(cfg, vectorStore, store.NewInMemoryListStore(), nil, NewHashEmbedder(testEmbeddingDim), client, store.NewInMemoryStagingStore(30), nil)
+	t.Cleanup(m.Shutdown)
+	return m, vectorStore
+}
+
+// runFunnel 两轮对话走完 STM→Staging→LTM（第二轮与已有记忆合并），再对人为写入的重复记录做全局去重，
+// 返回各用户最终的 LTM 内容
+func runFunnel(t *testing.T, client llm.LLM) map[string][]string {
+	t.Helper()
+	ctx := context.Background()
+	m, vectorStore := newFunnelTestManager(t, client)
+
+	// 第一轮：一条偏好 + 一条闲聊
+	m.Add(ctx, "u1", "s1", "我每天早上都喝黑咖啡", "好的，记住了", nil)
+	m.Add(ctx, "u1", "s1", "今天天气怎么样", "晴天", nil)
+	if err := m.JudgeAndStageFromSTM(ctx, "u1", "s1"); err != nil {
+		t.Fatalf("JudgeAndStageFromSTM: %v", err)
+	}
+	if stm := m.fetchSTM(ctx, "u1", "s1", 10); len(stm) != 0 {
+		t.Errorf("STM after judge = %d records, want 0", len(stm))
+	}
+	staged, _ := m.GetStagingEntries(ctx, "u1")
+	if len(staged) != 1 || staged[0].Content != coffeeFact {
+		t.Fatalf("staging = %+v, want one entry %q", staged, coffeeFact)
+	}
+
+	if err := m.PromoteStagingToLTM(ctx); err != nil {
+		t.Fatalf("PromoteStagingToLTM: %v", err)
+	}
+	ltm := listLTM(t, vectorStore, "u1")
+	if len(ltm) != 1 || ltm[0].Content != coffeeFact {
+		t.Fatalf("LTM after first promotion = %+v", ltm)
+	}
+	if tags := fmt.Sprint(ltm[0].Metadata["tags"]); tags != "[饮食 咖啡]" {
+		t.Errorf("LTM tags = %s, want extracted tags", tags)
+	}
+
+	// 第二轮：同一事实再次出现，晋升时与已有记忆合并而不是新增
+	m.Add(ctx, "u1", "s2", "我早上喝咖啡从来不加糖", "明白", nil)
+	if err := m.JudgeAndStageFromSTM(ctx, "u1", "s2"); err != nil {
+		t.Fatalf("JudgeAndStageFromSTM: %v", err)
+	}
+	if err := m.PromoteStagingToLTM(ctx); err != nil {
+		t.Fatalf("PromoteStagingToLTM: %v", err)
+	}
+	ltm = listLTM(t, vectorStore, "u1")
+	if len(ltm) != 1 || ltm[0].Content != coffeeMerged {
+		t.Fatalf("LTM after merge = %+v", ltm)
+	}
+	if staged, _ := m.GetStagingEntries(ctx, "u1"); len(staged) != 0 {
+		t.Errorf("staging after promotion = %d entries, want 0", len(staged))
+	}
+
+	// 全局去重：同一用户的重复记录被合并，其他用户的相同内容不受影响
+	vector, _ := m.embedder.EmbedQuery(ctx, coffeeMerged)
+	duplicates := []types.Record{
+		{ID: "dup-u1", Content: coffeeMerged, Embedding: vector, Timestamp: time.Now(), Type: types.LongTerm,
+			Metadata: map[string]interface{}{metaTenantID: types.DefaultTenant, "user_id": "u1"}},
+		{ID: "dup-u2", Content: coffeeMerged, Embedding: vector, Timestamp: time.Now(), Type: types.LongTerm,
+			Metadata: map[string]interface{}{metaTenantID: types.DefaultTenant, "user_id": "u2"}},
+	}
+	if err := vectorStore.Add(ctx, duplicates); err != nil {
+		t.Fatalf("Add duplicates: %v", err)
+	}
+	if err := m.DeduplicateLTM(ctx); err != nil {
+		t.Fatalf("DeduplicateLTM: %v", err)
+	}
+
+	result := make(map[string][]string)
+	for _, userID := range []string{"u1", "u2"} {
+		for _, rec := range listLTM(t, vectorStore, userID) {
+			result[userID] = append(result[userID], rec.Content)
+		}
+	}
+	return result
+}
+
+func listLTM(t *testing.T, vectorStore *store.InMemoryVectorStore, userID string) []types.Record {
+	t.Helper()
+	records, err := vectorStore.List(context.Background(), userFilters(context.Background(), userID), 0, 0)
+	if err != nil {
+		t.Fatalf("List LTM: %v", err)
+	}
+	return records
+}
+
+// TestFunnelReplay 先用假模型录制夹具，再以回放模型 + 哈希向量化重放同一流程，结果应完全一致且无未命中
+func TestFunnelReplay(t *testing.T) {
+	fixtures := filepath.Join(t.TempDir(), "fixtures.jsonl")
+
+	recorder, err := llm.NewRecordingLLM(fixtures, scriptedLLM{}, nil)
+	if err != nil {
+		t.Fatalf("NewRecordingLLM: %v", err)
+	}
+	recorded := runFunnel(t, recorder)
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close recorder: %v", err)
+	}
+
+	replay, err := llm.LoadReplayLLM(fixtures)
+	if err != nil {
+		t.Fatalf("LoadReplayLLM: %v", err)
+	}
+	replayed := runFunnel(t, replay)
+	if replay.Misses() != 0 {
+		t.Errorf("replay misses = %d, want 0", replay.Misses())
+	}
+
+	for _, result := range []map[string][]string{recorded, replayed} {
+		if len(result["u1"]) != 1 || result["u1"][0] != coffeeMerged {
+			t.Errorf("u1 LTM = %v, want [%s]", result["u1"], coffeeMerged)
+		}
+		if len(result["u2"]) != 1 {
+			t.Errorf("u2 LTM = %v, want one record", result["u2"])
+		}
+	}
+}
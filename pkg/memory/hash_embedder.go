@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"ai-memory/pkg/store"
+	"context"
+	"hash/fnv"
+	"math"
+)
+
+// HashEmbedder 不依赖模型的确定性向量化（特征哈希）：按关键词检索的切分规则分词，
+// 每个词哈希到一个维度并按哈希符号累加，最后做 L2 归一化。
+// 相同文本得到相同向量，共享词越多余弦相似度越高，可在离线评估与测试中替代真实 Embedder。
+type HashEmbedder struct {
+	dimension int
+}
+
+// NewHashEmbedder dimension <= 0 时使用 256
+func NewHashEmbedder(dimension int) *HashEmbedder {
+	if dimension <= 0 {
+		dimension = 256
+	}
+	return &HashEmbedder{dimension: dimension}
+}
+
+// Dimension 向量维度
+func (h *HashEmbedder) Dimension() int {
+	return h.dimension
+}
+
+func (h *HashEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return h.embed(text), nil
+}
+
+func (h *HashEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = h.embed(text)
+	}
+	return vectors, nil
+}
+
+func (h *HashEmbedder) embed(text string) []float32 {
+	vector := make([]float32, h.dimension)
+	for _, token := range store.TokenizeLexical(text) {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(token))
+		sum := hasher.Sum64()
+
+		idx := int(sum % uint64(h.dimension))
+		if sum>>63 == 1 {
+			vector[idx]--
+		} else {
+			vector[idx]++
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		// 空文本：返回固定的单位向量，避免零向量导致余弦相似度无意义
+		vector[0] = 1
+		return vector
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
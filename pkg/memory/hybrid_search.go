@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"ai-memory/pkg/llm"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 召回检索模式
+const (
+	SearchModeVector  = "vector"  // 仅向量相似度
+	SearchModeLexical = "lexical" // 仅关键词（BM25）
+	SearchModeHybrid  = "hybrid"  // 向量 + 关键词，RRF 融合
+)
+
+// lexicalRebuildBatchSize 重建索引时每批拉取的 LTM 条数
+const lexicalRebuildBatchSize = 500
+
+// lexicalVectorStore VectorStore 装饰器：写入/更新/删除 LTM 时同步维护关键词索引
+type lexicalVectorStore struct {
+	VectorStore
+	index *store.LexicalIndex
+}
+
+func newLexicalVectorStore(inner VectorStore, index *store.LexicalIndex) *lexicalVectorStore {
+	return &lexicalVectorStore{VectorStore: inner, index: index}
+}
+
+func (s *lexicalVectorStore) Add(ctx context.Context, records []types.Record) error {
+	if err := s.VectorStore.Add(ctx, records); err != nil {
+		return err
+	}
+	// 与底层存储保持一致：没有 Embedding 的记录不会被写入
+	for _, r := range records {
+		if r.Embedding != nil {
+			s.index.Upsert(r)
+		}
+	}
+	return nil
+}
+
+func (s *lexicalVectorStore) Update(ctx context.Context, record types.Record) error {
+	if err := s.VectorStore.Update(ctx, record); err != nil {
+		return err
+	}
+	s.index.Upsert(record)
+	return nil
+}
+
+func (s *lexicalVectorStore) Delete(ctx context.Context, ids []string) error {
+	if err := s.VectorStore.Delete(ctx, ids); err != nil {
+		return err
+	}
+	s.index.Remove(ids...)
+	return nil
+}
+
+// RebuildLexicalIndex 全量扫描 LTM 重建关键词索引（启动时及定期执行，兼容其他实例写入的数据）
+func (m *Manager) RebuildLexicalIndex(ctx context.Context) error {
+	if m.lexicalIndex == nil {
+		return nil
+	}
+
+	start := time.Now()
+	var all []types.Record
+	offset := 0
+	for {
+		records, err := m.vectorStore.List(ctx, map[string]interface{}{}, lexicalRebuildBatchSize, offset)
+		if err != nil {
+			return fmt.Errorf("扫描LTM失败: %w", err)
+		}
+		all = append(all, records...)
+		if len(records) < lexicalRebuildBatchSize {
+			break
+		}
+		offset += len(records)
+	}
+
+	m.lexicalIndex.Reset(all)
+	logger.System("🔤 关键词索引已重建", "docs", m.lexicalIndex.Len(), "duration", time.Since(start).String())
+	return nil
+}
+
+// ltmSearchOptions 单次 LTM 检索的参数
+type ltmSearchOptions struct {
+	Query         string
+	Limit         int
+	Filters       map[string]interface{}
+	Mode          string
+	VectorWeight  float64
+	LexicalWeight float64
+}
+
+// resolveSearchMode 请求未指定时使用配置的默认模式；关键词索引未启用时退化为向量检索
+func (m *Manager) resolveSearchMode(mode string) string {
+	if mode == "" {
+		mode = m.cfg.RecallSearchMode
+	}
+	if m.lexicalIndex == nil {
+		return SearchModeVector
+	}
+	switch mode {
+	case SearchModeLexical, SearchModeHybrid:
+		return mode
+	default:
+		return SearchModeVector
+	}
+}
+
+// searchLTMHybrid 按检索模式执行 LTM 召回
+// 混合模式下向量检索失败（如 embedding 服务不可用）时降级为纯关键词结果。
+func (m *Manager) searchLTMHybrid(ctx context.Context, userID string, opts ltmSearchOptions) ([]types.Record, error) {
+	ctx = llm.WithUserID(ctx, userID)
+	mode := m.resolveSearchMode(opts.Mode)
+
+	var vectorRecords []types.Record
+	var vectorErr error
+	if mode != SearchModeLexical {
+		var vector []float32
+		vector, vectorErr = m.embedder.EmbedQuery(ctx, opts.Query)
+		if vectorErr != nil {
+			vectorErr = fmt.Errorf("failed to embed query: %w", vectorErr)
+		} else {
+			vectorRecords, vectorErr = m.searchLTM(ctx, userID, vector, opts.Limit, opts.Filters)
+		}
+		if mode == SearchModeVector {
+			return vectorRecords, vectorErr
+		}
+		if vectorErr != nil {
+			logger.Error("Hybrid recall: vector search failed, using lexical results only", vectorErr)
+		}
+	}
+
+	hits := m.lexicalIndex.Search(opts.Query, opts.Limit, opts.Filters)
+	lexicalRecords := make([]types.Record, len(hits))
+	for i, h := range hits {
+		lexicalRecords[i] = h.Record
+	}
+	if mode == SearchModeLexical {
+		return lexicalRecords, nil
+	}
+
+	vectorWeight, lexicalWeight := opts.VectorWeight, opts.LexicalWeight
+	if vectorWeight <= 0 && lexicalWeight <= 0 {
+		vectorWeight, lexicalWeight = 1, 1
+	}
+	return fuseRRF(
+		[][]types.Record{vectorRecords, lexicalRecords},
+		[]float64{vectorWeight, lexicalWeight},
+		m.cfg.RecallRRFK,
+		opts.Limit,
+	), nil
+}
+
+// fuseRRF 加权倒数排名融合（Reciprocal Rank Fusion）
+// score(d) = Σ weight_i / (k + rank_i(d))，rank 从 1 开始；同一记录在多路结果中出现时分数累加。
+// 权重 <= 0 的结果列表不参与融合。
+func fuseRRF(lists [][]types.Record, weights []float64, k int, limit int) []types.Record {
+	if k <= 0 {
+		k = 60
+	}
+
+	scores := make(map[string]float64)
+	records := make(map[string]types.Record)
+	for i, list := range lists {
+		weight := 1.0
+		if i < len(weights) {
+			weight = weights[i]
+		}
+		if weight <= 0 {
+			continue
+		}
+		for rank, rec := range list {
+			scores[rec.ID] += weight / float64(k+rank+1)
+			if _, ok := records[rec.ID]; !ok {
+				records[rec.ID] = rec
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(records))
+	for id := range records {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	fused := make([]types.Record, len(ids))
+	for i, id := range ids {
+		fused[i] = records[id]
+	}
+	return fused
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"ai-memory/pkg/types"
+	"fmt"
+	"testing"
+)
+
+func TestFuseRRF(t *testing.T) {
+	records := func(ids ...string) []types.Record {
+		out := make([]types.Record, len(ids))
+		for i, id := range ids {
+			out[i] = types.Record{ID: id, Content: "content-" + id}
+		}
+		return out
+	}
+	vector := records("a", "b", "c")
+	lexical := records("c", "d")
+
+	tests := []struct {
+		name    string
+		lists   [][]types.Record
+		weights []float64
+		k       int
+		limit   int
+		want    []string
+	}{
+		// c 在两路中都出现，分数累加后排第一；b / d 同为各自列表第二名，分数相同时按 ID 排序
+		{name: "equal weights", lists: [][]types.Record{vector, lexical}, weights: []float64{1, 1}, k: 60, want: []string{"c", "a", "b", "d"}},
+		{name: "limit", lists: [][]types.Record{vector, lexical}, weights: []float64{1, 1}, k: 60, limit: 2, want: []string{"c", "a"}},
+		{name: "lexical weighted", lists: [][]types.Record{vector, lexical}, weights: []float64{1, 3}, k: 60, want: []string{"c", "d", "a", "b"}},
+		{name: "zero weight skipped", lists: [][]types.Record{vector, lexical}, weights: []float64{0, 1}, k: 60, want: []string{"c", "d"}},
+		{name: "missing weight defaults to 1", lists: [][]types.Record{vector, lexical}, weights: []float64{1}, k: 60, want: []string{"c", "a", "b", "d"}},
+		// k <= 0 回退为 60，结果与默认一致
+		{name: "default k", lists: [][]types.Record{vector, lexical}, weights: []float64{1, 1}, k: 0, want: []string{"c", "a", "b", "d"}},
+		{name: "empty lists", lists: [][]types.Record{nil, nil}, weights: []float64{1, 1}, k: 60, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fused := fuseRRF(tt.lists, tt.weights, tt.k, tt.limit)
+			ids := make([]string, len(fused))
+			for i, rec := range fused {
+				ids[i] = rec.ID
+				if rec.Content != "content-"+rec.ID {
+					t.Errorf("record %s content = %q", rec.ID, rec.Content)
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("fuseRRF = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"ai-memory/pkg/store"
 	"ai-memory/pkg/types"
 	"context"
 	"time"
@@ -35,13 +36,18 @@ type VectorStore interface {
 	// Delete removes records by ID.
 	Delete(ctx context.Context, ids []string) error
 
-	// List retrieves all records (for summarization/cleanup).
+	// List retrieves records ordered by ID (for summarization/cleanup).
+	// limit <= 0 means no limit; all backends must follow this.
 	List(ctx context.Context, filter map[string]interface{}, limit int, offset int) ([]types.Record, error)
 
 	// Update modifies an existing record.
 	Update(ctx context.Context, record types.Record) error
 
-	// Get retrieves a record by ID.
+	// UpdateMetadata merges fields into a record's metadata without touching its content or embedding.
+	// Returns store.ErrRecordNotFound when the record does not exist.
+	UpdateMetadata(ctx context.Context, id string, fields map[string]interface{}) error
+
+	// Get retrieves a record by ID; returns store.ErrRecordNotFound when it does not exist.
 	Get(ctx context.Context, id string) (*types.Record, error)
 	// Count returns the number of records matching a filter.
 	Count(ctx context.Context, filter map[string]interface{}) (int64, error)
@@ -75,10 +81,41 @@ type ListStore interface {
 	Expire(ctx context.Context, key string, expiration time.Duration) error
 }
 
+// StagingStore abstracts the staging area between STM and LTM (funnel middle layer).
+type StagingStore interface {
+	// AddOrIncrement 添加暂存条目，已存在（hash 或语义相似）时频次+1
+	AddOrIncrement(ctx context.Context, userID, sessionID, content string, judgeResult *types.JudgeResult, embedder store.Embedder) error
+	// SearchSimilar 在该用户的暂存区中查找最相似的条目（无则返回nil）
+	SearchSimilar(ctx context.Context, userID string, queryVector []float32, threshold float64) (*types.StagingEntry, error)
+	GetPendingEntries(ctx context.Context, minOccurrences int, minWaitHours int) ([]*types.StagingEntry, error)
+	GetAllByUser(ctx context.Context, userID string) ([]*types.StagingEntry, error)
+	GetBySession(ctx context.Context, userID, sessionID string) ([]*types.StagingEntry, error)
+	Update(ctx context.Context, entry *types.StagingEntry) error
+	Delete(ctx context.Context, entryID string) error
+	DeleteBatch(ctx context.Context, entryIDs []string) error
+}
+
+// JudgeCache 判定结果缓存（key 由内容哈希与判定 Prompt 版本组成）
+type JudgeCache interface {
+	// Get 读取缓存，未命中时返回 ok=false
+	Get(ctx context.Context, key string) (result *types.JudgeResult, ok bool, err error)
+	Set(ctx context.Context, key string, result *types.JudgeResult) error
+}
+
+// EmbeddingCache 向量缓存（key 由向量模型与内容哈希组成）
+type EmbeddingCache interface {
+	// Get 读取缓存，未命中时返回 ok=false
+	Get(ctx context.Context, key string) (vector []float32, ok bool, err error)
+	Set(ctx context.Context, key string, vector []float32) error
+}
+
 // EndUserStore 持久化层接口（for end_users table）
 type EndUserStore interface {
 	UpsertUser(ctx context.Context, identifier string) error
 	ListUsers(ctx context.Context) ([]types.EndUser, error)
+	GetLanguage(ctx context.Context, identifier string) (string, error) // 未设置时返回空字符串
+	SetLanguage(ctx context.Context, identifier string, language string) error
+	ListTenants(ctx context.Context) ([]string, error) // 出现过终端用户的全部租户（不受 context 租户限制）
 }
 
 // Embedder abstracts the text embedding model provider.
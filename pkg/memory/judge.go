@@ -2,8 +2,11 @@ package memory
 
 import (
 	"ai-memory/pkg/llm"
+	"ai-memory/pkg/logger"
 	"ai-memory/pkg/types"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -11,172 +14,198 @@ import (
 
 // Judge 判定引擎：评估记忆价值和提取结构化信息
 type Judge struct {
-	llm          llm.LLM
-	judgeModel   string
-	extractModel string
+	llm     llm.LLM
+	models  JudgeModels
+	prompts *PromptRegistry
 }
 
-// NewJudge 创建判定引擎实例
-func NewJudge(llmInstance llm.LLM, judgeModel, extractModel string) *Judge {
+// JudgeModels 各类 LLM 调用使用的模型，为空时使用 LLM 客户端的默认模型
+type JudgeModels struct {
+	Judge     string // 价值判定
+	Extract   string // 标签与实体提取
+	Summarize string // 总结重构，为空时沿用 Judge
+	Merge     string // 合并策略判定，为空时沿用 Judge
+}
+
+const (
+	// judgeTemperature 判定、提取类任务要求输出稳定
+	judgeTemperature float32 = 0
+	// summarizeTemperature 总结重构允许少量措辞变化
+	summarizeTemperature float32 = 0.3
+)
+
+// NewJudge 创建判定引擎实例，prompts 为 nil 时使用内置模板
+func NewJudge(llmInstance llm.LLM, models JudgeModels, prompts *PromptRegistry) *Judge {
+	if models.Summarize == "" {
+		models.Summarize = models.Judge
+	}
+	if models.Merge == "" {
+		models.Merge = models.Judge
+	}
+	if prompts == nil {
+		prompts = NewPromptRegistry("")
+	}
 	return &Judge{
-		llm:          llmInstance,
-		judgeModel:   judgeModel,
-		extractModel: extractModel,
+		llm:     llmInstance,
+		models:  models,
+		prompts: prompts,
 	}
 }
 
-// JudgeMemoryValue 判断记忆价值（单条）
-func (j *Judge) JudgeMemoryValue(ctx context.Context, content string) (*types.JudgeResult, error) {
-	prompt := fmt.Sprintf(`你是记忆价值评估专家。分析以下对话片段，判断是否包含值得长期记忆的信息。
-
-对话内容：
-%s
-
-评估维度（满分1.0）：
-1. 事实性 (0.4): 是否包含客观事实（如地点、日期、人名、技术栈等）
-2. 偏好性 (0.3): 是否反映用户偏好（如喜好、习惯、风格等）
-3. 目标性 (0.3): 是否涉及长期目标（如学习计划、项目意图等）
-
-输出JSON格式（严格遵守，不要添加额外文本）：
-{
-  "value_score": 0.0-1.0,
-  "confidence_score": 0.0-1.0,
-  "category": "fact|preference|goal|noise",
-  "reason": "简短理由",
-  "tags": ["标签1", "标签2"],
-  "entities": {"实体类型": "实体值"},
-  "should_stage": true/false,
-  "is_critical": true/false
+// render 按 context 中的语言渲染 Prompt 模板（未指定时使用默认语言），并记录使用的模板版本
+func (j *Judge) render(ctx context.Context, name string, data promptData) (string, *PromptTemplate, error) {
+	prompt, t, err := j.prompts.Render(name, PromptLanguageFrom(ctx), data)
+	if err != nil {
+		return "", nil, err
+	}
+	recordPromptVersion(ctx, t)
+	return prompt, t, nil
 }
 
-判定指南：
-- is_critical: 仅当满足以下任一条件时设为 true：
-  1. 强烈意图/深度承诺（如“我决定要学习Golang”、“我准备搬家到上海”）
-  2. 核心事实变更（如“我入职了Google”、“我结婚了”）
-  3. 用户显式要求记住（如“记住，我的生日是10月1日”）
-- should_stage: 通用的有价值信息。`, content)
+// JudgeMemoryValue 判断记忆价值（单条）
+func (j *Judge) JudgeMemoryValue(ctx context.Context, content string) (*types.JudgeResult, error) {
+	ctx = llm.WithOperation(ctx, llm.OperationJudge)
 
-	response, err := j.llm.GenerateText(ctx, prompt)
+	prompt, tmpl, err := j.render(ctx, PromptJudgeValue, promptData{Content: content})
 	if err != nil {
-		return nil, fmt.Errorf("LLM判定失败: %w", err)
+		return nil, err
 	}
 
-	// 清理响应（可能包含markdown代码块）
-	response = strings.TrimSpace(response)
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimPrefix(response, "```")
-	response = strings.TrimSuffix(response, "```")
-	response = strings.TrimSpace(response)
-
 	var result types.JudgeResult
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		return nil, fmt.Errorf("解析判定结果失败: %w, 原始响应: %s", err, response)
+	opts := []llm.Option{llm.WithModel(j.models.Judge), llm.WithTemperature(judgeTemperature), llm.WithJSONMode()}
+	err = j.generateValidated(ctx, prompt, opts, func(response string) error {
+		result = types.JudgeResult{}
+		if err := json.Unmarshal([]byte(response), &result); err != nil {
+			return fmt.Errorf("JSON格式错误: %w", err)
+		}
+		return validateJudgeResult(&result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LLM判定失败: %w", err)
 	}
 
+	result.PromptVersions = map[string]string{PromptJudgeValue: tmpl.Ref()}
 	return &result, nil
 }
 
 // JudgeBatch 批量判定（降低LLM调用次数）
+// 结果按【记忆N】编号对应，单条不合格不影响其他条目；不合格条目会携带校验错误重新判定，
+// 仍失败的位置为 nil。仅当所有条目都失败时返回错误。
 func (j *Judge) JudgeBatch(ctx context.Context, contents []string) ([]*types.JudgeResult, error) {
+	ctx = llm.WithOperation(ctx, llm.OperationJudgeBatch)
+
 	if len(contents) == 0 {
 		return nil, nil
 	}
 
-	// 构建批量prompt
-	var contentList string
-	for i, c := range contents {
-		contentList += fmt.Sprintf("【记忆%d】\n%s\n\n", i+1, c)
+	// 输出为 JSON 数组，不启用 JSON 模式（OpenAI 的 JSON 模式要求顶层为对象）
+	opts := []llm.Option{llm.WithModel(j.models.Judge), llm.WithTemperature(judgeTemperature)}
+
+	results := make([]*types.JudgeResult, len(contents))
+	pending := make([]int, len(contents))
+	for i := range contents {
+		pending[i] = i
 	}
 
-	prompt := fmt.Sprintf(`你是记忆价值评估专家。批量分析以下%d条对话片段，判断每条是否包含值得长期记忆的信息。
-
-%s
-
-评估维度（满分1.0）：
-1. 事实性 (0.4): 客观事实
-2. 偏好性 (0.3): 用户偏好
-3. 目标性 (0.3): 长期目标
-
-输出JSON数组格式（严格遵守，不要添加额外文本）：
-[
-  {
-    "value_score": 0.0-1.0,
-    "confidence_score": 0.0-1.0,
-    "category": "fact|preference|goal|noise",
-    "reason": "简短理由",
-    "tags": ["标签1"],
-    "entities": {"类型": "值"},
-    "should_stage": true/false,
-    "is_critical": true/false
-  }
-]
-
-判定指南：
-- is_critical: 关键事实、强烈意图或用户明确要求记忆的内容（直接晋升）。
-- should_stage: 普通有价值信息（进入暂存观察）。`, len(contents), contentList)
-
-	response, err := j.llm.GenerateText(ctx, prompt)
-	if err != nil {
-		return nil, fmt.Errorf("批量判定失败: %w", err)
+	var lastErr, parseErr error
+	var itemErrs map[int]error
+	for attempt := 0; attempt <= judgeMaxRepairAttempts && len(pending) > 0; attempt++ {
+		labels := make([]int, len(pending))
+		for k, idx := range pending {
+			labels[k] = idx + 1
+		}
+
+		prompt, tmpl, err := j.render(ctx, PromptJudgeBatch, batchPromptData(contents, labels))
+		if err != nil {
+			return nil, err
+		}
+		if attempt > 0 {
+			logger.System("⚠️ Batch judge output failed validation, requesting repair", "pending", len(pending))
+			prompt = buildBatchRepairPrompt(prompt, labels, itemErrs, parseErr)
+		}
+
+		response, err := j.llm.GenerateText(ctx, prompt, opts...)
+		if err != nil {
+			lastErr = fmt.Errorf("批量判定失败: %w", err)
+			break
+		}
+
+		var accepted map[int]*types.JudgeResult
+		accepted, itemErrs, parseErr = parseBatchJudgeResults(cleanJSONResponse(response), labels)
+		if parseErr != nil {
+			lastErr = parseErr
+		}
+
+		var remaining []int
+		for _, idx := range pending {
+			if result, ok := accepted[idx+1]; ok {
+				result.PromptVersions = map[string]string{PromptJudgeBatch: tmpl.Ref()}
+				results[idx] = result
+			} else {
+				remaining = append(remaining, idx)
+				if err := itemErrs[idx+1]; err != nil {
+					lastErr = fmt.Errorf("【记忆%d】%w", idx+1, err)
+				}
+			}
+		}
+		pending = remaining
 	}
 
-	// 清理响应
-	response = strings.TrimSpace(response)
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimPrefix(response, "```")
-	response = strings.TrimSuffix(response, "```")
-	response = strings.TrimSpace(response)
-
-	var results []*types.JudgeResult
-	if err := json.Unmarshal([]byte(response), &results); err != nil {
-		return nil, fmt.Errorf("解析批量判定结果失败: %w", err)
+	if len(pending) == len(contents) {
+		return nil, lastErr
 	}
+	if len(pending) > 0 {
+		logger.System("⚠️ Batch judge partially accepted", "accepted", len(contents)-len(pending), "rejected", len(pending), "last_error", lastErr.Error())
+	}
+	return results, nil
+}
 
-	// 校验数量
-	if len(results) != len(contents) {
-		return nil, fmt.Errorf("判定结果数量不匹配: 期望%d, 实际%d", len(contents), len(results))
+// CacheVersion 判定缓存版本：由判定模型与各语言批量判定模板的版本计算，修改模板或切换 JUDGE_MODEL 后旧缓存自动失效
+// 注意：JUDGE_MODEL 为空（使用提供商默认模型）时，更换提供商默认模型不会使缓存失效。
+func (j *Judge) CacheVersion() string {
+	var sb strings.Builder
+	sb.WriteString(j.models.Judge)
+	for _, t := range j.prompts.List() {
+		if t.Name == PromptJudgeBatch {
+			sb.WriteString("\n" + t.Ref())
+		}
 	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])[:12]
+}
 
-	return results, nil
+// batchPromptData 批量判定模板变量，labels[i] 为第 i 条待判定内容的编号（从1开始）
+func batchPromptData(contents []string, labels []int) promptData {
+	items := make([]promptItem, 0, len(labels))
+	for _, label := range labels {
+		items = append(items, promptItem{Label: label, Content: contents[label-1]})
+	}
+	return promptData{Count: len(labels), Items: items}
 }
 
 // ExtractStructuredTags 提取结构化标签和实体（用于LTM写入前）
 func (j *Judge) ExtractStructuredTags(ctx context.Context, content string, category types.MemoryCategory) ([]string, map[string]string, error) {
-	prompt := fmt.Sprintf(`提取以下记忆的结构化信息。
+	ctx = llm.WithOperation(ctx, llm.OperationExtractTags)
 
-记忆内容：
-%s
-
-分类：%s
-
-请提取：
-1. 关键标签（2-5个简洁的中文/英文标签）
-2. 实体映射（提取关键实体及其类型）
-
-输出JSON格式：
-{
-  "tags": ["标签1", "标签2"],
-  "entities": {"实体类型": "实体值"}
-}`, content, category)
-
-	response, err := j.llm.GenerateText(ctx, prompt)
+	prompt, _, err := j.render(ctx, PromptExtractTags, promptData{Content: content, Category: string(category)})
 	if err != nil {
-		return nil, nil, fmt.Errorf("标签提取失败: %w", err)
+		return nil, nil, err
 	}
 
-	// 清理响应
-	response = strings.TrimSpace(response)
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimPrefix(response, "```")
-	response = strings.TrimSuffix(response, "```")
-	response = strings.TrimSpace(response)
-
 	var result struct {
 		Tags     []string          `json:"tags"`
 		Entities map[string]string `json:"entities"`
 	}
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		return nil, nil, fmt.Errorf("解析标签提取结果失败: %w", err)
+	opts := []llm.Option{llm.WithModel(j.models.Extract), llm.WithTemperature(judgeTemperature), llm.WithJSONMode()}
+	err = j.generateValidated(ctx, prompt, opts, func(response string) error {
+		result.Tags, result.Entities = nil, nil
+		if err := json.Unmarshal([]byte(response), &result); err != nil {
+			return fmt.Errorf("JSON格式错误: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("标签提取失败: %w", err)
 	}
 
 	return result.Tags, result.Entities, nil
@@ -186,26 +215,14 @@ func (j *Judge) ExtractStructuredTags(ctx context.Context, content string, categ
 // 输入：原始对话/事件内容
 // 输出：结构化摘要（脱离上下文依然可读）
 func (j *Judge) SummarizeAndRestructure(ctx context.Context, rawContent string, category types.MemoryCategory) (string, error) {
-	prompt := fmt.Sprintf(`你是记忆重构专家。将以下对话/事件转换为独立的事实陈述。
-
-原始内容：
-%s
-
-分类：%s
+	ctx = llm.WithOperation(ctx, llm.OperationSummarize)
 
-重构要求：
-1. **独立可读**：移除"用户说"、"AI回复"等对话标记，转为客观事实
-2. **第三人称**：使用"该用户"或具体人名
-3. **完整信息**：包含所有关键信息（时间、地点、偏好、目标等）
-4. **简洁准确**：1-3句话概括核心事实
-
-输出格式：纯文本，不要JSON，直接输出重构后的独立事实陈述。
-
-示例：
-输入："User: 我喜欢Python\nAI: 好的，记住了"
-输出："该用户偏好使用Python编程语言"`, rawContent, category)
+	prompt, _, err := j.render(ctx, PromptSummarize, promptData{Content: rawContent, Category: string(category)})
+	if err != nil {
+		return "", err
+	}
 
-	response, err := j.llm.GenerateText(ctx, prompt)
+	response, err := j.llm.GenerateText(ctx, prompt, llm.WithModel(j.models.Summarize), llm.WithTemperature(summarizeTemperature))
 	if err != nil {
 		return "", fmt.Errorf("总结重构失败: %w", err)
 	}
@@ -224,52 +241,35 @@ func (j *Judge) SummarizeAndRestructure(ctx context.Context, rawContent string,
 // DecideMergeStrategy LLM判断两条相似记忆的合并策略
 // 返回：策略类型 + 合并后内容（如适用）
 func (j *Judge) DecideMergeStrategy(ctx context.Context, memory1, memory2 string) (strategy string, merged string, err error) {
-	prompt := fmt.Sprintf(`你是记忆管理专家。分析两条相似的长期记忆，判断如何处理。
-
-【记忆A】（已存在）：
-%s
-
-【记忆B】（新发现）：
-%s
-
-评估维度：
-1. 信息重复度：内容是否高度重叠
-2. 时间关系：是否存在信息更新/演化
-3. 独立性：是否为不同时间点的独立事实
-
-选择策略（严格输出JSON）：
-{
-  "strategy": "update_existing|merge|keep_both|keep_newer",
-  "reason": "简短理由",
-  "merged_content": "如选择merge，输出合并后的独立事实陈述"
-}
-
-策略说明：
-- update_existing: 记忆B与A高度重复，只更新A的访问计数
-- merge: 记忆B包含A的升级信息，合并为更完整的事实
-- keep_both: 两条记忆代表不同阶段的独立事实，都保留
-- keep_newer: 记忆B完全替代A，删除A保留B`, memory1, memory2)
+	ctx = llm.WithOperation(ctx, llm.OperationMergeStrategy)
 
-	response, err := j.llm.GenerateText(ctx, prompt)
+	prompt, _, err := j.render(ctx, PromptMergeStrategy, promptData{Existing: memory1, New: memory2})
 	if err != nil {
-		return "", "", fmt.Errorf("LLM合并策略判定失败: %w", err)
+		return "", "", err
 	}
 
-	// 清理响应
-	response = strings.TrimSpace(response)
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimPrefix(response, "```")
-	response = strings.TrimSuffix(response, "```")
-	response = strings.TrimSpace(response)
-
 	var result struct {
 		Strategy      string `json:"strategy"`
 		Reason        string `json:"reason"`
 		MergedContent string `json:"merged_content"`
 	}
-
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		return "", "", fmt.Errorf("解析合并策略失败: %w", err)
+	opts := []llm.Option{llm.WithModel(j.models.Merge), llm.WithTemperature(judgeTemperature), llm.WithJSONMode()}
+	err = j.generateValidated(ctx, prompt, opts, func(response string) error {
+		result.Strategy, result.Reason, result.MergedContent = "", "", ""
+		if err := json.Unmarshal([]byte(response), &result); err != nil {
+			return fmt.Errorf("JSON格式错误: %w", err)
+		}
+		result.Strategy = strings.ToLower(strings.TrimSpace(result.Strategy))
+		if !validMergeStrategies[result.Strategy] {
+			return fmt.Errorf("strategy 必须是 update_existing|merge|keep_both|keep_newer 之一（实际 %q）", result.Strategy)
+		}
+		if result.Strategy == "merge" && strings.TrimSpace(result.MergedContent) == "" {
+			return fmt.Errorf("strategy 为 merge 时 merged_content 不能为空")
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("LLM合并策略判定失败: %w", err)
 	}
 
 	return result.Strategy, result.MergedContent, nil
@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"ai-memory/pkg/llm"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// judgeMaxRepairAttempts 输出未通过校验时的最大修复重试次数
+const judgeMaxRepairAttempts = 1
+
+// maxRepairEchoLength 修复提示中回显的原始输出最大长度（字符）
+const maxRepairEchoLength = 2000
+
+var validJudgeCategories = map[types.MemoryCategory]bool{
+	types.CategoryFact:       true,
+	types.CategoryPreference: true,
+	types.CategoryGoal:       true,
+	types.CategoryNoise:      true,
+}
+
+var validMergeStrategies = map[string]bool{
+	"update_existing": true,
+	"merge":           true,
+	"keep_both":       true,
+	"keep_newer":      true,
+}
+
+// generateValidated 调用 LLM 并解析校验输出；未通过时携带校验错误重新提示修复
+// parse 负责解析与校验（清理后的）响应，返回的错误会原样反馈给模型。
+func (j *Judge) generateValidated(ctx context.Context, prompt string, opts []llm.Option, parse func(string) error) error {
+	response, err := j.llm.GenerateText(ctx, prompt, opts...)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		cleaned := cleanJSONResponse(response)
+		parseErr := parse(cleaned)
+		if parseErr == nil {
+			return nil
+		}
+		if attempt >= judgeMaxRepairAttempts {
+			return fmt.Errorf("%w, 原始响应: %s", parseErr, truncateRunes(cleaned, maxRepairEchoLength))
+		}
+
+		logger.System("⚠️ Judge output failed validation, requesting repair", "attempt", attempt+1, "error", parseErr.Error())
+		response, err = j.llm.GenerateText(ctx, buildRepairPrompt(prompt, cleaned, parseErr), opts...)
+		if err != nil {
+			return fmt.Errorf("修复请求失败: %w (原校验错误: %v)", err, parseErr)
+		}
+	}
+}
+
+// buildRepairPrompt 构造修复提示：原任务 + 上次输出 + 校验错误
+func buildRepairPrompt(originalPrompt, previous string, validationErr error) string {
+	return fmt.Sprintf(`%s
+
+---
+你上一次的输出未通过格式校验，请修正。
+
+上一次的输出：
+%s
+
+校验错误：
+%s
+
+请严格按照上述要求重新输出，只输出JSON，不要添加任何解释。`, originalPrompt, truncateRunes(previous, maxRepairEchoLength), validationErr)
+}
+
+// cleanJSONResponse 清理响应中的 markdown 代码块及 JSON 前后的多余文本
+func cleanJSONResponse(response string) string {
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+	response = strings.TrimSpace(response)
+
+	// 模型偶尔会在 JSON 前后附加说明文字，截取第一个 JSON 值
+	start := strings.IndexAny(response, "{[")
+	if start < 0 {
+		return response
+	}
+	closer := byte('}')
+	if response[start] == '[' {
+		closer = ']'
+	}
+	if end := strings.LastIndexByte(response, closer); end > start {
+		return response[start : end+1]
+	}
+	return response[start:]
+}
+
+// validateJudgeResult 校验并规范化单条判定结果
+func validateJudgeResult(r *types.JudgeResult) error {
+	if r == nil {
+		return errors.New("判定结果为空")
+	}
+	var problems []string
+	if r.ValueScore < 0 || r.ValueScore > 1 {
+		problems = append(problems, fmt.Sprintf("value_score 必须在 0.0-1.0 之间（实际 %v）", r.ValueScore))
+	}
+	if r.ConfidenceScore < 0 || r.ConfidenceScore > 1 {
+		problems = append(problems, fmt.Sprintf("confidence_score 必须在 0.0-1.0 之间（实际 %v）", r.ConfidenceScore))
+	}
+	r.Category = types.MemoryCategory(strings.ToLower(strings.TrimSpace(string(r.Category))))
+	if !validJudgeCategories[r.Category] {
+		problems = append(problems, fmt.Sprintf("category 必须是 fact|preference|goal|noise 之一（实际 %q）", r.Category))
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// batchJudgeItem 批量判定中的单条输出，index 对应提示中的【记忆N】编号
+type batchJudgeItem struct {
+	Index *int `json:"index"`
+	types.JudgeResult
+}
+
+// parseBatchJudgeResults 按编号解析批量判定结果，逐条校验
+// labels[i] 为第 i 条内容在提示中的编号；返回成功解析的 编号->结果 与各编号的错误。
+// 缺少 index 字段时仅在数量一致的情况下按位置对应。
+func parseBatchJudgeResults(response string, labels []int) (map[int]*types.JudgeResult, map[int]error, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(response), &items); err != nil {
+		return nil, nil, fmt.Errorf("解析批量判定结果失败: %w", err)
+	}
+
+	expected := make(map[int]bool, len(labels))
+	for _, label := range labels {
+		expected[label] = true
+	}
+
+	accepted := make(map[int]*types.JudgeResult)
+	itemErrs := make(map[int]error)
+	for pos, raw := range items {
+		var item batchJudgeItem
+		decodeErr := json.Unmarshal(raw, &item)
+
+		label := 0
+		switch {
+		case item.Index != nil:
+			label = *item.Index
+		case len(items) == len(labels):
+			label = labels[pos]
+		}
+		if !expected[label] {
+			continue
+		}
+
+		if decodeErr != nil {
+			itemErrs[label] = fmt.Errorf("格式错误: %v", decodeErr)
+			continue
+		}
+		result := item.JudgeResult
+		if err := validateJudgeResult(&result); err != nil {
+			itemErrs[label] = err
+			continue
+		}
+		accepted[label] = &result
+		delete(itemErrs, label)
+	}
+
+	for _, label := range labels {
+		if _, ok := accepted[label]; !ok {
+			if _, ok := itemErrs[label]; !ok {
+				itemErrs[label] = errors.New("缺少该条记忆的判定结果")
+			}
+		}
+	}
+	return accepted, itemErrs, nil
+}
+
+// buildBatchRepairPrompt 仅针对未通过校验的条目重新提示
+func buildBatchRepairPrompt(basePrompt string, labels []int, itemErrs map[int]error, parseErr error) string {
+	var sb strings.Builder
+	if parseErr != nil {
+		sb.WriteString(fmt.Sprintf("- 整体输出无法解析: %v\n", parseErr))
+	}
+	for _, label := range labels {
+		if err, ok := itemErrs[label]; ok {
+			sb.WriteString(fmt.Sprintf("- 【记忆%d】: %v\n", label, err))
+		}
+	}
+
+	return fmt.Sprintf(`%s
+
+---
+你上一次对以上记忆的输出未通过格式校验，问题如下：
+%s
+请重新输出以上每条记忆的判定结果，只输出JSON数组，不要添加任何解释。`, basePrompt, sb.String())
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "..."
+}
@@ -0,0 +1,352 @@
+package memory
+
+import (
+	"ai-memory/pkg/llm"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/types"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModelPrice 模型单价（美元 / 百万 token）；向量模型只使用 Input
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// LLMUsageRow 按 租户 × 用户 × 调用类型 × 模型 聚合的用量
+type LLMUsageRow struct {
+	TenantID         string  `json:"tenant_id,omitempty"`
+	UserID           string  `json:"user_id,omitempty"`
+	Operation        string  `json:"operation,omitempty"`
+	Provider         string  `json:"provider,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	EmbeddingTokens  int64   `json:"embedding_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+func (r *LLMUsageRow) add(other *LLMUsageRow) {
+	r.Calls += other.Calls
+	r.PromptTokens += other.PromptTokens
+	r.CompletionTokens += other.CompletionTokens
+	r.EmbeddingTokens += other.EmbeddingTokens
+	r.CostUSD += other.CostUSD
+}
+
+type llmUsageKey struct {
+	tenantID  string
+	userID    string
+	operation string
+	provider  string
+	model     string
+}
+
+func (k llmUsageKey) row() *LLMUsageRow {
+	return &LLMUsageRow{TenantID: k.tenantID, UserID: k.userID, Operation: k.operation, Provider: k.provider, Model: k.model}
+}
+
+// llmUsageStats LLM 用量统计：pending 等待写入 llm_usage 表，totals 为进程启动以来的累计（无数据库时展示）
+type llmUsageStats struct {
+	mu      sync.Mutex
+	pricing map[string]ModelPrice
+	pending map[llmUsageKey]*LLMUsageRow
+	totals  map[llmUsageKey]*LLMUsageRow
+	budget  *BudgetManager // 用量同时计入每日预算（未配置时为 nil）
+}
+
+// llmUsageTopUsers Dashboard 中按成本展示的用户数
+const llmUsageTopUsers = 20
+
+// SetLLMPricing 设置模型单价（用于成本估算）
+func (mc *MetricsCollector) SetLLMPricing(pricing map[string]ModelPrice) {
+	mc.llmUsage.mu.Lock()
+	defer mc.llmUsage.mu.Unlock()
+	mc.llmUsage.pricing = pricing
+}
+
+// SetBudgetManager 设置每日预算管理器，之后的用量同时计入预算
+func (mc *MetricsCollector) SetBudgetManager(budget *BudgetManager) {
+	mc.llmUsage.mu.Lock()
+	defer mc.llmUsage.mu.Unlock()
+	mc.llmUsage.budget = budget
+}
+
+// budgetManager 返回当前的预算管理器（可能为 nil）
+func (mc *MetricsCollector) budgetManager() *BudgetManager {
+	mc.llmUsage.mu.Lock()
+	defer mc.llmUsage.mu.Unlock()
+	return mc.llmUsage.budget
+}
+
+// RecordUsage 记录一次 LLM / Embedding 调用的用量（实现 llm.UsageRecorder）
+func (mc *MetricsCollector) RecordUsage(event llm.UsageEvent) {
+	key := llmUsageKey{tenantID: event.TenantID, userID: event.UserID, operation: event.Operation, provider: event.Provider, model: event.Model}
+
+	if budget := mc.budgetManager(); budget != nil {
+		budget.RecordUsage(event)
+	}
+
+	s := mc.llmUsage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delta := &LLMUsageRow{
+		Calls:            1,
+		PromptTokens:     int64(event.Usage.PromptTokens),
+		CompletionTokens: int64(event.Usage.CompletionTokens),
+		EmbeddingTokens:  int64(event.Usage.EmbeddingTokens),
+		CostUSD:          estimateLLMCost(s.pricing, event.Model, event.Usage),
+	}
+	for _, bucket := range []map[llmUsageKey]*LLMUsageRow{s.pending, s.totals} {
+		row, ok := bucket[key]
+		if !ok {
+			row = key.row()
+			bucket[key] = row
+		}
+		row.add(delta)
+	}
+}
+
+// drainLLMUsage 取出待持久化的用量
+func (mc *MetricsCollector) drainLLMUsage() []*LLMUsageRow {
+	s := mc.llmUsage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := make([]*LLMUsageRow, 0, len(s.pending))
+	for _, row := range s.pending {
+		rows = append(rows, row)
+	}
+	s.pending = make(map[llmUsageKey]*LLMUsageRow)
+	return rows
+}
+
+// restoreLLMUsage 写入失败时放回待持久化队列，下次重试
+func (mc *MetricsCollector) restoreLLMUsage(rows []*LLMUsageRow) {
+	s := mc.llmUsage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range rows {
+		key := llmUsageKey{tenantID: r.TenantID, userID: r.UserID, operation: r.Operation, provider: r.Provider, model: r.Model}
+		row, ok := s.pending[key]
+		if !ok {
+			row = key.row()
+			s.pending[key] = row
+		}
+		row.add(r)
+	}
+}
+
+// snapshotLLMUsage 复制待持久化（pending=true）或累计的用量
+func (mc *MetricsCollector) snapshotLLMUsage(pending bool) []*LLMUsageRow {
+	s := mc.llmUsage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source := s.totals
+	if pending {
+		source = s.pending
+	}
+	rows := make([]*LLMUsageRow, 0, len(source))
+	for _, row := range source {
+		copied := *row
+		rows = append(rows, &copied)
+	}
+	return rows
+}
+
+// estimateLLMCost 按单价估算成本；模型名未精确匹配时使用最长前缀匹配（如 gpt-4o-mini-2024-07-18）
+func estimateLLMCost(pricing map[string]ModelPrice, model string, usage llm.Usage) float64 {
+	price, ok := pricing[model]
+	if !ok {
+		matched := ""
+		for name, p := range pricing {
+			if strings.HasPrefix(model, name) && len(name) > len(matched) {
+				matched, price, ok = name, p, true
+			}
+		}
+		if !ok {
+			return 0
+		}
+	}
+	inputTokens := usage.PromptTokens + usage.EmbeddingTokens
+	return (float64(inputTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1e6
+}
+
+// ParseLLMPricing 解析单价配置："model=输入单价/输出单价,model2=输入单价"（美元 / 百万 token）
+func ParseLLMPricing(spec string) map[string]ModelPrice {
+	pricing := make(map[string]ModelPrice)
+	for _, item := range strings.Split(spec, ",") {
+		name, prices, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found || strings.TrimSpace(name) == "" {
+			continue
+		}
+		inStr, outStr, _ := strings.Cut(prices, "/")
+		input, err := strconv.ParseFloat(strings.TrimSpace(inStr), 64)
+		if err != nil {
+			logger.System("⚠️ Invalid LLM pricing entry ignored", "entry", item)
+			continue
+		}
+		var output float64
+		if strings.TrimSpace(outStr) != "" {
+			if output, err = strconv.ParseFloat(strings.TrimSpace(outStr), 64); err != nil {
+				logger.System("⚠️ Invalid LLM pricing entry ignored", "entry", item)
+				continue
+			}
+		}
+		pricing[strings.TrimSpace(name)] = ModelPrice{Input: input, Output: output}
+	}
+	return pricing
+}
+
+// insertLLMUsage 将聚合用量写入 llm_usage 表（与 metrics_timeseries 同周期持久化）
+func (mp *MetricsPersistence) insertLLMUsage(ctx context.Context, collector *MetricsCollector) error {
+	rows := collector.drainLLMUsage()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	tx, err := mp.db.BeginTx(ctx, nil)
+	if err != nil {
+		collector.restoreLLMUsage(rows)
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO llm_usage (tenant_id, user_id, operation, provider, model, calls, prompt_tokens, completion_tokens, embedding_tokens, cost_usd, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		collector.restoreLLMUsage(rows)
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, r := range rows {
+		if _, err := stmt.ExecContext(ctx, tenantOrDefault(r.TenantID), r.UserID, r.Operation, r.Provider, r.Model, r.Calls,
+			r.PromptTokens, r.CompletionTokens, r.EmbeddingTokens, r.CostUSD, now); err != nil {
+			collector.restoreLLMUsage(rows)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		collector.restoreLLMUsage(rows)
+		return err
+	}
+	return nil
+}
+
+// getLLMUsageSummary Dashboard 用量汇总：数据库中时间范围内的记录 + 尚未持久化的用量；
+// 无数据库时使用进程启动以来的累计。tenantID 非空时只汇总该租户的用量
+func (m *Manager) getLLMUsageSummary(ctx context.Context, hours int, tenantID string) map[string]interface{} {
+	var rows []*LLMUsageRow
+	if metricsDB != nil {
+		rows = append(m.queryLLMUsageFromDB(ctx, hours), globalMetrics.snapshotLLMUsage(true)...)
+	} else {
+		rows = globalMetrics.snapshotLLMUsage(false)
+	}
+
+	total := &LLMUsageRow{}
+	byOperation := make(map[string]*LLMUsageRow)
+	byModel := make(map[string]*LLMUsageRow)
+	byTenant := make(map[string]*LLMUsageRow)
+	byUser := make(map[string]*LLMUsageRow)
+	for _, r := range rows {
+		rowTenant := tenantOrDefault(r.TenantID)
+		if tenantID != "" && rowTenant != tenantID {
+			continue
+		}
+		total.add(r)
+		aggregateLLMUsage(byTenant, rowTenant, &LLMUsageRow{TenantID: rowTenant}, r)
+		aggregateLLMUsage(byOperation, r.Operation, &LLMUsageRow{Operation: r.Operation}, r)
+		aggregateLLMUsage(byModel, r.Provider+"/"+r.Model, &LLMUsageRow{Provider: r.Provider, Model: r.Model}, r)
+		if r.UserID != "" {
+			aggregateLLMUsage(byUser, types.TenantKey(rowTenant, r.UserID), &LLMUsageRow{TenantID: rowTenant, UserID: r.UserID}, r)
+		}
+	}
+
+	users := sortLLMUsageByCost(byUser)
+	if len(users) > llmUsageTopUsers {
+		users = users[:llmUsageTopUsers]
+	}
+
+	summary := map[string]interface{}{
+		"total":        total,
+		"by_operation": sortLLMUsageByCost(byOperation),
+		"by_model":     sortLLMUsageByCost(byModel),
+		"by_tenant":    sortLLMUsageByCost(byTenant),
+		"top_users":    users,
+	}
+	if m.budget.Enabled() {
+		status := m.budget.Status()
+		if tenantID != "" {
+			status = status.forTenant(tenantID)
+		}
+		summary["budget"] = status
+	}
+	return summary
+}
+
+func aggregateLLMUsage(groups map[string]*LLMUsageRow, key string, empty, row *LLMUsageRow) {
+	group, ok := groups[key]
+	if !ok {
+		group = empty
+		groups[key] = group
+	}
+	group.add(row)
+}
+
+func sortLLMUsageByCost(groups map[string]*LLMUsageRow) []*LLMUsageRow {
+	result := make([]*LLMUsageRow, 0, len(groups))
+	for _, row := range groups {
+		result = append(result, row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CostUSD != result[j].CostUSD {
+			return result[i].CostUSD > result[j].CostUSD
+		}
+		ti := result[i].PromptTokens + result[i].CompletionTokens + result[i].EmbeddingTokens
+		tj := result[j].PromptTokens + result[j].CompletionTokens + result[j].EmbeddingTokens
+		return ti > tj
+	})
+	return result
+}
+
+// queryLLMUsageFromDB 查询时间范围内的用量（按维度预聚合）
+func (m *Manager) queryLLMUsageFromDB(ctx context.Context, hours int) []*LLMUsageRow {
+	query := `
+		SELECT tenant_id, user_id, operation, provider, model,
+		       SUM(calls), SUM(prompt_tokens), SUM(completion_tokens), SUM(embedding_tokens), SUM(cost_usd)
+		FROM llm_usage
+		WHERE timestamp >= DATE_SUB(NOW(), INTERVAL ? HOUR)
+		GROUP BY tenant_id, user_id, operation, provider, model
+	`
+
+	rows, err := metricsDB.QueryContext(ctx, query, hours)
+	if err != nil {
+		logger.Error("查询LLM用量失败", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var result []*LLMUsageRow
+	for rows.Next() {
+		r := &LLMUsageRow{}
+		if err := rows.Scan(&r.TenantID, &r.UserID, &r.Operation, &r.Provider, &r.Model,
+			&r.Calls, &r.PromptTokens, &r.CompletionTokens, &r.EmbeddingTokens, &r.CostUSD); err != nil {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"ai-memory/pkg/llm"
+	"ai-memory/pkg/types"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLLMUsageSummaryTenantScope(t *testing.T) {
+	budget := NewBudgetManager(BudgetLimits{Calls: 1}, BudgetLimits{})
+	m := &Manager{budget: budget}
+	for _, event := range []llm.UsageEvent{
+		{Provider: "openai", Model: "scope-test", Operation: llm.OperationJudgeBatch, TenantID: "scope-a", UserID: "alice", Usage: llm.Usage{PromptTokens: 10}, Timestamp: time.Now()},
+		{Provider: "openai", Model: "scope-test", Operation: llm.OperationJudgeBatch, TenantID: "scope-b", UserID: "bob", Usage: llm.Usage{PromptTokens: 20}, Timestamp: time.Now()},
+	} {
+		GetGlobalMetrics().RecordUsage(event)
+		budget.RecordUsage(event)
+	}
+
+	tenants := func(rows []*LLMUsageRow) map[string]bool {
+		result := make(map[string]bool)
+		for _, row := range rows {
+			result[row.TenantID] = true
+		}
+		return result
+	}
+
+	scoped := m.getLLMUsageSummary(context.Background(), 24, "scope-a")
+	if got := tenants(scoped["by_tenant"].([]*LLMUsageRow)); len(got) != 1 || !got["scope-a"] {
+		t.Errorf("scoped by_tenant = %v, want only scope-a", got)
+	}
+	if got := tenants(scoped["top_users"].([]*LLMUsageRow)); len(got) != 1 || !got["scope-a"] {
+		t.Errorf("scoped top_users tenants = %v, want only scope-a", got)
+	}
+	status := scoped["budget"].(BudgetStatus)
+	if len(status.ExhaustedUsers) != 1 || status.ExhaustedUsers[0] != types.TenantKey("scope-a", "alice") {
+		t.Errorf("scoped exhausted users = %v", status.ExhaustedUsers)
+	}
+	if status.GlobalUsage.Calls != 0 {
+		t.Errorf("scoped global usage = %+v, want hidden", status.GlobalUsage)
+	}
+
+	all := m.getLLMUsageSummary(context.Background(), 24, "")
+	if got := tenants(all["by_tenant"].([]*LLMUsageRow)); !got["scope-a"] || !got["scope-b"] {
+		t.Errorf("all-tenant by_tenant = %v, want scope-a and scope-b", got)
+	}
+	if status := all["budget"].(BudgetStatus); len(status.ExhaustedUsers) != 2 || status.GlobalUsage.Calls != 2 {
+		t.Errorf("all-tenant budget = %+v", status)
+	}
+}
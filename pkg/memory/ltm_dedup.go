@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"ai-memory/pkg/llm"
 	"ai-memory/pkg/logger"
 	"ai-memory/pkg/types"
 	"context"
@@ -9,6 +10,7 @@ import (
 
 // DeduplicateLTM 扫描并去重LTM中的相似记忆
 func (m *Manager) DeduplicateLTM(ctx context.Context) error {
+	ctx = withJobActor(ctx, actorLTMDeduplication)
 	batchSize := 100
 	offset := 0
 	processed := 0
@@ -27,11 +29,12 @@ func (m *Manager) DeduplicateLTM(ctx context.Context) error {
 				continue
 			}
 
-			// 2. 利用向量搜索查找全局范围内的相似记录
+			seedUserID, _ := seed.Metadata["user_id"].(string)
+			seedCtx := llm.WithUserID(types.WithTenant(ctx, recordTenant(&seed)), seedUserID)
+
+			// 2. 利用向量搜索查找同一租户、同一用户范围内的相似记录
 			// 相似度阈值设为 0.95
-			similar, err := m.vectorStore.Search(ctx, seed.Embedding, 10, 0.90, map[string]interface{}{
-				"user_id": seed.Metadata["user_id"],
-			})
+			similar, err := m.vectorStore.Search(ctx, seed.Embedding, 10, 0.90, userFilters(seedCtx, seedUserID))
 			if err != nil {
 				continue
 			}
@@ -45,7 +48,7 @@ func (m *Manager) DeduplicateLTM(ctx context.Context) error {
 				sim := cosineSimilarity(seed.Embedding, match.Embedding)
 				if sim > 0.95 {
 					// 3. 调用智能合并策略
-					strategy, mergedContent, err := m.judge.DecideMergeStrategy(ctx, seed.Content, match.Content)
+					strategy, mergedContent, err := m.judge.DecideMergeStrategy(seedCtx, seed.Content, match.Content)
 					if err != nil {
 						logger.Error("合并策略判定失败", err)
 						continue
@@ -55,7 +58,7 @@ func (m *Manager) DeduplicateLTM(ctx context.Context) error {
 						continue
 					}
 
-					if err := m.executeMergeStrategy(ctx, seed, match, strategy, mergedContent); err != nil {
+					if err := m.executeMergeStrategy(seedCtx, seed, match, strategy, mergedContent); err != nil {
 						logger.Error("执行合并策略失败", err)
 					} else {
 						merged++
@@ -66,7 +69,7 @@ func (m *Manager) DeduplicateLTM(ctx context.Context) error {
 							break
 						}
 						if (strategy == "keep_higher_access" || strategy == "update_existing" || strategy == "merge") &&
-							(match.Metadata["access_count"].(int) > seed.Metadata["access_count"].(int)) {
+							(metaInt(match.Metadata["access_count"]) > metaInt(seed.Metadata["access_count"])) {
 							processedIDs[seed.ID] = true
 							break
 						}
@@ -93,31 +96,33 @@ func (m *Manager) executeMergeStrategy(
 	rec1, rec2 types.Record,
 	strategy, merged string,
 ) error {
-	count1, _ := rec1.Metadata["access_count"].(int)
-	count2, _ := rec2.Metadata["access_count"].(int)
+	count1 := metaInt(rec1.Metadata["access_count"])
+	count2 := metaInt(rec2.Metadata["access_count"])
+
+	// kept 为保留的记录，removed 为被合并删除的记录
+	var kept, removed types.Record
 
 	switch strategy {
 	case "keep_newer":
 		// 保留时间更新的记录
 		if rec1.Timestamp.After(rec2.Timestamp) {
-			m.vectorStore.Delete(ctx, []string{rec2.ID})
+			kept, removed = rec1, rec2
 		} else {
-			m.vectorStore.Delete(ctx, []string{rec1.ID})
+			kept, removed = rec2, rec1
 		}
+		m.vectorStore.Delete(ctx, []string{removed.ID})
 
 	case "keep_higher_access", "update_existing":
 		// 保留访问次数更多的记录
 		if count1 >= count2 {
-			rec1.Metadata["access_count"] = count1 + count2
-			rec1.Metadata["decay_score"] = 1.0
-			m.vectorStore.Update(ctx, rec1)
-			m.vectorStore.Delete(ctx, []string{rec2.ID})
+			kept, removed = rec1, rec2
 		} else {
-			rec2.Metadata["access_count"] = count1 + count2
-			rec2.Metadata["decay_score"] = 1.0
-			m.vectorStore.Update(ctx, rec2)
-			m.vectorStore.Delete(ctx, []string{rec1.ID})
+			kept, removed = rec2, rec1
 		}
+		kept.Metadata["access_count"] = count1 + count2
+		kept.Metadata["decay_score"] = 1.0
+		m.vectorStore.Update(ctx, kept)
+		m.vectorStore.Delete(ctx, []string{removed.ID})
 
 	case "merge":
 		// 合并为新记录，删除旧记录
@@ -132,12 +137,18 @@ func (m *Manager) executeMergeStrategy(
 		rec1.Metadata["decay_score"] = 1.0
 		m.vectorStore.Update(ctx, rec1)
 		m.vectorStore.Delete(ctx, []string{rec2.ID})
+		kept, removed = rec1, rec2
 
-	case "keep_both":
-		// 不做任何操作
+	default:
+		// keep_both：不做任何操作
 		return nil
 	}
 
+	m.auditMemory(ctx, AuditLTMMerge, removed.ID, recordUserID(&removed), map[string]interface{}{
+		"strategy":    strategy,
+		"merged_into": kept.ID,
+		"content":     removed.Content,
+	})
 	return nil
 }
 
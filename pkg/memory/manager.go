@@ -28,9 +28,18 @@ type Manager struct {
 
 	// 漏斗型记忆组件
 	judge           *Judge
-	stagingStore    *store.StagingStore
+	prompts         *PromptRegistry
+	stagingStore    StagingStore
 	decayCalculator *DecayCalculator
-	alertEngine     *AlertEngine // 告警引擎
+	lexicalIndex    *store.LexicalIndex // LTM 关键词索引（未启用时为 nil）
+	accessTracker   *AccessTracker      // 召回强化（未启用时为 nil）
+	tokenizer       Tokenizer           // 上下文组装 token 计数（nil 时使用 HeuristicTokenizer）
+	budget          *BudgetManager      // LLM 每日预算
+	alertEngine     *AlertEngine        // 告警引擎
+	auditRepo       AuditRepository     // 审计日志（未配置 MySQL 时为 nil）
+
+	// 判定结果观察者（离线评估使用，nil 表示不回调）
+	judgeObserver JudgeObserver
 
 	// 后台任务控制
 	ctx     context.Context
@@ -42,14 +51,48 @@ type Manager struct {
 	mysqlDB *sql.DB
 }
 
-func NewManager(cfg *config.Config, vStore VectorStore, lStore ListStore, uStore EndUserStore, embedder Embedder, llmModel llm.LLM, redisStore *store.RedisStore, mysqlDB *sql.DB) *Manager {
+func NewManager(cfg *config.Config, vStore VectorStore, lStore ListStore, uStore EndUserStore, embedder Embedder, llmModel llm.LLM, sStore StagingStore, mysqlDB *sql.DB) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Prompt 模板：内置 → 模板目录 → prompt_templates 表
+	prompts := NewPromptRegistry(cfg.PromptDefaultLanguage)
+	if cfg.PromptTemplateDir != "" {
+		if err := prompts.LoadDir(cfg.PromptTemplateDir); err != nil {
+			logger.Error("Failed to load prompt templates from dir", err)
+		}
+	}
+	if mysqlDB != nil {
+		if err := prompts.LoadFromDB(ctx, mysqlDB); err != nil {
+			logger.Error("Failed to load prompt templates from DB", err)
+		}
+	}
+
 	// 初始化漏斗组件
-	judge := NewJudge(llmModel, cfg.JudgeModel, cfg.ExtractTagsModel)
-	stagingStore := store.NewStagingStore(redisStore.GetClient(), 30) // TTL 30天
+	judge := NewJudge(llmModel, JudgeModels{
+		Judge:     cfg.JudgeModel,
+		Extract:   cfg.ExtractTagsModel,
+		Summarize: cfg.SummarizeModel,
+		Merge:     cfg.MergeModel,
+	}, prompts)
 	decayCalc := NewDecayCalculator(cfg.LTMDecayHalfLifeDays, cfg.LTMDecayMinScore)
 
+	// 关键词索引：包装 VectorStore，写入 LTM 时同步更新
+	var lexicalIndex *store.LexicalIndex
+	if cfg.LexicalIndexEnabled {
+		lexicalIndex = store.NewLexicalIndex()
+		vStore = newLexicalVectorStore(vStore, lexicalIndex)
+	}
+
+	var accessTracker *AccessTracker
+	if cfg.AccessTrackingEnabled {
+		accessTracker = NewAccessTracker(vStore, decayCalc, cfg.AccessFlushMaxPending)
+	}
+
+	budget := NewBudgetManager(
+		BudgetLimits{Tokens: cfg.LLMBudgetUserDailyTokens, Calls: cfg.LLMBudgetUserDailyCalls},
+		BudgetLimits{Tokens: cfg.LLMBudgetGlobalDailyTokens, Calls: cfg.LLMBudgetGlobalDailyCalls},
+	)
+
 	m := &Manager{
 		cfg:             cfg,
 		vectorStore:     vStore,
@@ -58,8 +101,12 @@ func NewManager(cfg *config.Config, vStore VectorStore, lStore ListStore, uStore
 		embedder:        embedder,
 		llm:             llmModel,
 		judge:           judge,
-		stagingStore:    stagingStore,
+		prompts:         prompts,
+		stagingStore:    sStore,
 		decayCalculator: decayCalc,
+		lexicalIndex:    lexicalIndex,
+		accessTracker:   accessTracker,
+		budget:          budget,
 		ctx:             ctx,
 		cancel:          cancel,
 		mysqlDB:         mysqlDB,
@@ -67,6 +114,18 @@ func NewManager(cfg *config.Config, vStore VectorStore, lStore ListStore, uStore
 
 	m.initPerformanceMonitor()
 
+	// LLM 用量与成本统计
+	GetGlobalMetrics().SetLLMPricing(ParseLLMPricing(cfg.LLMPricing))
+	llm.SetUsageRecorder(GetGlobalMetrics())
+
+	// LLM 每日预算：重启后从 llm_usage 表恢复当日用量
+	if budget.Enabled() {
+		if err := budget.LoadTodayUsage(ctx, mysqlDB); err != nil {
+			logger.Error("Failed to restore LLM budget usage", err)
+		}
+		GetGlobalMetrics().SetBudgetManager(budget)
+	}
+
 	// 初始化告警引擎
 	alertConfig := &AlertConfig{
 		CheckIntervalMinutes: cfg.AlertCheckIntervalMinutes,
@@ -79,12 +138,13 @@ func NewManager(cfg *config.Config, vStore VectorStore, lStore ListStore, uStore
 		CacheTrendPeriods:   cfg.AlertCacheTrendPeriods,
 		// 注意：规则阈值和冷却时间现在从数据库的 alert_rule_configs 表读取
 	}
-	// 创建告警存储层
+	// 创建告警存储层与审计日志
 	var alertRepo AlertRepository
 	if mysqlDB != nil {
 		alertRepo = NewMySQLAlertRepository(mysqlDB)
+		m.auditRepo = NewMySQLAuditRepository(mysqlDB)
 	}
-	m.alertEngine = NewAlertEngine(alertRepo, GetGlobalMetrics(), stagingStore, alertConfig)
+	m.alertEngine = NewAlertEngine(alertRepo, GetGlobalMetrics(), sStore, alertConfig)
 
 	// 初始化规则配置持久化
 	if mysqlDB != nil {
@@ -113,6 +173,7 @@ func (m *Manager) Add(ctx context.Context, userID string, sessionID string, inpu
 	if metadata == nil {
 		metadata = make(map[string]interface{})
 	}
+	metadata[metaTenantID] = types.TenantFrom(ctx)
 	metadata["user_id"] = userID
 	metadata["session_id"] = sessionID
 
@@ -129,8 +190,8 @@ func (m *Manager) Add(ctx context.Context, userID string, sessionID string, inpu
 		return fmt.Errorf("failed to marshal record: %w", err)
 	}
 
-	// Push to Redis List associated with User AND Session
-	key := fmt.Sprintf("memory:stm:%s:%s", userID, sessionID)
+	// Push to Redis List associated with Tenant, User AND Session
+	key := stmKey(ctx, userID, sessionID)
 	if err := m.stmStore.RPushWithExpire(ctx, key, m.cfg.STMExpirationDays, data); err != nil {
 		return fmt.Errorf("failed to add to STM: %w", err)
 	}
@@ -146,23 +207,9 @@ func (m *Manager) Add(ctx context.Context, userID string, sessionID string, inpu
 // Retrieve finds relevant memories from both STM (recent context) and LTM (vector search).
 func (m *Manager) Retrieve(ctx context.Context, userID string, sessionID string, query string, limit int) ([]types.Record, error) {
 	var allRecords []types.Record
-	key := fmt.Sprintf("memory:stm:%s:%s", userID, sessionID)
 
 	// 1. Fetch STM (Session Context)
-	stmData, err := m.stmStore.LRange(ctx, key, 0, -1)
-	if err == nil {
-		start := 0
-		if len(stmData) > m.cfg.ContextWindow {
-			start = len(stmData) - m.cfg.ContextWindow
-		}
-
-		for i := start; i < len(stmData); i++ {
-			var rec types.Record
-			if json.Unmarshal([]byte(stmData[i]), &rec) == nil {
-				allRecords = append(allRecords, rec)
-			}
-		}
-	}
+	allRecords = append(allRecords, m.fetchSTM(ctx, userID, sessionID, m.cfg.ContextWindow)...)
 
 	// 2. Fetch Staging (Mid-term Context)
 	// These are summarized facts that haven't reached LTM yet.
@@ -170,19 +217,7 @@ func (m *Manager) Retrieve(ctx context.Context, userID string, sessionID string,
 	stagingEntries, err := m.stagingStore.GetBySession(ctx, userID, sessionID)
 	if err == nil {
 		for _, entry := range stagingEntries {
-			// Convert StagingEntry to Record for uniform output
-			allRecords = append(allRecords, types.Record{
-				ID:        entry.ID,
-				Content:   entry.Content,
-				Timestamp: entry.LastSeenAt,
-				Type:      types.Staging,
-				Metadata: map[string]interface{}{
-					"category":         string(entry.Category),
-					"confidence_score": entry.ConfidenceScore,
-					"occurrence_count": entry.OccurrenceCount,
-					"source":           "staging",
-				},
-			})
+			allRecords = append(allRecords, stagingToRecord(entry))
 		}
 	}
 
@@ -192,50 +227,259 @@ func (m *Manager) Retrieve(ctx context.Context, userID string, sessionID string,
 		remainingSlots = m.cfg.MaxRecentMemories
 	}
 
-	vector, err := m.embedder.EmbedQuery(ctx, query)
+	// Filter by Tenant + User ID (access to ALL past sessions)
+	filters := userFilters(ctx, userID)
+
+	ltmRecords, err := m.searchLTMHybrid(ctx, userID, ltmSearchOptions{
+		Query:   query,
+		Limit:   remainingSlots,
+		Filters: filters,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to embed query: %w", err)
+		return nil, err
 	}
+	allRecords = append(allRecords, ltmRecords...)
 
-	// Filter by User ID (access to ALL past sessions)
-	filters := map[string]interface{}{
-		"user_id": userID,
+	// Enforce global MaxRecentMemories
+	if m.cfg.MaxRecentMemories > 0 && len(allRecords) > m.cfg.MaxRecentMemories {
+		allRecords = allRecords[:m.cfg.MaxRecentMemories]
 	}
 
-	ltmRecords, err := m.vectorStore.Search(ctx, vector, remainingSlots, 0.7, filters)
-	if err == nil {
-		allRecords = append(allRecords, ltmRecords...)
-
-		// [Proactive Self-Healing] Async Repair
-		// If we found multiple results, check if they are near-identical
-		if len(ltmRecords) > 1 {
-			go func(recs []types.Record, uid string) {
-				// Wait a bit or use a fresh context to avoid canceling with the request
-				repairCtx := context.Background()
-				for i := 0; i < len(recs); i++ {
-					for j := i + 1; j < len(recs); j++ {
-						sim := cosineSimilarity(recs[i].Embedding, recs[j].Embedding)
-						if sim > 0.98 {
-							logger.System("🔍 [Self-Healing] Found duplicate in recall, triggering repair", "user", uid)
-							// Trigger a targeted dedup/merge
-							strategy, mergedContent, err := m.judge.DecideMergeStrategy(repairCtx, recs[i].Content, recs[j].Content)
-							if err == nil && strategy != "keep_both" {
-								m.executeMergeStrategy(repairCtx, recs[i], recs[j], strategy, mergedContent)
-							}
-							return // Only trigger once per recall
-						}
-					}
-				}
-			}(ltmRecords, userID)
+	// 只登记实际返回的 LTM 记录（被截掉的不算命中）
+	m.trackAccess(allRecords)
+	return allRecords, nil
+}
+
+// Recall 增强召回：将 RecallOptions 中的标签/类别/衰减分数/时间范围过滤下推到 VectorStore，
+// 同时返回本会话中满足同样条件的 Staging 条目。Query 为空时按过滤条件列出 LTM。
+func (m *Manager) Recall(ctx context.Context, userID string, sessionID string, opts types.RecallOptions) ([]types.Record, error) {
+	if opts.TopK <= 0 {
+		opts.TopK = defaultRecallTopK
+	}
+
+	// 1. Staging（会话隔离，按相同条件过滤）
+	stagingRecords := rankStagingByQuery(m.recallStaging(ctx, userID, sessionID, opts), opts.Query)
+
+	// 2. LTM
+	ltmRecords, err := m.recallLTM(ctx, userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records := mergeRecallResults(stagingRecords, ltmRecords, opts.TopK)
+	m.trackAccess(records)
+	return records, nil
+}
+
+// mergeRecallResults Staging 最多占 TopK 的一半，其余名额留给 LTM；
+// LTM 不足时再用排在后面的 Staging 条目补齐
+func mergeRecallResults(staging, ltm []types.Record, topK int) []types.Record {
+	stagingSlots := min(len(staging), topK/2)
+	ltmSlots := min(len(ltm), topK-stagingSlots)
+	stagingSlots = min(len(staging), topK-ltmSlots)
+
+	records := make([]types.Record, 0, stagingSlots+ltmSlots)
+	records = append(records, staging[:stagingSlots]...)
+	return append(records, ltm[:ltmSlots]...)
+}
+
+// recallStaging 返回本会话中满足召回条件的 Staging 条目
+func (m *Manager) recallStaging(ctx context.Context, userID, sessionID string, opts types.RecallOptions) []types.Record {
+	if sessionID == "" {
+		return nil
+	}
+	stagingEntries, err := m.stagingStore.GetBySession(ctx, userID, sessionID)
+	if err != nil {
+		return nil
+	}
+
+	var records []types.Record
+	for _, entry := range stagingEntries {
+		if matchRecallOptions(entry, opts) {
+			records = append(records, stagingToRecord(entry))
 		}
 	}
+	return records
+}
 
-	// Enforce global MaxRecentMemories
-	if m.cfg.MaxRecentMemories > 0 && len(allRecords) > m.cfg.MaxRecentMemories {
-		allRecords = allRecords[:m.cfg.MaxRecentMemories]
+// rankStagingByQuery Query 非空时按 BM25 相关度排序 Staging 条目，并丢弃与 Query 没有共同词项的条目
+func rankStagingByQuery(records []types.Record, query string) []types.Record {
+	if query == "" || len(records) == 0 {
+		return records
 	}
 
-	return allRecords, nil
+	index := store.NewLexicalIndex()
+	index.Upsert(records...)
+	hits := index.Search(query, len(records), nil)
+	ranked := make([]types.Record, 0, len(hits))
+	for _, hit := range hits {
+		ranked = append(ranked, hit.Record)
+	}
+	return ranked
+}
+
+// recallLTM 按召回条件检索 LTM（最多 TopK 条）；调用方按最终返回的条目登记访问
+func (m *Manager) recallLTM(ctx context.Context, userID string, opts types.RecallOptions) ([]types.Record, error) {
+	filters := recallFilters(ctx, userID, opts)
+
+	var ltmRecords []types.Record
+	if opts.Query == "" {
+		records, err := m.vectorStore.List(ctx, filters, opts.TopK, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list memories: %w", err)
+		}
+		ltmRecords = records
+	} else {
+		records, err := m.searchLTMHybrid(ctx, userID, ltmSearchOptions{
+			Query:         opts.Query,
+			Limit:         opts.TopK,
+			Filters:       filters,
+			Mode:          opts.SearchMode,
+			VectorWeight:  opts.VectorWeight,
+			LexicalWeight: opts.LexicalWeight,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search memories: %w", err)
+		}
+		ltmRecords = records
+	}
+	return ltmRecords, nil
+}
+
+// defaultRecallTopK Recall 未指定 TopK 时的默认返回条数
+const defaultRecallTopK = 10
+
+// recallFilters 将 RecallOptions 转换为 VectorStore 过滤条件
+func recallFilters(ctx context.Context, userID string, opts types.RecallOptions) map[string]interface{} {
+	filters := userFilters(ctx, userID)
+	if len(opts.RequiredTags) > 0 {
+		filters[store.FilterTags] = opts.RequiredTags
+	}
+	if len(opts.ExcludedTags) > 0 {
+		filters[store.FilterExcludedTags] = opts.ExcludedTags
+	}
+	if opts.CategoryFilter != "" {
+		filters["category"] = string(opts.CategoryFilter)
+	}
+	if opts.MinDecayScore > 0 {
+		filters[store.FilterMinDecayScore] = opts.MinDecayScore
+	}
+	if opts.TimeRangeStart != nil {
+		filters[store.FilterTimeRangeStart] = *opts.TimeRangeStart
+	}
+	if opts.TimeRangeEnd != nil {
+		filters[store.FilterTimeRangeEnd] = *opts.TimeRangeEnd
+	}
+	return filters
+}
+
+// matchRecallOptions 判断 Staging 条目是否满足召回条件
+// Staging 条目尚未进入衰减周期，视为满分，不受 MinDecayScore 限制。
+func matchRecallOptions(entry *types.StagingEntry, opts types.RecallOptions) bool {
+	if opts.CategoryFilter != "" && entry.Category != opts.CategoryFilter {
+		return false
+	}
+	for _, tag := range opts.RequiredTags {
+		if !containsString(entry.ExtractedTags, tag) {
+			return false
+		}
+	}
+	for _, tag := range opts.ExcludedTags {
+		if containsString(entry.ExtractedTags, tag) {
+			return false
+		}
+	}
+	if opts.TimeRangeStart != nil && entry.LastSeenAt.Before(*opts.TimeRangeStart) {
+		return false
+	}
+	if opts.TimeRangeEnd != nil && entry.LastSeenAt.After(*opts.TimeRangeEnd) {
+		return false
+	}
+	return true
+}
+
+func containsString(list []string, target string) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchSTM 读取会话最近的 window 条 STM 记录（window < 0 表示全部）
+func (m *Manager) fetchSTM(ctx context.Context, userID, sessionID string, window int) []types.Record {
+	var records []types.Record
+	key := stmKey(ctx, userID, sessionID)
+
+	stmData, err := m.stmStore.LRange(ctx, key, 0, -1)
+	if err != nil {
+		return nil
+	}
+
+	start := 0
+	if window >= 0 && len(stmData) > window {
+		start = len(stmData) - window
+	}
+
+	for i := start; i < len(stmData); i++ {
+		var rec types.Record
+		if json.Unmarshal([]byte(stmData[i]), &rec) == nil {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+// stagingToRecord Convert StagingEntry to Record for uniform output
+func stagingToRecord(entry *types.StagingEntry) types.Record {
+	return types.Record{
+		ID:        entry.ID,
+		Content:   entry.Content,
+		Timestamp: entry.LastSeenAt,
+		Type:      types.Staging,
+		Metadata: map[string]interface{}{
+			"category":         string(entry.Category),
+			"confidence_score": entry.ConfidenceScore,
+			"occurrence_count": entry.OccurrenceCount,
+			"tags":             entry.ExtractedTags,
+			"source":           "staging",
+		},
+	}
+}
+
+// searchLTM 向量检索 LTM，并在命中近似重复时异步触发自愈合并
+func (m *Manager) searchLTM(ctx context.Context, userID string, vector []float32, limit int, filters map[string]interface{}) ([]types.Record, error) {
+	ltmRecords, err := m.vectorStore.Search(ctx, vector, limit, 0.7, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	// [Proactive Self-Healing] Async Repair
+	// If we found multiple results, check if they are near-identical
+	if len(ltmRecords) > 1 {
+		go func(recs []types.Record, uid, tenantID string) {
+			// Wait a bit or use a fresh context to avoid canceling with the request
+			repairCtx := llm.WithUserID(types.WithTenant(context.Background(), tenantID), uid)
+			repairCtx = WithActor(repairCtx, actorRecallRepair)
+			for i := 0; i < len(recs); i++ {
+				for j := i + 1; j < len(recs); j++ {
+					sim := cosineSimilarity(recs[i].Embedding, recs[j].Embedding)
+					if sim > 0.98 {
+						logger.System("🔍 [Self-Healing] Found duplicate in recall, triggering repair", "user", uid)
+						// Trigger a targeted dedup/merge
+						strategy, mergedContent, err := m.judge.DecideMergeStrategy(repairCtx, recs[i].Content, recs[j].Content)
+						if err == nil && strategy != "keep_both" {
+							m.executeMergeStrategy(repairCtx, recs[i], recs[j], strategy, mergedContent)
+						}
+						return // Only trigger once per recall
+					}
+				}
+			}
+		}(ltmRecords, userID, types.TenantFrom(ctx))
+	}
+
+	return ltmRecords, nil
 }
 
 // List retrieves all records with filtering.
@@ -255,13 +499,8 @@ func (m *Manager) List(ctx context.Context, filter Filter) ([]types.Record, erro
 	// 1. Fetch Short-Term Memory if requested
 	if filter.Type == "short_term" || filter.Type == "all" || filter.Type == "" {
 		// If UserID is provided, search specific session keys
-		// Pattern: memory:stm:<UserID>:*
-		pattern := "memory:stm:*:*"
-		if filter.UserID != "" {
-			pattern = fmt.Sprintf("memory:stm:%s:*", filter.UserID)
-		}
-
-		keys, err := m.stmStore.ScanKeys(ctx, pattern)
+		// Pattern: [tenant:<Tenant>:]memory:stm:<UserID>:*
+		keys, err := m.stmStore.ScanKeys(ctx, stmUserPattern(ctx, filter.UserID))
 		if err == nil {
 			for _, key := range keys {
 				// Fetch all items from list (inefficient for large lists but STM is short by definition)
@@ -293,7 +532,11 @@ func (m *Manager) List(ctx context.Context, filter Filter) ([]types.Record, erro
 		}
 
 		if err == nil {
+			tenantID := types.TenantFrom(ctx)
 			for _, entry := range stagingEntries {
+				if entry.Tenant() != tenantID {
+					continue
+				}
 				results = append(results, types.Record{
 					ID:        entry.ID,
 					Content:   entry.Content,
@@ -313,7 +556,7 @@ func (m *Manager) List(ctx context.Context, filter Filter) ([]types.Record, erro
 	// 3. Fetch Long-Term Memory if requested
 	if filter.Type == "long_term" || filter.Type == "all" || filter.Type == "" {
 		// Call Vector Store List with filters
-		vFilters := make(map[string]interface{})
+		vFilters := map[string]interface{}{metaTenantID: types.TenantFrom(ctx)}
 		if filter.UserID != "" {
 			vFilters["user_id"] = filter.UserID
 		}
@@ -386,13 +629,13 @@ func (m *Manager) List(ctx context.Context, filter Filter) ([]types.Record, erro
 	return results[offset:end], nil
 }
 
-// Update modifies a memory record.
+// Update modifies a memory record of the current tenant.
 func (m *Manager) Update(ctx context.Context, id string, newContent string) error {
 	var rec *types.Record
 	var isLTM bool
 
 	// 1. Try LTM
-	if r, err := m.vectorStore.Get(ctx, id); err == nil {
+	if r, err := m.vectorStore.Get(ctx, id); err == nil && recordTenant(r) == types.TenantFrom(ctx) {
 		rec = r
 		isLTM = true
 	} else {
@@ -401,7 +644,7 @@ func (m *Manager) Update(ctx context.Context, id string, newContent string) erro
 			rec = r
 			isLTM = false
 		} else {
-			return fmt.Errorf("record not found in LTM or STM")
+			return fmt.Errorf("%w in LTM or STM", ErrRecordNotFound)
 		}
 	}
 
@@ -413,36 +656,57 @@ func (m *Manager) Update(ctx context.Context, id string, newContent string) erro
 	}
 
 	// 4. Update fields
+	oldContent := rec.Content
 	rec.Content = newContent
 	rec.Embedding = vector
 	// Keep Timestamp
 
 	// 5. Save
+	storeName := "ltm"
 	if isLTM {
 		if err := m.vectorStore.Update(ctx, *rec); err != nil {
 			return fmt.Errorf("failed to update LTM: %w", err)
 		}
 	} else {
+		storeName = "stm"
 		if err := m.stmStore.Update(ctx, *rec); err != nil {
 			return fmt.Errorf("failed to update STM: %w", err)
 		}
 	}
 
+	m.auditMemory(ctx, AuditMemoryUpdate, id, recordUserID(rec), map[string]interface{}{
+		"store":       storeName,
+		"old_content": oldContent,
+		"new_content": newContent,
+	})
 	return nil
 }
 
-// Delete removes a record from LTM by ID.
+// Delete removes a record of the current tenant from LTM by ID.
 func (m *Manager) Delete(ctx context.Context, id string) error {
-	return m.vectorStore.Delete(ctx, []string{id})
+	rec, err := m.vectorStore.Get(ctx, id)
+	if err != nil || recordTenant(rec) != types.TenantFrom(ctx) {
+		return ErrRecordNotFound
+	}
+	if err := m.vectorStore.Delete(ctx, []string{id}); err != nil {
+		return err
+	}
+
+	m.auditMemory(ctx, AuditMemoryDelete, id, recordUserID(rec), map[string]interface{}{
+		"content":  rec.Content,
+		"category": rec.Metadata["category"],
+	})
+	return nil
 }
 
 // Clear resets both stores.
 func (m *Manager) Clear(ctx context.Context, userID string, sessionID string) error {
-	key := fmt.Sprintf("memory:stm:%s:%s", userID, sessionID)
+	key := stmKey(ctx, userID, sessionID)
 	if err := m.stmStore.Del(ctx, key); err != nil {
 		return err
 	}
 	logger.System("STM cleared", "user_id", userID, "session_id", sessionID)
+	m.auditMemory(ctx, AuditSTMClear, sessionID, userID, nil)
 	return nil
 }
 
@@ -463,19 +727,65 @@ func (m *Manager) GetUsers(ctx context.Context) ([]types.EndUser, error) {
 		u := &users[i]
 
 		// STM Sessions Count
-		// Pattern: memory:stm:<UserID>:*
-		pattern := fmt.Sprintf("memory:stm:%s:*", u.UserIdentifier)
-		keys, _ := m.stmStore.ScanKeys(ctx, pattern)
+		keys, _ := m.stmStore.ScanKeys(ctx, stmUserPattern(ctx, u.UserIdentifier))
 		u.SessionCount = len(keys)
 
 		// LTM Count
-		count, _ := m.vectorStore.Count(ctx, map[string]interface{}{"user_id": u.UserIdentifier})
+		count, _ := m.vectorStore.Count(ctx, userFilters(ctx, u.UserIdentifier))
 		u.LTMCount = int(count)
 	}
 
 	return users, nil
 }
 
+// JudgeObserver STM 判定结果回调：staged 表示该条记录进入了暂存区或绿色通道
+type JudgeObserver func(record types.Record, result *types.JudgeResult, staged bool)
+
+// SetJudgeObserver 设置判定结果观察者（如 cmd/eval 统计判定准确率）
+func (m *Manager) SetJudgeObserver(observer JudgeObserver) {
+	m.judgeObserver = observer
+}
+
+// SetUserLanguage 设置用户的 Prompt 语言偏好（空字符串表示恢复默认）
+func (m *Manager) SetUserLanguage(ctx context.Context, userID, language string) error {
+	if m.endUserStore == nil {
+		return fmt.Errorf("end user store not initialized")
+	}
+	return m.endUserStore.SetLanguage(ctx, userID, language)
+}
+
+// ListTenants 列出出现过终端用户的租户（管理界面切换租户用）
+func (m *Manager) ListTenants(ctx context.Context) ([]string, error) {
+	if m.endUserStore == nil {
+		return nil, nil
+	}
+	return m.endUserStore.ListTenants(ctx)
+}
+
+// ListPromptTemplates 列出当前生效的 Prompt 模板
+func (m *Manager) ListPromptTemplates() []PromptTemplate {
+	return m.prompts.List()
+}
+
+// resolvePromptLanguage 确定判定使用的 Prompt 语言：
+// 记录中携带的 language（写入请求指定）→ 用户语言偏好 → 默认语言
+func (m *Manager) resolvePromptLanguage(ctx context.Context, userID string, records []types.Record) string {
+	for _, record := range records {
+		if language, ok := record.Metadata["language"].(string); ok && language != "" {
+			return language
+		}
+	}
+	if m.endUserStore != nil && userID != "" {
+		language, err := m.endUserStore.GetLanguage(ctx, userID)
+		if err != nil {
+			logger.Error("读取用户语言偏好失败", err)
+		} else if language != "" {
+			return language
+		}
+	}
+	return m.prompts.DefaultLanguage()
+}
+
 // GetSystemStatus returns basic health info.
 func (m *Manager) GetSystemStatus(ctx context.Context) map[string]string {
 	status := make(map[string]string)
@@ -494,9 +804,26 @@ func (m *Manager) GetSystemStatus(ctx context.Context) map[string]string {
 		status["LongTermMemory"] = "Online"
 	}
 
+	// LLM / Embedding 熔断器状态
+	for _, breaker := range llm.GetBreakerStatuses() {
+		status["Breaker("+breaker.Name+")"] = breakerStatusText(breaker.State)
+	}
+
 	return status
 }
 
+// breakerStatusText 熔断器状态的展示文本
+func breakerStatusText(state llm.BreakerState) string {
+	switch state {
+	case llm.BreakerOpen:
+		return "Circuit Open"
+	case llm.BreakerHalfOpen:
+		return "Half-Open"
+	default:
+		return "Online"
+	}
+}
+
 // GetRecentAlerts 获取最近的告警记录（供API调用）
 func (m *Manager) GetRecentAlerts(limit int) []Alert {
 	if m.alertEngine == nil {
@@ -505,20 +832,25 @@ func (m *Manager) GetRecentAlerts(limit int) []Alert {
 	return m.alertEngine.GetRecentAlerts(limit)
 }
 
-// QueryAlerts 查询告警
-func (m *Manager) QueryAlerts(ctx context.Context, level, rule string, limit, offset int) ([]Alert, int, error) {
+// QueryAlerts 查询当前租户的告警，includeSystem 时同时返回系统级告警
+func (m *Manager) QueryAlerts(ctx context.Context, level, rule string, includeSystem bool, limit, offset int) ([]Alert, int, error) {
 	if m.alertEngine == nil {
 		return nil, 0, fmt.Errorf("alert engine not initialized")
 	}
-	return m.alertEngine.QueryAlerts(ctx, level, rule, limit, offset)
+	return m.alertEngine.QueryAlerts(ctx, level, rule, alertScope(ctx, includeSystem), limit, offset)
 }
 
-// DeleteAlert 删除告警
-func (m *Manager) DeleteAlert(ctx context.Context, id string) error {
+// DeleteAlert 删除当前租户的告警（includeSystem 时也可删除系统级告警），不可见的告警返回 ErrRecordNotFound
+func (m *Manager) DeleteAlert(ctx context.Context, id string, includeSystem bool) error {
 	if m.alertEngine == nil {
 		return fmt.Errorf("alert engine not initialized")
 	}
-	return m.alertEngine.DeleteAlert(ctx, id)
+	return m.alertEngine.DeleteAlert(ctx, id, alertScope(ctx, includeSystem))
+}
+
+// alertScope 按 context 中的租户构造告警可见范围
+func alertScope(ctx context.Context, includeSystem bool) AlertScope {
+	return AlertScope{TenantID: types.TenantFrom(ctx), IncludeSystem: includeSystem}
 }
 
 // CreateAlert 创建告警
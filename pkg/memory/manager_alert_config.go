@@ -1,13 +1,18 @@
 package memory
 
 import (
+	"context"
 	"fmt"
 )
 
 // UpdateAlertRuleConfigJSON 更新规则配置JSON（Manager代理）
-func (m *Manager) UpdateAlertRuleConfigJSON(ruleID string, configJSON string) error {
+func (m *Manager) UpdateAlertRuleConfigJSON(ctx context.Context, ruleID string, configJSON string) error {
 	if m.alertEngine == nil {
 		return fmt.Errorf("alert engine not initialized")
 	}
-	return m.alertEngine.UpdateRuleConfigJSON(ruleID, configJSON)
+	if err := m.alertEngine.UpdateRuleConfigJSON(ruleID, configJSON); err != nil {
+		return err
+	}
+	m.auditAlertRule(ctx, ruleID, map[string]interface{}{"config_json": configJSON})
+	return nil
 }
@@ -15,19 +15,32 @@ func (m *Manager) GetAllAlertRules() []RuleInfo {
 }
 
 // ToggleAlertRule 启用/禁用告警规则
-func (m *Manager) ToggleAlertRule(ruleID string, enabled bool) error {
+func (m *Manager) ToggleAlertRule(ctx context.Context, ruleID string, enabled bool) error {
 	if m.alertEngine == nil {
 		return fmt.Errorf("alert engine not initialized")
 	}
-	return m.alertEngine.ToggleRule(ruleID, enabled)
+	if err := m.alertEngine.ToggleRule(ruleID, enabled); err != nil {
+		return err
+	}
+	m.auditAlertRule(ctx, ruleID, map[string]interface{}{"enabled": enabled})
+	return nil
 }
 
 // UpdateAlertRuleCooldown 更新规则冷却时间
-func (m *Manager) UpdateAlertRuleCooldown(ruleID string, cooldown time.Duration) error {
+func (m *Manager) UpdateAlertRuleCooldown(ctx context.Context, ruleID string, cooldown time.Duration) error {
 	if m.alertEngine == nil {
 		return fmt.Errorf("alert engine not initialized")
 	}
-	return m.alertEngine.UpdateRuleCooldown(ruleID, cooldown)
+	if err := m.alertEngine.UpdateRuleCooldown(ruleID, cooldown); err != nil {
+		return err
+	}
+	m.auditAlertRule(ctx, ruleID, map[string]interface{}{"cooldown_minutes": cooldown.Minutes()})
+	return nil
+}
+
+// auditAlertRule 记录告警规则变更（系统级，不归属租户）
+func (m *Manager) auditAlertRule(ctx context.Context, ruleID string, detail map[string]interface{}) {
+	m.RecordAudit(ctx, AuditEntry{Action: AuditAlertRuleUpdate, TargetID: ruleID, Detail: detail})
 }
 
 // GetAlertStats 获取告警统计信息
@@ -38,18 +51,23 @@ func (m *Manager) GetAlertStats() *AlertEngineStats {
 	return m.alertEngine.GetStats()
 }
 
-// GetAlertTrend 获取告警趋势
-func (m *Manager) GetAlertTrend(ctx context.Context, hours int) (map[string]interface{}, error) {
+// GetAlertTrend 获取当前租户的告警趋势，includeSystem 时包含系统级告警
+func (m *Manager) GetAlertTrend(ctx context.Context, hours int, includeSystem bool) (map[string]interface{}, error) {
 	if m.alertEngine == nil {
 		return nil, fmt.Errorf("alert engine not initialized")
 	}
-	return m.alertEngine.GetAlertTrend(ctx, hours)
+	return m.alertEngine.GetAlertTrend(ctx, hours, alertScope(ctx, includeSystem))
 }
 
-// GetAlertsByLevel 按级别统计告警
-func (m *Manager) GetAlertsByLevel(ctx context.Context) (map[AlertLevel]int, error) {
+// GetAlertsByLevel 按级别统计当前租户的告警，includeSystem 时包含系统级告警
+func (m *Manager) GetAlertsByLevel(ctx context.Context, includeSystem bool) (map[AlertLevel]int, error) {
 	if m.alertEngine == nil {
 		return nil, fmt.Errorf("alert engine not initialized")
 	}
-	return m.alertEngine.GetAlertsByLevel(ctx)
+	return m.alertEngine.GetAlertsByLevel(ctx, alertScope(ctx, includeSystem))
+}
+
+// GetAggregatedAlerts 获取当前租户聚合后的告警，includeSystem 时包含系统级告警
+func (m *Manager) GetAggregatedAlerts(ctx context.Context, includeSystem bool) []*AggregatedAlert {
+	return GetAggregatedAlerts(alertScope(ctx, includeSystem))
 }
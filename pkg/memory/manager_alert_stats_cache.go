@@ -5,10 +5,11 @@ import (
 	"fmt"
 )
 
-// GetAlertStatsWithCache 获取告警统计（带缓存，Manager代理方法）
-func (m *Manager) GetAlertStatsWithCache(ctx context.Context) (*AlertEngineStats, map[AlertLevel]int, error) {
+// GetAlertStatsWithCache 获取告警统计（带缓存，Manager代理方法），按级别计数只统计当前租户的告警，
+// includeSystem 时包含系统级告警
+func (m *Manager) GetAlertStatsWithCache(ctx context.Context, includeSystem bool) (*AlertEngineStats, map[AlertLevel]int, error) {
 	if m.alertEngine == nil {
 		return nil, nil, fmt.Errorf("alert engine not initialized")
 	}
-	return m.alertEngine.GetStatsWithCache(ctx)
+	return m.alertEngine.GetStatsWithCache(ctx, alertScope(ctx, includeSystem))
 }
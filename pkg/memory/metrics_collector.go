@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"ai-memory/pkg/types"
 	"context"
 	"database/sql"
 	"sync"
@@ -25,21 +26,27 @@ type cacheEntry struct {
 
 type dashboardCache struct {
 	mu    sync.RWMutex
-	cache map[string]*cacheEntry // key: timeRange (1h/24h/7d/30d)
+	cache map[string]*cacheEntry // key: timeRange (1h/24h/7d/30d)，单租户视角追加 |<tenantID>
 }
 
 var dbCache = &dashboardCache{
 	cache: make(map[string]*cacheEntry),
 }
 
-// 分类分布独立缓存（30秒过期，不随时间范围变化）
+// 分类分布独立缓存（30秒过期，不随时间范围变化，按租户视角分别缓存，全部租户的 key 为空）
 type categoryCache struct {
-	mu       sync.RWMutex
+	mu      sync.RWMutex
+	entries map[string]*categoryCacheEntry
+}
+
+type categoryCacheEntry struct {
 	data     map[string]int
 	expireAt time.Time
 }
 
-var catCache = &categoryCache{}
+var catCache = &categoryCache{
+	entries: make(map[string]*categoryCacheEntry),
+}
 
 // MetricsCollector 监控指标收集器
 type MetricsCollector struct {
@@ -56,12 +63,20 @@ type MetricsCollector struct {
 	TotalForgotten  int64
 	CacheHits       int64
 	CacheMisses     int64
+
+	// 向量化缓存统计（进程启动以来）
+	EmbeddingCacheHits   int64
+	EmbeddingCacheMisses int64
+
+	// LLM 用量统计（独立加锁）
+	llmUsage *llmUsageStats
 }
 
 type TimeSeriesPoint struct {
 	Timestamp time.Time `json:"timestamp"`
 	Value     float64   `json:"value"`
 	Label     string    `json:"label,omitempty"`
+	TenantID  string    `json:"tenant_id,omitempty"` // 仅晋升记录带租户（升级前的记录为空，视为默认租户）
 }
 
 type CategoryCount struct {
@@ -74,6 +89,10 @@ var globalMetrics = &MetricsCollector{
 	PromotionHistory:   make([]TimeSeriesPoint, 0, 144), // 24小时，每10分钟一个点
 	QueueLengthHistory: make([]TimeSeriesPoint, 0, 144),
 	CategoryHistory:    make([]CategoryCount, 0),
+	llmUsage: &llmUsageStats{
+		pending: make(map[llmUsageKey]*LLMUsageRow),
+		totals:  make(map[llmUsageKey]*LLMUsageRow),
+	},
 }
 
 // GetGlobalMetrics 获取全局指标收集器实例（供main.go等外部使用）
@@ -82,7 +101,7 @@ func GetGlobalMetrics() *MetricsCollector {
 }
 
 // RecordPromotion 记录晋升事件
-func (mc *MetricsCollector) RecordPromotion(category string, success bool) {
+func (mc *MetricsCollector) RecordPromotion(tenantID, category string, success bool) {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
 
@@ -93,6 +112,7 @@ func (mc *MetricsCollector) RecordPromotion(category string, success bool) {
 			Timestamp: now,
 			Value:     1,
 			Label:     category,
+			TenantID:  tenantID,
 		})
 	} else {
 		mc.TotalRejections++
@@ -102,6 +122,15 @@ func (mc *MetricsCollector) RecordPromotion(category string, success bool) {
 	mc.trimHistory(&mc.PromotionHistory, 24*time.Hour)
 }
 
+// RecordEmbeddingCache 记录向量化缓存命中 / 未命中次数
+func (mc *MetricsCollector) RecordEmbeddingCache(hits, misses int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.EmbeddingCacheHits += int64(hits)
+	mc.EmbeddingCacheMisses += int64(misses)
+}
+
 // RecordQueueLength 记录队列长度
 func (mc *MetricsCollector) RecordQueueLength(length int) {
 	mc.mu.Lock()
@@ -162,10 +191,19 @@ func (mc *MetricsCollector) trimHistory(history *[]TimeSeriesPoint, duration tim
 // GetDashboardMetrics 获取Dashboard所需的所有指标
 // timeRange 支持: 1h, 24h, 7d, 30d
 // 使用 30 秒本地缓存减少数据库查询
-func (m *Manager) GetDashboardMetrics(ctx context.Context, timeRange string) map[string]interface{} {
-	// 检查缓存是否命中（按时间范围独立缓存）
+// allTenants 为 false 时只返回 context 中租户的数据：暂存队列、晋升趋势、分类分布与 LLM 用量按租户过滤，
+// total_promotions 为时间范围内该租户的晋升数；只有全局口径的队列长度趋势、拒绝/遗忘累计与成功率不返回。
+// 缓存命中率是服务级指标、不含租户数据，始终返回
+func (m *Manager) GetDashboardMetrics(ctx context.Context, timeRange string, allTenants bool) map[string]interface{} {
+	tenantID, cacheKey := "", timeRange
+	if !allTenants {
+		tenantID = types.TenantFrom(ctx)
+		cacheKey = timeRange + "|" + tenantID
+	}
+
+	// 检查缓存是否命中（按时间范围与租户视角独立缓存）
 	dbCache.mu.RLock()
-	if entry, ok := dbCache.cache[timeRange]; ok && time.Now().Before(entry.expireAt) {
+	if entry, ok := dbCache.cache[cacheKey]; ok && time.Now().Before(entry.expireAt) {
 		cached := entry.data
 		dbCache.mu.RUnlock()
 		return cached
@@ -180,10 +218,12 @@ func (m *Manager) GetDashboardMetrics(ctx context.Context, timeRange string) map
 	hours := parseTimeRangeToHours(timeRange)
 
 	// 获取当前Staging队列长度
-	currentQueueLength := m.getStagingQueueLength(ctx)
+	currentQueueLength := m.getStagingQueueLength(ctx, tenantID)
 
-	// 更新队列长度历史
-	go globalMetrics.RecordQueueLength(currentQueueLength)
+	// 更新队列长度历史（队列长度趋势为全局口径，只记录全部租户的长度）
+	if allTenants {
+		go globalMetrics.RecordQueueLength(currentQueueLength)
+	}
 
 	// 1. 获取DB中的原始数据
 	dbPromotions, dbQueues := m.queryRawMetricsFromDB(ctx, hours)
@@ -230,6 +270,9 @@ func (m *Manager) GetDashboardMetrics(ctx context.Context, timeRange string) map
 	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour)
 	allPromotions = filterPointsAfter(allPromotions, cutoff)
 	allQueues = filterPointsAfter(allQueues, cutoff)
+	if tenantID != "" {
+		allPromotions = filterPointsByTenant(allPromotions, tenantID)
+	}
 
 	// 4. 执行聚合
 	var promotionTrend, queueTrend []TimeSeriesPoint
@@ -248,22 +291,26 @@ func (m *Manager) GetDashboardMetrics(ctx context.Context, timeRange string) map
 	// 长期记忆分布：使用独立缓存，30秒过期后再查DB
 	var categoryMap map[string]int
 	catCache.mu.RLock()
-	if catCache.data != nil && time.Now().Before(catCache.expireAt) {
-		categoryMap = catCache.data
+	if entry, ok := catCache.entries[tenantID]; ok && time.Now().Before(entry.expireAt) {
+		categoryMap = entry.data
 		catCache.mu.RUnlock()
 	} else {
 		catCache.mu.RUnlock()
 		// 缓存过期，查询数据库
-		categoryMap = m.queryCategoryDistributionFromDB(ctx, 24*30)
+		categoryMap = m.queryCategoryDistributionFromDB(ctx, 24*30, tenantID)
 		// 更新缓存
 		catCache.mu.Lock()
-		catCache.data = categoryMap
-		catCache.expireAt = time.Now().Add(dashboardCacheTTL)
+		catCache.entries[tenantID] = &categoryCacheEntry{
+			data:     categoryMap,
+			expireAt: time.Now().Add(dashboardCacheTTL),
+		}
 		catCache.mu.Unlock()
 	}
 
 	// 更新全局分类分布缓存
-	go globalMetrics.UpdateCategoryDistribution(categoryMap)
+	if allTenants {
+		go globalMetrics.UpdateCategoryDistribution(categoryMap)
+	}
 
 	// 计算成功率
 	totalAttempts := globalMetrics.TotalPromotions + globalMetrics.TotalRejections
@@ -279,6 +326,13 @@ func (m *Manager) GetDashboardMetrics(ctx context.Context, timeRange string) map
 		cacheHitRate = float64(globalMetrics.CacheHits) / float64(totalCacheAccess) * 100
 	}
 
+	// 向量化缓存命中率
+	totalEmbeddingAccess := globalMetrics.EmbeddingCacheHits + globalMetrics.EmbeddingCacheMisses
+	embeddingCacheHitRate := 0.0
+	if totalEmbeddingAccess > 0 {
+		embeddingCacheHitRate = float64(globalMetrics.EmbeddingCacheHits) / float64(totalEmbeddingAccess) * 100
+	}
+
 	result := map[string]interface{}{
 		// 实时统计
 		"current_queue_length": currentQueueLength,
@@ -294,6 +348,11 @@ func (m *Manager) GetDashboardMetrics(ctx context.Context, timeRange string) map
 		"cache_hits":     globalMetrics.CacheHits,
 		"cache_misses":   globalMetrics.CacheMisses,
 
+		// 向量化缓存统计
+		"embedding_cache_hit_rate": embeddingCacheHitRate,
+		"embedding_cache_hits":     globalMetrics.EmbeddingCacheHits,
+		"embedding_cache_misses":   globalMetrics.EmbeddingCacheMisses,
+
 		// 时间序列
 		"promotion_trend":    promotionTrend,
 		"queue_length_trend": queueTrend,
@@ -301,14 +360,29 @@ func (m *Manager) GetDashboardMetrics(ctx context.Context, timeRange string) map
 		// 分类分布（直接使用查询结果，转换为 CategoryCount 格式）
 		"category_distribution": convertToCategoryHistory(categoryMap),
 
+		// LLM 用量与成本（按调用类型 / 模型 / 终端用户）
+		"llm_usage": m.getLLMUsageSummary(ctx, hours, tenantID),
+
 		// 元信息
 		"timestamp":        time.Now().Format(time.RFC3339),
 		"data_range_hours": hours,
 	}
 
-	// 更新缓存（按时间范围独立存储）
+	// 单租户视角：晋升数取时间范围内该租户的晋升，去掉只有全局口径的指标
+	if !allTenants {
+		var tenantPromotions float64
+		for _, p := range allPromotions {
+			tenantPromotions += p.Value
+		}
+		result["total_promotions"] = int64(tenantPromotions)
+		for _, key := range []string{"total_rejections", "total_forgotten", "promotion_success_rate", "queue_length_trend"} {
+			delete(result, key)
+		}
+	}
+
+	// 更新缓存（按时间范围与租户视角独立存储）
 	dbCache.mu.Lock()
-	dbCache.cache[timeRange] = &cacheEntry{
+	dbCache.cache[cacheKey] = &cacheEntry{
 		data:     result,
 		expireAt: time.Now().Add(dashboardCacheTTL),
 	}
@@ -343,10 +417,19 @@ func convertToCategoryHistory(categoryMap map[string]int) []CategoryCount {
 	return result
 }
 
-// getStagingQueueLength 获取 Staging 队列长度
-func (m *Manager) getStagingQueueLength(ctx context.Context) int {
+// getStagingQueueLength 获取 Staging 队列长度（tenantID 非空时只统计该租户）
+func (m *Manager) getStagingQueueLength(ctx context.Context, tenantID string) int {
 	entries, _ := m.stagingStore.GetPendingEntries(ctx, 1, 0)
-	return len(entries)
+	if tenantID == "" {
+		return len(entries)
+	}
+	count := 0
+	for _, entry := range entries {
+		if entry.Tenant() == tenantID {
+			count++
+		}
+	}
+	return count
 }
 
 // parseTimeRangeToHours 解析时间范围字符串为小时数
@@ -372,7 +455,7 @@ func (m *Manager) queryRawMetricsFromDB(ctx context.Context, hours int) (promoti
 	}
 
 	query := `
-		SELECT metric_type, value, timestamp
+		SELECT metric_type, value, tenant_id, timestamp
 		FROM metrics_timeseries 
 		WHERE timestamp >= DATE_SUB(NOW(), INTERVAL ? HOUR)
 		ORDER BY timestamp ASC
@@ -390,15 +473,17 @@ func (m *Manager) queryRawMetricsFromDB(ctx context.Context, hours int) (promoti
 	for rows.Next() {
 		var metricType string
 		var value float64
+		var tenantID sql.NullString
 		var timestamp time.Time
 
-		if err := rows.Scan(&metricType, &value, &timestamp); err != nil {
+		if err := rows.Scan(&metricType, &value, &tenantID, &timestamp); err != nil {
 			continue
 		}
 
 		point := TimeSeriesPoint{
 			Timestamp: timestamp,
 			Value:     value,
+			TenantID:  tenantID.String,
 		}
 
 		switch metricType {
@@ -423,23 +508,41 @@ func filterPointsAfter(points []TimeSeriesPoint, cutoff time.Time) []TimeSeriesP
 	return result
 }
 
-// queryCategoryDistributionFromDB 从数据库直接统计分类分布
-func (m *Manager) queryCategoryDistributionFromDB(ctx context.Context, hours int) map[string]int {
+// filterPointsByTenant 过滤出属于指定租户的数据点
+func filterPointsByTenant(points []TimeSeriesPoint, tenantID string) []TimeSeriesPoint {
+	result := make([]TimeSeriesPoint, 0, len(points))
+	for _, p := range points {
+		if tenantOrDefault(p.TenantID) == tenantID {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// queryCategoryDistributionFromDB 从数据库直接统计分类分布（tenantID 非空时只统计该租户，未标记租户的记录算作默认租户）
+func (m *Manager) queryCategoryDistributionFromDB(ctx context.Context, hours int, tenantID string) map[string]int {
 	categoryMap := make(map[string]int)
 	if metricsDB == nil {
 		return categoryMap
 	}
 
+	tenantCondition := ""
+	args := []interface{}{hours}
+	if tenantID != "" {
+		tenantCondition = "AND COALESCE(NULLIF(tenant_id, ''), ?) = ?"
+		args = append(args, types.DefaultTenant, tenantID)
+	}
 	query := `
 		SELECT category, COUNT(*) as cnt
 		FROM metrics_timeseries FORCE INDEX (idx_type_time)
 		WHERE metric_type = 'promotion' 
 		  AND category IS NOT NULL 
 		  AND timestamp >= DATE_SUB(NOW(), INTERVAL ? HOUR)
+		  ` + tenantCondition + `
 		GROUP BY category
 	`
 
-	rows, err := metricsDB.QueryContext(ctx, query, hours)
+	rows, err := metricsDB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return categoryMap
 	}
@@ -70,6 +70,11 @@ func (mp *MetricsPersistence) persistMetrics(collector *MetricsCollector) {
 	if err := mp.insertTimeSeriesData(ctx, collector); err != nil {
 		logger.Error("Failed to persist timeseries data", err)
 	}
+
+	// 3. 写入LLM用量
+	if err := mp.insertLLMUsage(ctx, collector); err != nil {
+		logger.Error("Failed to persist llm usage", err)
+	}
 }
 
 // updateCumulativeStats 更新累计统计表
@@ -104,7 +109,7 @@ func (mp *MetricsPersistence) insertTimeSeriesData(ctx context.Context, collecto
 	defer tx.Rollback()
 
 	stmt, err := tx.PrepareContext(ctx,
-		"INSERT INTO metrics_timeseries (metric_type, value, category, timestamp) VALUES (?, ?, ?, ?)")
+		"INSERT INTO metrics_timeseries (metric_type, value, category, tenant_id, timestamp) VALUES (?, ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
@@ -114,7 +119,7 @@ func (mp *MetricsPersistence) insertTimeSeriesData(ctx context.Context, collecto
 	maxTime := mp.lastPersistedTime
 	for _, point := range collector.PromotionHistory {
 		if point.Timestamp.After(mp.lastPersistedTime) {
-			if _, err := stmt.ExecContext(ctx, "promotion", point.Value, point.Label, point.Timestamp); err != nil {
+			if _, err := stmt.ExecContext(ctx, "promotion", point.Value, point.Label, point.TenantID, point.Timestamp); err != nil {
 				logger.Error("Failed to insert promotion metric", err)
 			}
 			if point.Timestamp.After(maxTime) {
@@ -128,7 +133,7 @@ func (mp *MetricsPersistence) insertTimeSeriesData(ctx context.Context, collecto
 		if point.Timestamp.After(mp.lastPersistedTime) {
 			// 只在队列长度变化时才写入数据库
 			if point.Value != mp.lastQueueLength {
-				if _, err := stmt.ExecContext(ctx, "queue_length", point.Value, nil, point.Timestamp); err != nil {
+				if _, err := stmt.ExecContext(ctx, "queue_length", point.Value, nil, nil, point.Timestamp); err != nil {
 					logger.Error("Failed to insert queue_length metric", err)
 				}
 				mp.lastQueueLength = point.Value
@@ -209,7 +214,7 @@ func (mp *MetricsPersistence) LoadCumulativeStats(ctx context.Context, collector
 // LoadRecentTimeSeries 加载最近N小时的时间序列数据
 func (mp *MetricsPersistence) LoadRecentTimeSeries(ctx context.Context, collector *MetricsCollector, hours int) error {
 	query := `
-		SELECT metric_type, value, category, timestamp 
+		SELECT metric_type, value, category, tenant_id, timestamp 
 		FROM metrics_timeseries 
 		WHERE timestamp >= DATE_SUB(NOW(), INTERVAL ? HOUR)
 		ORDER BY timestamp ASC
@@ -231,10 +236,10 @@ func (mp *MetricsPersistence) LoadRecentTimeSeries(ctx context.Context, collecto
 	for rows.Next() {
 		var metricType string
 		var value float64
-		var category sql.NullString
+		var category, tenantID sql.NullString
 		var timestamp time.Time
 
-		if err := rows.Scan(&metricType, &value, &category, &timestamp); err != nil {
+		if err := rows.Scan(&metricType, &value, &category, &tenantID, &timestamp); err != nil {
 			continue
 		}
 
@@ -246,6 +251,9 @@ func (mp *MetricsPersistence) LoadRecentTimeSeries(ctx context.Context, collecto
 		if category.Valid {
 			point.Label = category.String
 		}
+		if tenantID.Valid {
+			point.TenantID = tenantID.String
+		}
 
 		switch metricType {
 		case "promotion":
@@ -289,6 +297,14 @@ func (mp *MetricsPersistence) CleanupOldData(ctx context.Context, retentionDays
 	}
 
 	rowsDeleted, _ := result.RowsAffected()
+
+	usageResult, err := mp.db.ExecContext(ctx, `DELETE FROM llm_usage WHERE timestamp < DATE_SUB(NOW(), INTERVAL ? DAY)`, retentionDays)
+	if err != nil {
+		return err
+	}
+	usageDeleted, _ := usageResult.RowsAffected()
+	rowsDeleted += usageDeleted
+
 	if rowsDeleted > 0 {
 		logger.System("✅ 监控数据清理完成", "deleted_rows", rowsDeleted, "retention_days", retentionDays)
 	}
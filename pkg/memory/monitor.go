@@ -1,8 +1,12 @@
 package memory
 
 import (
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/store"
 	"ai-memory/pkg/types"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"sync"
 	"time"
 )
@@ -19,73 +23,72 @@ type PerformanceMonitor struct {
 	JudgmentCacheHits      int64
 	JudgmentCacheMisses    int64
 
-	// 缓存
-	judgeCache      map[string]*types.JudgeResult
-	cacheExpiry     time.Duration
-	cacheTimestamps map[string]time.Time
+	// 判定结果缓存（key = 判定 Prompt 版本 + 语言 + 内容哈希）
+	judgeCache   JudgeCache
+	cacheVersion string
 }
 
-// NewPerformanceMonitor 创建监控实例
-func NewPerformanceMonitor() *PerformanceMonitor {
+// NewPerformanceMonitor 创建监控实例，cacheVersion 变化时旧的判定缓存不再命中
+func NewPerformanceMonitor(cache JudgeCache, cacheVersion string) *PerformanceMonitor {
 	return &PerformanceMonitor{
-		judgeCache:      make(map[string]*types.JudgeResult),
-		cacheTimestamps: make(map[string]time.Time),
-		cacheExpiry:     time.Hour * 24, // 缓存24小时
+		judgeCache:   cache,
+		cacheVersion: cacheVersion,
 	}
 }
 
-// GetJudgeResultFromCache 从缓存获取判定结果
-func (pm *PerformanceMonitor) GetJudgeResultFromCache(content string) (*types.JudgeResult, bool) {
+// SetJudgeCache 替换判定缓存后端
+func (pm *PerformanceMonitor) SetJudgeCache(cache JudgeCache) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.judgeCache = cache
+}
+
+// judgeCacheKey 缓存 key：判定 Prompt 版本 + 请求语言 + 内容 SHA-256
+// 语言不同时判定结果（及其记录的 Prompt 版本）不可复用，否则后续总结/合并会沿用错误的语言
+func (pm *PerformanceMonitor) judgeCacheKey(ctx context.Context, content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return pm.cacheVersion + ":" + PromptLanguageFrom(ctx) + ":" + hex.EncodeToString(sum[:])
+}
+
+// GetJudgeResultFromCache 从缓存获取判定结果（缓存读取失败按未命中处理）
+func (pm *PerformanceMonitor) GetJudgeResultFromCache(ctx context.Context, content string) (*types.JudgeResult, bool) {
 	pm.mu.RLock()
-	defer pm.mu.RUnlock()
+	cache := pm.judgeCache
+	pm.mu.RUnlock()
 
-	result, exists := pm.judgeCache[content]
-	if !exists {
-		pm.mu.RUnlock()
-		pm.mu.Lock()
-		pm.JudgmentCacheMisses++
-		GetGlobalMetrics().mu.Lock()
-		GetGlobalMetrics().CacheMisses++
-		GetGlobalMetrics().mu.Unlock()
-		pm.mu.Unlock()
-		pm.mu.RLock()
-		return nil, false
+	result, ok, err := cache.Get(ctx, pm.judgeCacheKey(ctx, content))
+	if err != nil {
+		logger.Error("读取判定缓存失败", err)
 	}
 
-	// 检查过期
-	if time.Since(pm.cacheTimestamps[content]) > pm.cacheExpiry {
-		pm.mu.RUnlock()
-		pm.mu.Lock()
-		delete(pm.judgeCache, content)
-		delete(pm.cacheTimestamps, content)
+	pm.mu.Lock()
+	if ok {
+		pm.JudgmentCacheHits++
+	} else {
 		pm.JudgmentCacheMisses++
-		GetGlobalMetrics().mu.Lock()
-		GetGlobalMetrics().CacheMisses++
-		GetGlobalMetrics().mu.Unlock()
-		pm.mu.Unlock()
-		pm.mu.RLock()
-		return nil, false
 	}
+	pm.mu.Unlock()
 
-	pm.mu.RUnlock()
-	pm.mu.Lock()
-	pm.JudgmentCacheHits++
 	GetGlobalMetrics().mu.Lock()
-	GetGlobalMetrics().CacheHits++
+	if ok {
+		GetGlobalMetrics().CacheHits++
+	} else {
+		GetGlobalMetrics().CacheMisses++
+	}
 	GetGlobalMetrics().mu.Unlock()
-	pm.mu.Unlock()
-	pm.mu.RLock()
 
-	return result, true
+	return result, ok
 }
 
 // SetJudgeResultCache 设置判定结果缓存
-func (pm *PerformanceMonitor) SetJudgeResultCache(content string, result *types.JudgeResult) {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
+func (pm *PerformanceMonitor) SetJudgeResultCache(ctx context.Context, content string, result *types.JudgeResult) {
+	pm.mu.RLock()
+	cache := pm.judgeCache
+	pm.mu.RUnlock()
 
-	pm.judgeCache[content] = result
-	pm.cacheTimestamps[content] = time.Now()
+	if err := cache.Set(ctx, pm.judgeCacheKey(ctx, content), result); err != nil {
+		logger.Error("写入判定缓存失败", err)
+	}
 }
 
 // RecordPromotion 记录晋升结果
@@ -133,7 +136,7 @@ func (pm *PerformanceMonitor) GetMetrics() map[string]interface{} {
 		cacheHitRate = float64(pm.JudgmentCacheHits) / float64(totalCacheAccess) * 100
 	}
 
-	return map[string]interface{}{
+	metrics := map[string]interface{}{
 		"staging_queue_length":     pm.StagingQueueLength,
 		"promotion_success_count":  pm.PromotionSuccessCount,
 		"promotion_fail_count":     pm.PromotionFailCount,
@@ -142,8 +145,12 @@ func (pm *PerformanceMonitor) GetMetrics() map[string]interface{} {
 		"judgment_cache_hits":      pm.JudgmentCacheHits,
 		"judgment_cache_misses":    pm.JudgmentCacheMisses,
 		"judgment_cache_hit_rate":  cacheHitRate,
-		"judgment_cache_size":      len(pm.judgeCache),
 	}
+	// 只有内存缓存能廉价地统计条数
+	if sized, ok := pm.judgeCache.(interface{ Len() int }); ok {
+		metrics["judgment_cache_size"] = sized.Len()
+	}
+	return metrics
 }
 
 // ========== Manager 扩展 ==========
@@ -151,10 +158,18 @@ func (pm *PerformanceMonitor) GetMetrics() map[string]interface{} {
 // 在Manager结构中添加监控器
 func (m *Manager) initPerformanceMonitor() {
 	if m.monitor == nil {
-		m.monitor = NewPerformanceMonitor()
+		// 默认使用进程内 LRU 缓存，JUDGE_CACHE_PROVIDER=redis 时由 SetJudgeCache 替换
+		cache := store.NewInMemoryJudgeCache(m.cfg.JudgeCacheMaxEntries, time.Duration(m.cfg.JudgeCacheTTLHours)*time.Hour)
+		m.monitor = NewPerformanceMonitor(cache, m.judge.CacheVersion())
 	}
 }
 
+// SetJudgeCache 设置判定缓存后端（如 Redis，多副本共享、重启不丢失）
+func (m *Manager) SetJudgeCache(cache JudgeCache) {
+	m.initPerformanceMonitor()
+	m.monitor.SetJudgeCache(cache)
+}
+
 // GetPerformanceMetrics 获取性能指标（供API调用）
 func (m *Manager) GetPerformanceMetrics(ctx context.Context) map[string]interface{} {
 	// 获取暂存区长度
@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestJudgeCacheKeyedByLanguage(t *testing.T) {
+	pm := NewPerformanceMonitor(store.NewInMemoryJudgeCache(10, time.Hour), "v1")
+	en := WithPromptLanguage(context.Background(), "en")
+	zh := WithPromptLanguage(context.Background(), "zh")
+
+	pm.SetJudgeResultCache(en, "I drink black coffee", &types.JudgeResult{
+		ValueScore:     0.9,
+		PromptVersions: map[string]string{PromptJudgeBatch: "en@abc"},
+	})
+
+	if _, ok := pm.GetJudgeResultFromCache(zh, "I drink black coffee"); ok {
+		t.Error("result cached for en should not be reused for zh")
+	}
+	cached, ok := pm.GetJudgeResultFromCache(en, "I drink black coffee")
+	if !ok || promptLanguageOf(cached.PromptVersions) != "en" {
+		t.Errorf("cached result for en = %+v, %v", cached, ok)
+	}
+}
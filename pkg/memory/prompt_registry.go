@@ -0,0 +1,307 @@
+package memory
+
+import (
+	"ai-memory/pkg/logger"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// 判定引擎使用的 Prompt 模板名
+const (
+	PromptJudgeValue    = "judge_value"
+	PromptJudgeBatch    = "judge_batch"
+	PromptExtractTags   = "extract_tags"
+	PromptSummarize     = "summarize"
+	PromptMergeStrategy = "merge_strategy"
+)
+
+// DefaultPromptLanguage 未指定语言且未配置 PROMPT_DEFAULT_LANGUAGE 时使用的语言
+const DefaultPromptLanguage = "zh"
+
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// PromptTemplate 某个 Prompt 在某种语言下的一个版本
+type PromptTemplate struct {
+	Name     string `json:"name"`
+	Language string `json:"language"`
+	Version  string `json:"version"` // 未显式指定时为模板内容的哈希
+	Source   string `json:"source"`  // builtin / file / db
+	Text     string `json:"text"`
+
+	tmpl *template.Template
+}
+
+// Ref 记录到元数据中的版本标识，如 "zh@3fa9c2d1"
+func (t *PromptTemplate) Ref() string {
+	return t.Language + "@" + t.Version
+}
+
+// promptData 模板变量
+type promptData struct {
+	Content  string
+	Category string
+	Existing string
+	New      string
+	Count    int
+	Items    []promptItem
+}
+
+type promptItem struct {
+	Label   int
+	Content string
+}
+
+// PromptRegistry Prompt 模板注册表
+// 加载顺序：内置模板 → PROMPT_TEMPLATE_DIR 目录 → prompt_templates 表，后加载的覆盖同名同语言的模板。
+type PromptRegistry struct {
+	mu              sync.RWMutex
+	defaultLanguage string
+	templates       map[string]map[string]*PromptTemplate // name -> language -> template
+}
+
+// NewPromptRegistry 创建注册表并加载内置模板
+func NewPromptRegistry(defaultLanguage string) *PromptRegistry {
+	if defaultLanguage == "" {
+		defaultLanguage = DefaultPromptLanguage
+	}
+	r := &PromptRegistry{
+		defaultLanguage: defaultLanguage,
+		templates:       make(map[string]map[string]*PromptTemplate),
+	}
+
+	entries, _ := builtinPrompts.ReadDir("prompts")
+	for _, entry := range entries {
+		data, err := builtinPrompts.ReadFile("prompts/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		name, language, ok := parsePromptFileName(entry.Name())
+		if !ok {
+			continue
+		}
+		if err := r.Register(PromptTemplate{Name: name, Language: language, Source: "builtin", Text: string(data)}); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// parsePromptFileName 解析 "<name>.<language>.tmpl"
+func parsePromptFileName(fileName string) (name, language string, ok bool) {
+	base := strings.TrimSuffix(fileName, ".tmpl")
+	if base == fileName {
+		return "", "", false
+	}
+	idx := strings.LastIndex(base, ".")
+	if idx <= 0 || idx == len(base)-1 {
+		return "", "", false
+	}
+	return base[:idx], base[idx+1:], true
+}
+
+// DefaultLanguage 默认语言
+func (r *PromptRegistry) DefaultLanguage() string {
+	return r.defaultLanguage
+}
+
+// Register 编译并注册模板（覆盖同名同语言的已有模板）
+func (r *PromptRegistry) Register(t PromptTemplate) error {
+	t.Text = strings.TrimSpace(t.Text)
+	t.Language = strings.ToLower(strings.TrimSpace(t.Language))
+	if t.Name == "" || t.Language == "" || t.Text == "" {
+		return fmt.Errorf("prompt template requires name, language and text")
+	}
+
+	tmpl, err := template.New(t.Name + "." + t.Language).Option("missingkey=error").Parse(t.Text)
+	if err != nil {
+		return fmt.Errorf("parse prompt template %s.%s: %w", t.Name, t.Language, err)
+	}
+	t.tmpl = tmpl
+	if t.Version == "" {
+		sum := sha256.Sum256([]byte(t.Text))
+		t.Version = hex.EncodeToString(sum[:])[:8]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.templates[t.Name] == nil {
+		r.templates[t.Name] = make(map[string]*PromptTemplate)
+	}
+	r.templates[t.Name][t.Language] = &t
+	return nil
+}
+
+// LoadDir 从目录加载模板文件（文件名 <name>.<language>.tmpl）
+func (r *PromptRegistry) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name, language, ok := parsePromptFileName(filepath.Base(file))
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := r.Register(PromptTemplate{Name: name, Language: language, Source: "file", Text: string(data)}); err != nil {
+			return err
+		}
+		logger.System("Prompt template loaded", "name", name, "language", language, "file", file)
+	}
+	return nil
+}
+
+// LoadFromDB 从 prompt_templates 表加载启用的模板
+func (r *PromptRegistry) LoadFromDB(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT name, language, version, content FROM prompt_templates WHERE enabled = TRUE`)
+	if err != nil {
+		return fmt.Errorf("查询Prompt模板失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t PromptTemplate
+		if err := rows.Scan(&t.Name, &t.Language, &t.Version, &t.Text); err != nil {
+			return err
+		}
+		t.Source = "db"
+		if err := r.Register(t); err != nil {
+			logger.Error("忽略无效的Prompt模板", err)
+			continue
+		}
+		logger.System("Prompt template loaded", "name", t.Name, "language", t.Language, "version", t.Version, "source", "db")
+	}
+	return rows.Err()
+}
+
+// lookup 取指定语言的模板，缺失时回退到默认语言
+func (r *PromptRegistry) lookup(name, language string) (*PromptTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	variants := r.templates[name]
+	if t, ok := variants[strings.ToLower(language)]; ok {
+		return t, nil
+	}
+	if t, ok := variants[r.defaultLanguage]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("prompt template not found: %s (%s)", name, language)
+}
+
+// Render 渲染模板，返回 Prompt 文本与实际使用的模板
+func (r *PromptRegistry) Render(name, language string, data promptData) (string, *PromptTemplate, error) {
+	t, err := r.lookup(name, language)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", nil, fmt.Errorf("render prompt %s.%s: %w", t.Name, t.Language, err)
+	}
+	return buf.String(), t, nil
+}
+
+// List 列出所有模板（按名称、语言排序）
+func (r *PromptRegistry) List() []PromptTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var list []PromptTemplate
+	for _, variants := range r.templates {
+		for _, t := range variants {
+			list = append(list, *t)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Language < list[j].Language
+	})
+	return list
+}
+
+// ========== 请求语言与版本追踪 ==========
+
+type promptLanguageKey struct{}
+type promptTraceKey struct{}
+
+// WithPromptLanguage 指定后续判定调用使用的 Prompt 语言
+func WithPromptLanguage(ctx context.Context, language string) context.Context {
+	if language == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, promptLanguageKey{}, strings.ToLower(language))
+}
+
+// PromptLanguageFrom 读取 context 中的 Prompt 语言
+func PromptLanguageFrom(ctx context.Context) string {
+	language, _ := ctx.Value(promptLanguageKey{}).(string)
+	return language
+}
+
+// PromptTrace 记录一次处理过程中使用过的模板版本（模板名 -> "语言@版本"）
+type PromptTrace struct {
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+// withPromptTrace 开始记录模板版本
+func withPromptTrace(ctx context.Context) (context.Context, *PromptTrace) {
+	trace := &PromptTrace{versions: make(map[string]string)}
+	return context.WithValue(ctx, promptTraceKey{}, trace), trace
+}
+
+func recordPromptVersion(ctx context.Context, t *PromptTemplate) {
+	if trace, ok := ctx.Value(promptTraceKey{}).(*PromptTrace); ok {
+		trace.mu.Lock()
+		trace.versions[t.Name] = t.Ref()
+		trace.mu.Unlock()
+	}
+}
+
+// Versions 返回已记录的版本（副本）
+func (p *PromptTrace) Versions() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return mergePromptVersions(p.versions)
+}
+
+// promptLanguageOf 从已记录的版本中取判定时使用的语言（如 "en@3fa9c2d1" -> "en"），
+// 用于晋升阶段沿用判定阶段的语言；启发式判定等无语言的记录返回空字符串
+func promptLanguageOf(versions map[string]string) string {
+	for _, name := range []string{PromptJudgeBatch, PromptJudgeValue, PromptSummarize} {
+		if language, _, ok := strings.Cut(versions[name], "@"); ok {
+			return language
+		}
+	}
+	return ""
+}
+
+// mergePromptVersions 合并多组模板版本，后者覆盖前者（返回新 map）
+func mergePromptVersions(groups ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, group := range groups {
+		for name, ref := range group {
+			merged[name] = ref
+		}
+	}
+	return merged
+}
@@ -4,6 +4,7 @@ import (
 	"ai-memory/pkg/types"
 	"context"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -13,13 +14,29 @@ func (m *Manager) GetStagingEntries(ctx context.Context, userID string) ([]*type
 		return m.stagingStore.GetAllByUser(ctx, userID)
 	}
 	// 获取所有待处理的（用于管理界面）
-	return m.stagingStore.GetPendingEntries(ctx, 1, 0) // 至少1次，不限时间
+	return m.tenantPendingEntries(ctx)
+}
+
+// tenantPendingEntries 当前租户的全部待处理条目（GetPendingEntries 跨租户扫描，需按条目租户过滤）
+func (m *Manager) tenantPendingEntries(ctx context.Context) ([]*types.StagingEntry, error) {
+	entries, err := m.stagingStore.GetPendingEntries(ctx, 1, 0) // 至少1次，不限时间
+	if err != nil {
+		return nil, err
+	}
+	tenantID := types.TenantFrom(ctx)
+	filtered := make([]*types.StagingEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Tenant() == tenantID {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered, nil
 }
 
 // ConfirmStagingEntry 用户确认暂存区记忆并晋升到LTM
 func (m *Manager) ConfirmStagingEntry(ctx context.Context, entryID string) error {
 	// 获取条目
-	entries, err := m.stagingStore.GetPendingEntries(ctx, 1, 0)
+	entries, err := m.tenantPendingEntries(ctx)
 	if err != nil {
 		return fmt.Errorf("获取暂存区条目失败: %w", err)
 	}
@@ -33,7 +50,7 @@ func (m *Manager) ConfirmStagingEntry(ctx context.Context, entryID string) error
 	}
 
 	if targetEntry == nil {
-		return fmt.Errorf("条目不存在: %s", entryID)
+		return fmt.Errorf("条目不存在: %s: %w", entryID, ErrRecordNotFound)
 	}
 
 	// 标记为已确认
@@ -41,17 +58,60 @@ func (m *Manager) ConfirmStagingEntry(ctx context.Context, entryID string) error
 	targetEntry.ConfirmedBy = "user"
 
 	// 晋升到LTM
-	return m.promoteSingleEntry(ctx, targetEntry, "user")
+	if err := m.promoteSingleEntry(ctx, targetEntry, "user"); err != nil {
+		return err
+	}
+
+	m.auditMemory(ctx, AuditStagingConfirm, entryID, targetEntry.UserID, stagingAuditDetail(targetEntry))
+	return nil
 }
 
 // RejectStagingEntry 用户拒绝暂存区记忆
 func (m *Manager) RejectStagingEntry(ctx context.Context, entryID string) error {
-	return m.stagingStore.Delete(ctx, entryID)
+	if !stagingEntryInTenant(ctx, entryID) {
+		return fmt.Errorf("条目不存在: %s: %w", entryID, ErrRecordNotFound)
+	}
+
+	// 删除前取出条目内容，供审计追溯
+	userID := stagingEntryUserID(entryID)
+	var detail map[string]interface{}
+	if entries, err := m.stagingStore.GetAllByUser(ctx, userID); err == nil {
+		for _, entry := range entries {
+			if entry.ID == entryID {
+				detail = stagingAuditDetail(entry)
+				break
+			}
+		}
+	}
+
+	if err := m.stagingStore.Delete(ctx, entryID); err != nil {
+		return err
+	}
+	m.auditMemory(ctx, AuditStagingReject, entryID, userID, detail)
+	return nil
+}
+
+// stagingEntryUserID 从条目 ID（[tenant:<tenant>:]staging:<userID>:<hash>）中解析终端用户
+func stagingEntryUserID(entryID string) string {
+	_, rest := types.SplitTenantKey(entryID)
+	rest = strings.TrimPrefix(rest, "staging:")
+	if idx := strings.LastIndex(rest, ":"); idx >= 0 {
+		return rest[:idx]
+	}
+	return rest
+}
+
+func stagingAuditDetail(entry *types.StagingEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"content":    entry.Content,
+		"category":   entry.Category,
+		"confidence": entry.ConfidenceScore,
+	}
 }
 
 // GetStagingStats 获取暂存区统计信息
 func (m *Manager) GetStagingStats(ctx context.Context) (map[string]interface{}, error) {
-	allEntries, err := m.stagingStore.GetPendingEntries(ctx, 1, 0)
+	allEntries, err := m.tenantPendingEntries(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 多租户：Manager 的所有对外方法按 context 中的租户（types.WithTenant）隔离数据。
+// - STM / Staging 的 Redis 键带租户前缀（默认租户不加前缀，兼容升级前的键）
+// - LTM 记录 metadata.tenant_id，检索时与 user_id 一起作为过滤条件
+// - 后台任务跨租户扫描，按条目自身的租户恢复 context
+
+// metaTenantID LTM / STM 记录 metadata 中的租户字段
+const metaTenantID = "tenant_id"
+
+// ErrRecordNotFound 记录不存在或不属于当前租户
+var ErrRecordNotFound = errors.New("record not found")
+
+// stmKey 会话 STM 列表键
+func stmKey(ctx context.Context, userID, sessionID string) string {
+	return types.TenantKey(types.TenantFrom(ctx), fmt.Sprintf("memory:stm:%s:%s", userID, sessionID))
+}
+
+// stmUserPattern 当前租户下某用户（userID 为空表示全部用户）的 STM 键模式
+func stmUserPattern(ctx context.Context, userID string) string {
+	if userID == "" {
+		userID = "*"
+	}
+	return types.TenantKey(types.TenantFrom(ctx), fmt.Sprintf("memory:stm:%s:*", userID))
+}
+
+// parseSTMKey 解析 STM 键：[tenant:<tenant>:]memory:stm:<userID>:<sessionID>
+func parseSTMKey(key string) (tenantID, userID, sessionID string, ok bool) {
+	tenantID, rest := types.SplitTenantKey(key)
+	rest, found := strings.CutPrefix(rest, "memory:stm:")
+	if !found {
+		return "", "", "", false
+	}
+	userID, sessionID, ok = strings.Cut(rest, ":")
+	return tenantID, userID, sessionID, ok && userID != "" && sessionID != ""
+}
+
+// userFilters 当前租户下某用户的 LTM 过滤条件
+func userFilters(ctx context.Context, userID string) map[string]interface{} {
+	return map[string]interface{}{
+		metaTenantID: types.TenantFrom(ctx),
+		"user_id":    userID,
+	}
+}
+
+// recordTenant LTM 记录所属租户（升级前写入的记录没有 tenant_id，归属默认租户）
+func recordTenant(rec *types.Record) string {
+	if tenantID, _ := rec.Metadata[metaTenantID].(string); tenantID != "" {
+		return tenantID
+	}
+	return types.DefaultTenant
+}
+
+// tenantOrDefault 未标记租户的数据（升级前写入的指标、后台任务之外的直接调用）归入默认租户
+func tenantOrDefault(tenantID string) string {
+	if tenantID == "" {
+		return types.DefaultTenant
+	}
+	return tenantID
+}
+
+// stagingEntryInTenant 暂存条目是否属于当前租户（条目 ID 即带租户前缀的 Redis 键）
+func stagingEntryInTenant(ctx context.Context, entryID string) bool {
+	tenantID, _ := types.SplitTenantKey(entryID)
+	return tenantID == types.TenantFrom(ctx)
+}
+
+// BackfillTenantIDs 为升级前写入、缺少 tenant_id 的 LTM 记录补写默认租户，
+// 使其能被按租户过滤的检索命中（启动时执行一次，已补写的记录会被跳过）。
+// List 返回的记录不含向量，因此只更新 metadata 字段，不能用 Update 整条写回。
+func (m *Manager) BackfillTenantIDs(ctx context.Context) error {
+	start := time.Now()
+	updated := 0
+	offset := 0
+	for {
+		records, err := m.vectorStore.List(ctx, map[string]interface{}{}, lexicalRebuildBatchSize, offset)
+		if err != nil {
+			return fmt.Errorf("扫描LTM失败: %w", err)
+		}
+		for _, rec := range records {
+			if _, ok := rec.Metadata[metaTenantID].(string); ok {
+				continue
+			}
+			fields := map[string]interface{}{metaTenantID: types.DefaultTenant}
+			if err := m.vectorStore.UpdateMetadata(ctx, rec.ID, fields); err != nil {
+				logger.Error("补写租户失败", err, "id", rec.ID)
+				continue
+			}
+			updated++
+		}
+		if len(records) < lexicalRebuildBatchSize {
+			break
+		}
+		offset += len(records)
+	}
+
+	if updated > 0 {
+		logger.System("🏷️ 历史LTM已补写默认租户", "updated", updated, "duration", time.Since(start).String())
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"ai-memory/pkg/store"
+	"ai-memory/pkg/types"
+	"context"
+	"testing"
+)
+
+// vectorlessListStore 模拟 Qdrant / pgvector：List 返回的记录不含向量，Update 会跳过没有向量的记录
+type vectorlessListStore struct {
+	*store.InMemoryVectorStore
+}
+
+func (s vectorlessListStore) List(ctx context.Context, filter map[string]interface{}, limit int, offset int) ([]types.Record, error) {
+	records, err := s.InMemoryVectorStore.List(ctx, filter, limit, offset)
+	for i := range records {
+		records[i].Embedding = nil
+	}
+	return records, err
+}
+
+func TestBackfillTenantIDsWithoutVectors(t *testing.T) {
+	ctx := context.Background()
+	vectorStore := store.NewInMemoryVectorStore("")
+	if err := vectorStore.Add(ctx, []types.Record{
+		{ID: "legacy", Content: "升级前的记忆", Embedding: []float32{1, 0}, Type: types.LongTerm,
+			Metadata: map[string]interface{}{"user_id": "u1"}},
+		{ID: "tenant-a", Content: "其他租户的记忆", Embedding: []float32{0, 1}, Type: types.LongTerm,
+			Metadata: map[string]interface{}{"user_id": "u1", metaTenantID: "tenant-a"}},
+	}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	m := &Manager{vectorStore: vectorlessListStore{vectorStore}}
+	if err := m.BackfillTenantIDs(ctx); err != nil {
+		t.Fatalf("BackfillTenantIDs: %v", err)
+	}
+
+	records, err := vectorStore.List(ctx, userFilters(ctx, "u1"), 0, 0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != "legacy" {
+		t.Fatalf("default tenant records = %+v, want [legacy]", records)
+	}
+	if len(records[0].Embedding) != 2 || records[0].Content != "升级前的记忆" {
+		t.Errorf("backfill must keep content and embedding, got %+v", records[0])
+	}
+
+	other, _ := vectorStore.Get(ctx, "tenant-a")
+	if recordTenant(other) != "tenant-a" {
+		t.Errorf("tenant-a record tenant = %q", recordTenant(other))
+	}
+
+	if err := vectorStore.UpdateMetadata(ctx, "missing", map[string]interface{}{metaTenantID: "x"}); err != store.ErrRecordNotFound {
+		t.Errorf("UpdateMetadata(missing) err = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// embeddingCacheKeyPrefix Redis 中向量缓存的 key 前缀
+const embeddingCacheKeyPrefix = "memory:embedding_cache:"
+
+// InMemoryEmbeddingCache 进程内向量缓存（LRU，按条数限制）
+// 向量由内容与模型唯一确定，不设过期时间。
+type InMemoryEmbeddingCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // 队首为最近使用
+	items      map[string]*list.Element
+}
+
+type embeddingCacheItem struct {
+	key    string
+	vector []float32
+}
+
+// NewInMemoryEmbeddingCache 创建内存向量缓存，maxEntries <= 0 时不限制条数
+func NewInMemoryEmbeddingCache(maxEntries int) *InMemoryEmbeddingCache {
+	return &InMemoryEmbeddingCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get 读取缓存（返回副本，调用方可以修改）
+func (c *InMemoryEmbeddingCache) Get(ctx context.Context, key string) ([]float32, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	c.order.MoveToFront(elem)
+	return append([]float32(nil), elem.Value.(*embeddingCacheItem).vector...), true, nil
+}
+
+// Set 写入缓存，超出容量时淘汰最久未使用的条目
+func (c *InMemoryEmbeddingCache) Set(ctx context.Context, key string, vector []float32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	vector = append([]float32(nil), vector...)
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*embeddingCacheItem).vector = vector
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&embeddingCacheItem{key: key, vector: vector})
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*embeddingCacheItem).key)
+	}
+	return nil
+}
+
+// Len 当前缓存条数
+func (c *InMemoryEmbeddingCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// RedisEmbeddingCache Redis 向量缓存（多副本共享），向量以 float32 小端序二进制存储
+type RedisEmbeddingCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisEmbeddingCache 创建 Redis 向量缓存，ttl 为 0 表示不过期
+func NewRedisEmbeddingCache(client *redis.Client, ttl time.Duration) *RedisEmbeddingCache {
+	return &RedisEmbeddingCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Get 读取缓存
+func (c *RedisEmbeddingCache) Get(ctx context.Context, key string) ([]float32, bool, error) {
+	data, err := c.client.Get(ctx, embeddingCacheKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data)%4 != 0 {
+		return nil, false, fmt.Errorf("invalid cached embedding length: %d", len(data))
+	}
+
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector, true, nil
+}
+
+// Set 写入缓存
+func (c *RedisEmbeddingCache) Set(ctx context.Context, key string, vector []float32) error {
+	data := make([]byte, len(vector)*4)
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	return c.client.Set(ctx, embeddingCacheKeyPrefix+key, data, c.ttl).Err()
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"ai-memory/pkg/types"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRecordNotFound 按 ID 更新的记录不存在（各 VectorStore 实现统一返回）
+var ErrRecordNotFound = errors.New("record not found")
+
+// 过滤条件中具有特殊语义的键（其余键均按 metadata 字段关键字精确匹配）
+const (
+	FilterType           = "type"             // 记录类型（顶层字段）
+	FilterTags           = "tags"             // []string，必须包含全部标签
+	FilterExcludedTags   = "excluded_tags"    // []string，包含任一标签即排除
+	FilterMinDecayScore  = "min_decay_score"  // float64，metadata.decay_score 下限
+	FilterTimeRangeStart = "time_range_start" // time.Time，记录时间下限（含）
+	FilterTimeRangeEnd   = "time_range_end"   // time.Time，记录时间上限（含）
+)
+
+// recordFilter 解析后的过滤条件，供各 VectorStore 实现转换为各自的查询语法
+type recordFilter struct {
+	Type          string            // 为空表示不限
+	Keywords      map[string]string // metadata 字段 -> 关键字（user_id / category 等）
+	RequiredTags  []string
+	ExcludedTags  []string
+	MinDecayScore *float64
+	TimeStart     *time.Time
+	TimeEnd       *time.Time
+}
+
+// parseRecordFilter 解析 VectorStore 的 filters 参数
+// - "user_id" / "category" 等普通键对应 metadata 中的同名字段（兼容 "metadata.xxx" 写法）
+// - "type" 对应记录类型
+// - 特殊键见 Filter* 常量；空值（空数组、nil）视为未设置
+func parseRecordFilter(filters map[string]interface{}) recordFilter {
+	f := recordFilter{Keywords: make(map[string]string)}
+
+	for k, v := range filters {
+		if v == nil {
+			continue
+		}
+		switch k {
+		case FilterType:
+			f.Type = filterValueString(v)
+		case FilterTags:
+			f.RequiredTags = toStringSlice(v)
+		case FilterExcludedTags:
+			f.ExcludedTags = toStringSlice(v)
+		case FilterMinDecayScore:
+			if score, ok := toFloat(v); ok {
+				f.MinDecayScore = &score
+			}
+		case FilterTimeRangeStart:
+			if t, ok := toTime(v); ok {
+				f.TimeStart = &t
+			}
+		case FilterTimeRangeEnd:
+			if t, ok := toTime(v); ok {
+				f.TimeEnd = &t
+			}
+		default:
+			f.Keywords[strings.TrimPrefix(k, "metadata.")] = filterValueString(v)
+		}
+	}
+	return f
+}
+
+// sortedKeywordFields 固定顺序的关键字字段，保证生成的查询稳定
+func (f recordFilter) sortedKeywordFields() []string {
+	fields := make([]string, 0, len(f.Keywords))
+	for field := range f.Keywords {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// matchRecordFilters 判断记录是否满足过滤条件（与 QdrantStore 的过滤语义保持一致）
+// 所有关键字按字符串精确匹配；若 metadata 字段为数组，则任一元素匹配即可。
+func matchRecordFilters(rec *types.Record, filters map[string]interface{}) bool {
+	f := parseRecordFilter(filters)
+
+	if f.Type != "" && string(rec.Type) != f.Type {
+		return false
+	}
+	for field, want := range f.Keywords {
+		if !matchPayloadValue(rec.Metadata[field], want) {
+			return false
+		}
+	}
+	for _, tag := range f.RequiredTags {
+		if !matchPayloadValue(rec.Metadata["tags"], tag) {
+			return false
+		}
+	}
+	for _, tag := range f.ExcludedTags {
+		if matchPayloadValue(rec.Metadata["tags"], tag) {
+			return false
+		}
+	}
+	if f.MinDecayScore != nil {
+		score, ok := toFloat(rec.Metadata["decay_score"])
+		if !ok || score < *f.MinDecayScore {
+			return false
+		}
+	}
+	if f.TimeStart != nil && rec.Timestamp.Before(*f.TimeStart) {
+		return false
+	}
+	if f.TimeEnd != nil && rec.Timestamp.After(*f.TimeEnd) {
+		return false
+	}
+	return true
+}
+
+// matchPayloadValue 关键字匹配单个 payload 值
+func matchPayloadValue(val interface{}, want string) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if fmt.Sprintf("%v", item) == want {
+				return true
+			}
+		}
+		return false
+	case []string:
+		for _, item := range v {
+			if item == want {
+				return true
+			}
+		}
+		return false
+	default:
+		return fmt.Sprintf("%v", v) == want
+	}
+}
+
+// filterValueString 过滤值统一按关键字（字符串）比较
+func filterValueString(v interface{}) string {
+	return fmt.Sprintf("%v", v)
+}
+
+// toStringSlice 兼容 []string 与 JSON 解码得到的 []interface{}，单个值视为一个元素
+func toStringSlice(v interface{}) []string {
+	switch val := v.(type) {
+	case []string:
+		return val
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			out = append(out, fmt.Sprintf("%v", item))
+		}
+		return out
+	case string:
+		if val == "" {
+			return nil
+		}
+		return []string{val}
+	default:
+		return []string{fmt.Sprintf("%v", val)}
+	}
+}
+
+// toFloat 兼容各种数值类型及数字字符串
+func toFloat(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toTime 兼容 time.Time / *time.Time / RFC3339 字符串
+func toTime(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, !val.IsZero()
+	case *time.Time:
+		if val == nil || val.IsZero() {
+			return time.Time{}, false
+		}
+		return *val, true
+	case string:
+		t, err := time.Parse(time.RFC3339, val)
+		return t, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"ai-memory/pkg/types"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	filterStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	filterEnd   = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// pgKeyword buildPgFilter 为单个关键字字段生成的条件
+func pgKeyword(k, v int) string {
+	return fmt.Sprintf(
+		"(metadata @> jsonb_build_object($%d::text, $%d::text) OR metadata @> jsonb_build_object($%d::text, jsonb_build_array($%d::text)))",
+		k, v, k, v,
+	)
+}
+
+func TestBuildPgFilter(t *testing.T) {
+	score := 0.5
+	tests := []struct {
+		name      string
+		filters   map[string]interface{}
+		prefix    []interface{}
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{name: "empty", filters: nil, wantWhere: "", wantArgs: nil},
+		{
+			name:      "keywords sorted after existing args",
+			filters:   map[string]interface{}{"user_id": "u1", "tenant_id": "t1"},
+			prefix:    []interface{}{0},
+			wantWhere: pgKeyword(2, 3) + " AND " + pgKeyword(4, 5),
+			wantArgs:  []interface{}{0, "tenant_id", "t1", "user_id", "u1"},
+		},
+		{
+			name:      "metadata prefix and empty values",
+			filters:   map[string]interface{}{"metadata.category": "preference", FilterTags: []string{}, FilterExcludedTags: nil},
+			wantWhere: pgKeyword(1, 2),
+			wantArgs:  []interface{}{"category", "preference"},
+		},
+		{
+			name: "all special keys",
+			filters: map[string]interface{}{
+				FilterType:           "long_term",
+				FilterTags:           []interface{}{"咖啡"},
+				FilterExcludedTags:   []string{"x", "y"},
+				FilterMinDecayScore:  score,
+				FilterTimeRangeStart: filterStart,
+				FilterTimeRangeEnd:   filterEnd.Format(time.RFC3339),
+			},
+			wantWhere: "type = $1 AND " + pgKeyword(2, 3) +
+				" AND NOT (COALESCE(metadata->'tags', '[]'::jsonb) ?| $4::text[])" +
+				" AND jsonb_typeof(metadata->'decay_score') = 'number' AND (metadata->>'decay_score')::float8 >= $5" +
+				" AND timestamp >= $6 AND timestamp <= $7",
+			wantArgs: []interface{}{"long_term", "tags", "咖啡", pq.Array([]string{"x", "y"}), score, filterStart, filterEnd},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := buildPgFilter(tt.filters, tt.prefix)
+			if where != tt.wantWhere {
+				t.Errorf("where =\n%s\nwant\n%s", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBuildQdrantFilter(t *testing.T) {
+	score := 0.5
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		want    *qdrant.Filter
+	}{
+		{name: "empty", filters: map[string]interface{}{}, want: nil},
+		{name: "only empty values", filters: map[string]interface{}{FilterTags: []string{}, FilterExcludedTags: nil}, want: nil},
+		{
+			name:    "keywords",
+			filters: map[string]interface{}{"user_id": "u1", "metadata.tenant_id": "t1"},
+			want: &qdrant.Filter{Must: []*qdrant.Condition{
+				qdrant.NewMatchKeyword("metadata.tenant_id", "t1"),
+				qdrant.NewMatchKeyword("metadata.user_id", "u1"),
+			}},
+		},
+		{
+			name: "all special keys",
+			filters: map[string]interface{}{
+				FilterType:           "long_term",
+				FilterTags:           []string{"咖啡"},
+				FilterExcludedTags:   []string{"x", "y"},
+				FilterMinDecayScore:  score,
+				FilterTimeRangeStart: filterStart,
+				FilterTimeRangeEnd:   &filterEnd,
+			},
+			want: &qdrant.Filter{
+				Must: []*qdrant.Condition{
+					qdrant.NewMatchKeyword("type", "long_term"),
+					qdrant.NewMatchKeyword("metadata.tags", "咖啡"),
+					qdrant.NewRange("metadata.decay_score", &qdrant.Range{Gte: &score}),
+					qdrant.NewDatetimeRange("timestamp", &qdrant.DatetimeRange{
+						Gte: timestamppb.New(filterStart),
+						Lte: timestamppb.New(filterEnd),
+					}),
+				},
+				MustNot: []*qdrant.Condition{qdrant.NewMatchKeywords("metadata.tags", "x", "y")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildQdrantFilter(tt.filters); !proto.Equal(got, tt.want) {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRecordFilters(t *testing.T) {
+	rec := &types.Record{
+		Type:      types.LongTerm,
+		Timestamp: filterStart.Add(24 * time.Hour),
+		Metadata: map[string]interface{}{
+			"user_id":     "u1",
+			"tags":        []interface{}{"饮食", "咖啡"},
+			"decay_score": 0.7,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		want    bool
+	}{
+		{name: "no filters", filters: nil, want: true},
+		{name: "keyword match", filters: map[string]interface{}{"user_id": "u1", FilterType: string(types.LongTerm)}, want: true},
+		{name: "keyword mismatch", filters: map[string]interface{}{"user_id": "u2"}, want: false},
+		{name: "missing field", filters: map[string]interface{}{"tenant_id": "t1"}, want: false},
+		{name: "type mismatch", filters: map[string]interface{}{FilterType: "short_term"}, want: false},
+		{name: "array keyword", filters: map[string]interface{}{"metadata.tags": "咖啡"}, want: true},
+		{name: "required tags", filters: map[string]interface{}{FilterTags: []string{"饮食", "咖啡"}}, want: true},
+		{name: "required tag missing", filters: map[string]interface{}{FilterTags: []string{"咖啡", "茶"}}, want: false},
+		{name: "excluded tag", filters: map[string]interface{}{FilterExcludedTags: []string{"茶", "咖啡"}}, want: false},
+		{name: "decay score passes", filters: map[string]interface{}{FilterMinDecayScore: "0.5"}, want: true},
+		{name: "decay score below", filters: map[string]interface{}{FilterMinDecayScore: 0.8}, want: false},
+		{name: "inside time range", filters: map[string]interface{}{FilterTimeRangeStart: filterStart, FilterTimeRangeEnd: filterEnd}, want: true},
+		{name: "before time range", filters: map[string]interface{}{FilterTimeRangeStart: filterEnd}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchRecordFilters(rec, tt.filters); got != tt.want {
+				t.Errorf("matchRecordFilters = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
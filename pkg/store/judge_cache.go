@@ -0,0 +1,134 @@
+package store
+
+import (
+	"ai-memory/pkg/types"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// judgeCacheKeyPrefix Redis 中判定缓存的 key 前缀
+const judgeCacheKeyPrefix = "memory:judge_cache:"
+
+// InMemoryJudgeCache 进程内判定结果缓存：LRU 淘汰 + TTL 过期
+type InMemoryJudgeCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List // 队首为最近使用
+	items      map[string]*list.Element
+}
+
+type judgeCacheItem struct {
+	key       string
+	result    types.JudgeResult
+	expiresAt time.Time
+}
+
+// NewInMemoryJudgeCache 创建内存判定缓存，maxEntries <= 0 时不限制条数
+func NewInMemoryJudgeCache(maxEntries int, ttl time.Duration) *InMemoryJudgeCache {
+	return &InMemoryJudgeCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get 读取缓存，过期条目在读取时删除
+func (c *InMemoryJudgeCache) Get(ctx context.Context, key string) (*types.JudgeResult, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := elem.Value.(*judgeCacheItem)
+	if c.ttl > 0 && time.Now().After(item.expiresAt) {
+		c.removeLocked(elem)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(elem)
+
+	result := item.result
+	return &result, true, nil
+}
+
+// Set 写入缓存，超出容量时淘汰最久未使用的条目
+func (c *InMemoryJudgeCache) Set(ctx context.Context, key string, result *types.JudgeResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.items[key]; ok {
+		item := elem.Value.(*judgeCacheItem)
+		item.result = *result
+		item.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&judgeCacheItem{key: key, result: *result, expiresAt: expiresAt})
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.removeLocked(c.order.Back())
+	}
+	return nil
+}
+
+// Len 当前缓存条数（含尚未被读取清理的过期条目）
+func (c *InMemoryJudgeCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *InMemoryJudgeCache) removeLocked(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*judgeCacheItem).key)
+}
+
+// RedisJudgeCache Redis 判定结果缓存：多副本共享，重启不丢失，过期由 Redis TTL 负责
+type RedisJudgeCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisJudgeCache 创建 Redis 判定缓存
+func NewRedisJudgeCache(client *redis.Client, ttl time.Duration) *RedisJudgeCache {
+	return &RedisJudgeCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Get 读取缓存
+func (c *RedisJudgeCache) Get(ctx context.Context, key string) (*types.JudgeResult, bool, error) {
+	data, err := c.client.Get(ctx, judgeCacheKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var result types.JudgeResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false, err
+	}
+	return &result, true, nil
+}
+
+// Set 写入缓存
+func (c *RedisJudgeCache) Set(ctx context.Context, key string, result *types.JudgeResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, judgeCacheKeyPrefix+key, data, c.ttl).Err()
+}
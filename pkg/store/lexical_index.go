@@ -0,0 +1,265 @@
+package store
+
+import (
+	"ai-memory/pkg/types"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25 参数（常用默认值）
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// LexicalIndex 基于 BM25 的内存倒排索引（LTM 关键词检索）
+// 索引内容为 Record.Content 与 metadata.entities 的值；过滤语义与 VectorStore 一致。
+// 中日韩文本按单字与二元组（unigram + bigram）切分，其余按字母数字连续串切分并转小写。
+type LexicalIndex struct {
+	mu       sync.RWMutex
+	docs     map[string]*lexicalDoc
+	postings map[string]map[string]int // term -> docID -> term frequency
+	totalLen int
+}
+
+// lexicalDoc 单个文档（record 不含 Embedding）
+type lexicalDoc struct {
+	record types.Record
+	length int
+	terms  map[string]int
+}
+
+// LexicalHit 关键词检索结果
+type LexicalHit struct {
+	Record types.Record
+	Score  float64
+}
+
+// NewLexicalIndex 创建空索引
+func NewLexicalIndex() *LexicalIndex {
+	return &LexicalIndex{
+		docs:     make(map[string]*lexicalDoc),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// Upsert 写入或覆盖记录
+func (idx *LexicalIndex) Upsert(records ...types.Record) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, r := range records {
+		idx.removeLocked(r.ID)
+		idx.addLocked(r)
+	}
+}
+
+// Remove 删除记录
+func (idx *LexicalIndex) Remove(ids ...string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, id := range ids {
+		idx.removeLocked(id)
+	}
+}
+
+// Reset 用给定记录整体重建索引（先在锁外构建，再原子替换）
+func (idx *LexicalIndex) Reset(records []types.Record) {
+	fresh := NewLexicalIndex()
+	for _, r := range records {
+		fresh.addLocked(r)
+	}
+
+	idx.mu.Lock()
+	idx.docs = fresh.docs
+	idx.postings = fresh.postings
+	idx.totalLen = fresh.totalLen
+	idx.mu.Unlock()
+}
+
+// Len 返回索引中的文档数
+func (idx *LexicalIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search 按 BM25 打分返回满足过滤条件的前 limit 条记录（分数降序）
+func (idx *LexicalIndex) Search(query string, limit int, filters map[string]interface{}) []LexicalHit {
+	queryTerms := uniqueTerms(TokenizeLexical(query))
+	if len(queryTerms) == 0 || limit <= 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := len(idx.docs)
+	if n == 0 {
+		return nil
+	}
+	avgLen := float64(idx.totalLen) / float64(n)
+
+	scores := make(map[string]float64)
+	for _, term := range queryTerms {
+		posting := idx.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		df := float64(len(posting))
+		idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+
+		for docID, tf := range posting {
+			doc := idx.docs[docID]
+			norm := 1 - bm25B + bm25B*float64(doc.length)/avgLen
+			scores[docID] += idf * (float64(tf) * (bm25K1 + 1)) / (float64(tf) + bm25K1*norm)
+		}
+	}
+
+	hits := make([]LexicalHit, 0, len(scores))
+	for docID, score := range scores {
+		doc := idx.docs[docID]
+		if !matchRecordFilters(&doc.record, filters) {
+			continue
+		}
+		hits = append(hits, LexicalHit{Record: *cloneRecord(doc.record), Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Record.ID < hits[j].Record.ID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// addLocked 建立文档倒排（调用方需持有锁或独占索引）
+func (idx *LexicalIndex) addLocked(r types.Record) {
+	terms := TokenizeLexical(lexicalText(r))
+	if len(terms) == 0 {
+		return
+	}
+
+	doc := &lexicalDoc{
+		record: *cloneRecord(r),
+		length: len(terms),
+		terms:  make(map[string]int),
+	}
+	doc.record.Embedding = nil
+	for _, t := range terms {
+		doc.terms[t]++
+	}
+
+	for t, tf := range doc.terms {
+		posting, ok := idx.postings[t]
+		if !ok {
+			posting = make(map[string]int)
+			idx.postings[t] = posting
+		}
+		posting[r.ID] = tf
+	}
+	idx.docs[r.ID] = doc
+	idx.totalLen += doc.length
+}
+
+// removeLocked 删除文档倒排（调用方需持有锁）
+func (idx *LexicalIndex) removeLocked(id string) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for t := range doc.terms {
+		posting := idx.postings[t]
+		delete(posting, id)
+		if len(posting) == 0 {
+			delete(idx.postings, t)
+		}
+	}
+	idx.totalLen -= doc.length
+	delete(idx.docs, id)
+}
+
+// lexicalText 参与索引的文本：内容 + 实体值
+func lexicalText(r types.Record) string {
+	parts := []string{r.Content}
+
+	switch entities := r.Metadata["entities"].(type) {
+	case map[string]string:
+		for _, v := range entities {
+			parts = append(parts, v)
+		}
+	case map[string]interface{}:
+		for _, v := range entities {
+			parts = append(parts, fmt.Sprintf("%v", v))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// TokenizeLexical 切分文本：中日韩字符同时产出单字与 bigram（单字查询也能命中），其他按字母数字连续串，统一小写
+func TokenizeLexical(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i := range cjk {
+			tokens = append(tokens, string(cjk[i]))
+			if i+1 < len(cjk) {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	out := terms[:0:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"ai-memory/pkg/types"
+	"fmt"
+	"testing"
+)
+
+func TestTokenizeLexical(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "", want: nil},
+		{text: "Hello, World 42", want: []string{"hello", "world", "42"}},
+		{text: "咖", want: []string{"咖"}},
+		{text: "喝咖啡", want: []string{"喝", "喝咖", "咖", "咖啡", "啡"}},
+		{text: "我爱Go语言!", want: []string{"我", "我爱", "爱", "go", "语", "语言", "言"}},
+		{text: "カフェ latte", want: []string{"カ", "カフ", "フ", "フェ", "ェ", "latte"}},
+	}
+
+	for _, tt := range tests {
+		if got := TokenizeLexical(tt.text); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("TokenizeLexical(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLexicalIndexSearch(t *testing.T) {
+	idx := NewLexicalIndex()
+	idx.Upsert(
+		types.Record{ID: "coffee", Content: "用户每天早上喝黑咖啡", Metadata: map[string]interface{}{"user_id": "u1"}},
+		types.Record{ID: "tea", Content: "用户下午喜欢喝茶", Metadata: map[string]interface{}{"user_id": "u1"}},
+		types.Record{ID: "other", Content: "用户每天喝咖啡", Metadata: map[string]interface{}{"user_id": "u2"}},
+		types.Record{ID: "entity", Content: "常去的店", Metadata: map[string]interface{}{
+			"user_id":  "u1",
+			"entities": map[string]interface{}{"店名": "Blue Bottle"},
+		}},
+	)
+
+	ids := func(hits []LexicalHit) []string {
+		out := make([]string, 0, len(hits))
+		for _, h := range hits {
+			out = append(out, h.Record.ID)
+		}
+		return out
+	}
+	u1 := map[string]interface{}{"user_id": "u1"}
+
+	tests := []struct {
+		name    string
+		query   string
+		limit   int
+		filters map[string]interface{}
+		want    []string
+	}{
+		{name: "bigram", query: "咖啡", limit: 10, filters: u1, want: []string{"coffee"}},
+		{name: "single cjk char", query: "茶", limit: 10, filters: u1, want: []string{"tea"}},
+		{name: "entity value", query: "blue bottle", limit: 10, filters: u1, want: []string{"entity"}},
+		{name: "filter by user", query: "咖啡", limit: 10, filters: map[string]interface{}{"user_id": "u2"}, want: []string{"other"}},
+		{name: "no filters", query: "咖啡", limit: 10, want: []string{"other", "coffee"}},
+		{name: "limit", query: "咖啡", limit: 1, want: []string{"other"}},
+		{name: "zero limit", query: "咖啡", limit: 0, want: []string{}},
+		{name: "no match", query: "牛奶", limit: 10, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(idx.Search(tt.query, tt.limit, tt.filters)); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	// 覆盖与删除后倒排同步更新
+	idx.Upsert(types.Record{ID: "tea", Content: "用户下午喜欢喝咖啡", Metadata: map[string]interface{}{"user_id": "u1"}})
+	idx.Remove("coffee")
+	if got := ids(idx.Search("茶", 10, nil)); len(got) != 0 {
+		t.Errorf("Search(茶) after upsert = %v, want none", got)
+	}
+	if got := ids(idx.Search("咖啡", 10, u1)); fmt.Sprint(got) != "[tea]" {
+		t.Errorf("Search(咖啡) after remove = %v, want [tea]", got)
+	}
+	if idx.Len() != 3 {
+		t.Errorf("Len = %d, want 3", idx.Len())
+	}
+}
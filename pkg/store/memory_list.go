@@ -0,0 +1,311 @@
+package store
+
+import (
+	"ai-memory/pkg/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryListStore 纯Go实现的 STM 存储（用于嵌入式部署与单元测试）
+// 语义与 RedisStore 保持一致：列表/集合按 key 存储，支持过期时间（惰性清理）。
+type InMemoryListStore struct {
+	mu      sync.Mutex
+	lists   map[string][]string
+	sets    map[string]map[string]struct{}
+	expires map[string]time.Time
+}
+
+// NewInMemoryListStore 创建内存 STM 存储
+func NewInMemoryListStore() *InMemoryListStore {
+	return &InMemoryListStore{
+		lists:   make(map[string][]string),
+		sets:    make(map[string]map[string]struct{}),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// RPush appends values to a list.
+func (s *InMemoryListStore) RPush(ctx context.Context, key string, values ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+	if _, ok := s.sets[key]; ok {
+		return fmt.Errorf("WRONGTYPE key %s holds a set", key)
+	}
+	for _, v := range values {
+		s.lists[key] = append(s.lists[key], toRedisString(v))
+	}
+	return nil
+}
+
+// RPushWithExpire appends values to a list and sets expiration.
+func (s *InMemoryListStore) RPushWithExpire(ctx context.Context, key string, expirationDays int, values ...interface{}) error {
+	if err := s.RPush(ctx, key, values...); err != nil {
+		return err
+	}
+	// 如果expirationDays为0，则不设置过期时间
+	if expirationDays <= 0 {
+		return nil
+	}
+	return s.Expire(ctx, key, time.Duration(expirationDays)*24*time.Hour)
+}
+
+// LRange retrieves a range of elements from a list (Redis 下标语义，支持负数).
+func (s *InMemoryListStore) LRange(ctx context.Context, key string, start, stop int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+	list := s.lists[key]
+	n := len(list)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return []string{}, nil
+	}
+
+	result := make([]string, stop-start+1)
+	copy(result, list[start:stop+1])
+	return result, nil
+}
+
+// LRem removes elements from a list.
+// count > 0 从头部开始删除 count 个；count < 0 从尾部开始；count = 0 删除全部。
+func (s *InMemoryListStore) LRem(ctx context.Context, key string, count int64, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+	list, ok := s.lists[key]
+	if !ok {
+		return nil
+	}
+	target := toRedisString(value)
+
+	remove := make([]bool, len(list))
+	removed := int64(0)
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+	if count >= 0 {
+		for i := 0; i < len(list); i++ {
+			if list[i] == target && (limit == 0 || removed < limit) {
+				remove[i] = true
+				removed++
+			}
+		}
+	} else {
+		for i := len(list) - 1; i >= 0; i-- {
+			if list[i] == target && removed < limit {
+				remove[i] = true
+				removed++
+			}
+		}
+	}
+
+	kept := list[:0]
+	for i, item := range list {
+		if !remove[i] {
+			kept = append(kept, item)
+		}
+	}
+	if len(kept) == 0 {
+		s.deleteKey(key)
+	} else {
+		s.lists[key] = kept
+	}
+	return nil
+}
+
+// Del removes keys.
+func (s *InMemoryListStore) Del(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		s.deleteKey(key)
+	}
+	return nil
+}
+
+// ScanKeys finds keys matching a pattern (支持 Redis glob 的 * 与 ?).
+func (s *InMemoryListStore) ScanKeys(ctx context.Context, pattern string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.lists {
+		if s.expireIfNeeded(key) {
+			continue
+		}
+		if matchGlob(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range s.sets {
+		if s.expireIfNeeded(key) {
+			continue
+		}
+		if matchGlob(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Update searches the current tenant's STM lists for the record and updates it.
+func (s *InMemoryListStore) Update(ctx context.Context, record types.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, items := range s.lists {
+		if s.expireIfNeeded(key) || !matchGlob(types.TenantKey(types.TenantFrom(ctx), "memory:stm:*:*"), key) {
+			continue
+		}
+		for idx, itemStr := range items {
+			var current types.Record
+			if err := json.Unmarshal([]byte(itemStr), &current); err == nil && current.ID == record.ID {
+				enc, _ := json.Marshal(record)
+				items[idx] = string(enc)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("record not found in stm")
+}
+
+// Get finds a record by ID in the current tenant's STM.
+func (s *InMemoryListStore) Get(ctx context.Context, id string) (*types.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, items := range s.lists {
+		if s.expireIfNeeded(key) || !matchGlob(types.TenantKey(types.TenantFrom(ctx), "memory:stm:*:*"), key) {
+			continue
+		}
+		for _, itemStr := range items {
+			var current types.Record
+			if err := json.Unmarshal([]byte(itemStr), &current); err == nil && current.ID == id {
+				return &current, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("record not found")
+}
+
+// SIsMember checks if a member exists in a set.
+func (s *InMemoryListStore) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+	_, ok := s.sets[key][toRedisString(member)]
+	return ok, nil
+}
+
+// SAdd adds members to a set.
+func (s *InMemoryListStore) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+	if _, ok := s.lists[key]; ok {
+		return fmt.Errorf("WRONGTYPE key %s holds a list", key)
+	}
+	set, ok := s.sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		s.sets[key] = set
+	}
+	for _, m := range members {
+		set[toRedisString(m)] = struct{}{}
+	}
+	return nil
+}
+
+// Expire sets a timeout on a key (key 不存在时忽略，与 Redis 一致).
+func (s *InMemoryListStore) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expireIfNeeded(key) {
+		return nil
+	}
+	_, isList := s.lists[key]
+	_, isSet := s.sets[key]
+	if !isList && !isSet {
+		return nil
+	}
+	if expiration <= 0 {
+		s.deleteKey(key)
+		return nil
+	}
+	s.expires[key] = time.Now().Add(expiration)
+	return nil
+}
+
+// expireIfNeeded 惰性过期：key 已过期则删除并返回 true（调用方需持有锁）
+func (s *InMemoryListStore) expireIfNeeded(key string) bool {
+	deadline, ok := s.expires[key]
+	if !ok || time.Now().Before(deadline) {
+		return false
+	}
+	s.deleteKey(key)
+	return true
+}
+
+// deleteKey 删除 key 及其过期时间（调用方需持有锁）
+func (s *InMemoryListStore) deleteKey(key string) {
+	delete(s.lists, key)
+	delete(s.sets, key)
+	delete(s.expires, key)
+}
+
+// toRedisString 按 go-redis 的参数编码规则把值转换为字符串
+func toRedisString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// matchGlob Redis 风格的 key 匹配（支持 * 与 ?）
+func matchGlob(pattern, key string) bool {
+	if pattern == "" {
+		return key == ""
+	}
+	switch pattern[0] {
+	case '*':
+		for i := 0; i <= len(key); i++ {
+			if matchGlob(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case '?':
+		return key != "" && matchGlob(pattern[1:], key[1:])
+	default:
+		return key != "" && pattern[0] == key[0] && matchGlob(pattern[1:], key[1:])
+	}
+}
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"ai-memory/pkg/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InMemoryStagingStore 纯Go实现的暂存区（与 StagingStore 行为一致）
+// 条目以 JSON 形式保存，与 Redis 版本一样每次写入都会重置 TTL。
+type InMemoryStagingStore struct {
+	mu      sync.Mutex
+	entries map[string]stagingItem
+	ttl     time.Duration
+}
+
+// stagingItem 单个暂存条目及其过期时间
+type stagingItem struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// NewInMemoryStagingStore 创建内存暂存区
+func NewInMemoryStagingStore(ttlDays int) *InMemoryStagingStore {
+	return &InMemoryStagingStore{
+		entries: make(map[string]stagingItem),
+		ttl:     time.Hour * 24 * time.Duration(ttlDays),
+	}
+}
+
+// AddOrIncrement 添加或更新暂存区条目（频次+1），去重逻辑与 StagingStore 相同
+func (s *InMemoryStagingStore) AddOrIncrement(ctx context.Context, userID, sessionID, content string, judgeResult *types.JudgeResult, embedder Embedder) error {
+	// 1. 生成embedding（用于语义去重），失败时降级为hash去重
+	var embedding []float32
+	if embedder != nil {
+		if vec, err := embedder.EmbedQuery(ctx, content); err == nil {
+			embedding = vec
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	tenantID := types.TenantFrom(ctx)
+
+	// 2. 语义去重：搜索相似的已有条目
+	if embedding != nil {
+		if similarEntry := s.searchSimilarLocked(tenantID, userID, embedding, 0.95); similarEntry != nil {
+			applyJudgeResult(similarEntry, judgeResult, sessionID, now)
+			return s.putLocked(similarEntry)
+		}
+	}
+
+	// 3. hash去重
+	entryID := types.TenantKey(tenantID, fmt.Sprintf("staging:%s:%s", userID, hash(content)))
+
+	var entry types.StagingEntry
+	if existing, ok := s.getLocked(entryID); ok {
+		entry = *existing
+		applyJudgeResult(&entry, judgeResult, sessionID, now)
+	} else {
+		entry = newStagingEntry(entryID, tenantID, userID, sessionID, content, embedding, judgeResult, now)
+	}
+
+	return s.putLocked(&entry)
+}
+
+// SearchSimilar 在该用户的暂存区中搜索语义相似的条目（如无则返回nil）
+func (s *InMemoryStagingStore) SearchSimilar(ctx context.Context, userID string, queryVector []float32, threshold float64) (*types.StagingEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.searchSimilarLocked(types.TenantFrom(ctx), userID, queryVector, threshold), nil
+}
+
+// GetPendingEntries 获取待晋升的暂存区条目（跨全部租户）
+func (s *InMemoryStagingStore) GetPendingEntries(ctx context.Context, minOccurrences int, minWaitHours int) ([]*types.StagingEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []*types.StagingEntry
+	for _, entry := range s.scanLocked("") {
+		if entry.Status != types.StagingPending {
+			continue
+		}
+		if entry.OccurrenceCount < minOccurrences {
+			continue
+		}
+		if time.Since(entry.FirstSeenAt).Hours() < float64(minWaitHours) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GetAllByUser 获取用户的所有暂存区条目（用于Admin界面）
+func (s *InMemoryStagingStore) GetAllByUser(ctx context.Context, userID string) ([]*types.StagingEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.scanLocked(types.TenantKey(types.TenantFrom(ctx), fmt.Sprintf("staging:%s:", userID))), nil
+}
+
+// GetBySession 获取该会话触达过的暂存区条目 (Session 隔离)
+func (s *InMemoryStagingStore) GetBySession(ctx context.Context, userID, sessionID string) ([]*types.StagingEntry, error) {
+	allEntries, err := s.GetAllByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return filterBySession(allEntries, sessionID), nil
+}
+
+// Update 更新暂存区条目状态
+func (s *InMemoryStagingStore) Update(ctx context.Context, entry *types.StagingEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.putLocked(entry)
+}
+
+// Delete 删除暂存区条目
+func (s *InMemoryStagingStore) Delete(ctx context.Context, entryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, entryID)
+	return nil
+}
+
+// DeleteBatch 批量删除
+func (s *InMemoryStagingStore) DeleteBatch(ctx context.Context, entryIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range entryIDs {
+		delete(s.entries, id)
+	}
+	return nil
+}
+
+// putLocked 序列化并写入条目，重置 TTL（调用方需持有锁）
+func (s *InMemoryStagingStore) putLocked(entry *types.StagingEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化暂存区条目失败: %w", err)
+	}
+	s.entries[entry.ID] = stagingItem{data: data, expiresAt: time.Now().Add(s.ttl)}
+	return nil
+}
+
+// getLocked 读取未过期的条目（调用方需持有锁）
+func (s *InMemoryStagingStore) getLocked(id string) (*types.StagingEntry, bool) {
+	item, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(item.expiresAt) {
+		delete(s.entries, id)
+		return nil, false
+	}
+
+	var entry types.StagingEntry
+	if err := json.Unmarshal(item.data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// scanLocked 按 key 前缀扫描未过期条目，按 ID 排序保证结果稳定（调用方需持有锁）
+func (s *InMemoryStagingStore) scanLocked(prefix string) []*types.StagingEntry {
+	ids := make([]string, 0, len(s.entries))
+	for id := range s.entries {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var entries []*types.StagingEntry
+	for _, id := range ids {
+		if entry, ok := s.getLocked(id); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// searchSimilarLocked 余弦相似度搜索（调用方需持有锁）
+func (s *InMemoryStagingStore) searchSimilarLocked(tenantID, userID string, queryVector []float32, threshold float64) *types.StagingEntry {
+	var bestEntry *types.StagingEntry
+	var bestSimilarity float64
+
+	for _, entry := range s.scanLocked(types.TenantKey(tenantID, fmt.Sprintf("staging:%s:", userID))) {
+		// 跳过没有embedding的条目
+		if len(entry.Embedding) == 0 {
+			continue
+		}
+		similarity := cosineSimilarity(queryVector, entry.Embedding)
+		if similarity > threshold && similarity > bestSimilarity {
+			bestSimilarity = similarity
+			bestEntry = entry
+		}
+	}
+	return bestEntry
+}
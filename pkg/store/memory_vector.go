@@ -0,0 +1,442 @@
+package store
+
+import (
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryVectorStore 纯Go实现的向量存储（用于测试与单节点部署）
+// 过滤语义与 QdrantStore 一致，可选定期快照到磁盘。
+type InMemoryVectorStore struct {
+	mu         sync.RWMutex
+	records    map[string]*types.Record
+	vectorSize int
+
+	// 快照持久化
+	snapshotPath string
+	dirty        bool
+	stopChan     chan struct{}
+	wg           sync.WaitGroup
+}
+
+// snapshotFile 快照文件格式
+type snapshotFile struct {
+	Version    int              `json:"version"`
+	VectorSize int              `json:"vector_size"`
+	SavedAt    time.Time        `json:"saved_at"`
+	Records    []snapshotRecord `json:"records"`
+}
+
+// snapshotRecord 快照中的单条记录（types.Record 的 Embedding 不参与JSON序列化，因此单独定义）
+type snapshotRecord struct {
+	ID        string                 `json:"id"`
+	Content   string                 `json:"content"`
+	Embedding []float32              `json:"embedding"`
+	Timestamp time.Time              `json:"timestamp"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	Type      types.MemoryType       `json:"type"`
+}
+
+// NewInMemoryVectorStore 创建内存向量存储，snapshotPath 为空表示不持久化
+func NewInMemoryVectorStore(snapshotPath string) *InMemoryVectorStore {
+	return &InMemoryVectorStore{
+		records:      make(map[string]*types.Record),
+		snapshotPath: snapshotPath,
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// Init 设置向量维度并加载已有快照（对应 QdrantStore.Init）
+func (s *InMemoryVectorStore) Init(ctx context.Context, vectorSize int) error {
+	s.mu.Lock()
+	s.vectorSize = vectorSize
+	s.mu.Unlock()
+
+	if s.snapshotPath == "" {
+		return nil
+	}
+	return s.Load()
+}
+
+// Add 写入记录（Upsert 语义），没有 Embedding 的记录会被忽略
+func (s *InMemoryVectorStore) Add(ctx context.Context, records []types.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range records {
+		if r.Embedding == nil {
+			continue
+		}
+		if s.vectorSize > 0 && len(r.Embedding) != s.vectorSize {
+			return fmt.Errorf("vector dimension mismatch: expected %d, got %d", s.vectorSize, len(r.Embedding))
+		}
+		s.records[r.ID] = cloneRecord(r)
+	}
+	s.dirty = true
+	return nil
+}
+
+// Search 余弦相似度检索，返回分数不低于阈值的最近邻
+func (s *InMemoryVectorStore) Search(ctx context.Context, vector []float32, limit int, scoreThreshold float32, filters map[string]interface{}) ([]types.Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type scored struct {
+		rec   *types.Record
+		score float64
+	}
+
+	var hits []scored
+	for _, rec := range s.records {
+		if !matchRecordFilters(rec, filters) {
+			continue
+		}
+		score := cosineSimilarity(vector, rec.Embedding)
+		if score < float64(scoreThreshold) {
+			continue
+		}
+		hits = append(hits, scored{rec: rec, score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score == hits[j].score {
+			return hits[i].rec.ID < hits[j].rec.ID
+		}
+		return hits[i].score > hits[j].score
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	records := make([]types.Record, 0, len(hits))
+	for _, h := range hits {
+		records = append(records, *cloneRecord(*h.rec))
+	}
+	return records, nil
+}
+
+// Delete 按ID删除记录
+func (s *InMemoryVectorStore) Delete(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.records, id)
+	}
+	s.dirty = true
+	return nil
+}
+
+// List 按ID顺序分页列出记录（与 Qdrant Scroll 的顺序保持一致），limit <= 0 表示不限条数
+func (s *InMemoryVectorStore) List(ctx context.Context, filter map[string]interface{}, limit int, offset int) ([]types.Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := s.sortedMatches(filter)
+
+	if offset >= len(matched) {
+		return []types.Record{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(matched) {
+		end = len(matched)
+	}
+
+	records := make([]types.Record, 0, end-offset)
+	for _, rec := range matched[offset:end] {
+		records = append(records, *cloneRecord(*rec))
+	}
+	return records, nil
+}
+
+// Update 覆盖已有记录（与 QdrantStore 一致，复用 Add 的 Upsert 逻辑）
+func (s *InMemoryVectorStore) Update(ctx context.Context, record types.Record) error {
+	return s.Add(ctx, []types.Record{record})
+}
+
+// UpdateMetadata 只合并 metadata 字段，不改动内容与向量
+func (s *InMemoryVectorStore) UpdateMetadata(ctx context.Context, id string, fields map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	if rec.Metadata == nil {
+		rec.Metadata = make(map[string]interface{})
+	}
+	for k, v := range normalizePayloadValue(toPayloadMap(fields)).(map[string]interface{}) {
+		rec.Metadata[k] = v
+	}
+	s.dirty = true
+	return nil
+}
+
+// Get 按ID获取记录（包含向量）
+func (s *InMemoryVectorStore) Get(ctx context.Context, id string) (*types.Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.records[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return cloneRecord(*rec), nil
+}
+
+// Count 统计满足过滤条件的记录数
+func (s *InMemoryVectorStore) Count(ctx context.Context, filter map[string]interface{}) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, rec := range s.records {
+		if matchRecordFilters(rec, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// sortedMatches 返回按ID排序的匹配记录（调用者需持有读锁）
+func (s *InMemoryVectorStore) sortedMatches(filter map[string]interface{}) []*types.Record {
+	matched := make([]*types.Record, 0, len(s.records))
+	for _, rec := range s.records {
+		if matchRecordFilters(rec, filter) {
+			matched = append(matched, rec)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID < matched[j].ID
+	})
+	return matched
+}
+
+// ========== 快照持久化 ==========
+
+// StartSnapshotLoop 启动定期快照任务（仅在有变更时写盘）
+func (s *InMemoryVectorStore) StartSnapshotLoop(interval time.Duration) {
+	if s.snapshotPath == "" || interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Snapshot(); err != nil {
+					logger.Error("In-memory vector store snapshot failed", err)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+
+	logger.System("In-memory vector store snapshot started", "path", s.snapshotPath, "interval", interval)
+}
+
+// Close 停止快照任务并写入最后一次快照
+func (s *InMemoryVectorStore) Close() error {
+	select {
+	case <-s.stopChan:
+	default:
+		close(s.stopChan)
+	}
+	s.wg.Wait()
+
+	if s.snapshotPath == "" {
+		return nil
+	}
+	return s.Snapshot()
+}
+
+// Snapshot 将当前数据原子地写入快照文件（写临时文件后重命名）
+func (s *InMemoryVectorStore) Snapshot() error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	snap := snapshotFile{
+		Version:    1,
+		VectorSize: s.vectorSize,
+		SavedAt:    time.Now(),
+		Records:    make([]snapshotRecord, 0, len(s.records)),
+	}
+	for _, rec := range s.sortedMatches(nil) {
+		snap.Records = append(snap.Records, snapshotRecord{
+			ID:        rec.ID,
+			Content:   rec.Content,
+			Embedding: rec.Embedding,
+			Timestamp: rec.Timestamp,
+			Metadata:  rec.Metadata,
+			Type:      rec.Type,
+		})
+	}
+	data, err := json.Marshal(snap)
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err != nil {
+		s.markDirty()
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	if err := writeFileAtomic(s.snapshotPath, data); err != nil {
+		s.markDirty()
+		return err
+	}
+	return nil
+}
+
+// Load 从快照文件恢复数据，文件不存在时视为空库
+func (s *InMemoryVectorStore) Load() error {
+	data, err := os.ReadFile(s.snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap snapshotFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&snap); err != nil {
+		return fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.vectorSize > 0 && snap.VectorSize > 0 && snap.VectorSize != s.vectorSize {
+		return fmt.Errorf("snapshot vector size %d does not match configured size %d", snap.VectorSize, s.vectorSize)
+	}
+
+	s.records = make(map[string]*types.Record, len(snap.Records))
+	for _, r := range snap.Records {
+		meta, _ := normalizePayloadValue(r.Metadata).(map[string]interface{})
+		s.records[r.ID] = &types.Record{
+			ID:        r.ID,
+			Content:   r.Content,
+			Embedding: r.Embedding,
+			Timestamp: r.Timestamp,
+			Metadata:  meta,
+			Type:      r.Type,
+		}
+	}
+
+	logger.System("Loaded in-memory vector store snapshot", "path", s.snapshotPath, "records", len(s.records))
+	return nil
+}
+
+func (s *InMemoryVectorStore) markDirty() {
+	s.mu.Lock()
+	s.dirty = true
+	s.mu.Unlock()
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免写一半时崩溃导致快照损坏
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp snapshot: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// ========== 记录拷贝与 payload 规范化 ==========
+
+// cloneRecord 深拷贝记录，metadata 按 Qdrant payload 的方式规范化，
+// 保证内存存储读出的数据类型与 QdrantStore 一致（时间为RFC3339字符串、整数为int64等）
+func cloneRecord(r types.Record) *types.Record {
+	clone := r
+	if r.Embedding != nil {
+		clone.Embedding = make([]float32, len(r.Embedding))
+		copy(clone.Embedding, r.Embedding)
+	}
+	meta, _ := normalizePayloadValue(toPayloadMap(r.Metadata)).(map[string]interface{})
+	clone.Metadata = meta
+	return &clone
+}
+
+// normalizePayloadValue 将数值统一为 int64/float64，json.Number 按是否为整数转换
+func normalizePayloadValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = normalizePayloadValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = normalizePayloadValue(item)
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	default:
+		return v
+	}
+}
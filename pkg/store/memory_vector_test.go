@@ -0,0 +1,48 @@
+package store
+
+import (
+	"ai-memory/pkg/types"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestInMemoryVectorStoreListLimit(t *testing.T) {
+	ctx := context.Background()
+	s := NewInMemoryVectorStore("")
+	var records []types.Record
+	for i := 0; i < 5; i++ {
+		records = append(records, types.Record{
+			ID:        fmt.Sprintf("rec-%d", i),
+			Embedding: []float32{1, 0},
+			Metadata:  map[string]interface{}{"user_id": "u1"},
+		})
+	}
+	if err := s.Add(ctx, records); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	tests := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{limit: 2, offset: 0, want: []string{"rec-0", "rec-1"}},
+		{limit: 2, offset: 4, want: []string{"rec-4"}},
+		{limit: 0, offset: 0, want: []string{"rec-0", "rec-1", "rec-2", "rec-3", "rec-4"}},
+		{limit: -1, offset: 3, want: []string{"rec-3", "rec-4"}},
+		{limit: 0, offset: 5, want: []string{}},
+	}
+	for _, tt := range tests {
+		got, err := s.List(ctx, map[string]interface{}{"user_id": "u1"}, tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("List(%d, %d): %v", tt.limit, tt.offset, err)
+		}
+		ids := make([]string, 0, len(got))
+		for _, rec := range got {
+			ids = append(ids, rec.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("List(limit=%d, offset=%d) = %v, want %v", tt.limit, tt.offset, ids, tt.want)
+		}
+	}
+}
@@ -39,6 +39,7 @@ func NewMySQLStore(cfg *config.Config) (*sql.DB, error) {
 }
 
 // MySQLEndUserStore implements memory.EndUserStore.
+// 终端用户按 (tenant_id, user_identifier) 唯一，租户取自 context（见 types.TenantFrom）。
 type MySQLEndUserStore struct {
 	db *sql.DB
 }
@@ -49,17 +50,17 @@ func NewMySQLEndUserStore(db *sql.DB) *MySQLEndUserStore {
 
 func (s *MySQLEndUserStore) UpsertUser(ctx context.Context, identifier string) error {
 	query := `
-		INSERT INTO end_users (user_identifier, last_active) 
-		VALUES (?, NOW()) 
+		INSERT INTO end_users (tenant_id, user_identifier, last_active) 
+		VALUES (?, ?, NOW()) 
 		ON DUPLICATE KEY UPDATE last_active = NOW()
 	`
-	_, err := s.db.ExecContext(ctx, query, identifier)
+	_, err := s.db.ExecContext(ctx, query, types.TenantFrom(ctx), identifier)
 	return err
 }
 
 func (s *MySQLEndUserStore) ListUsers(ctx context.Context) ([]types.EndUser, error) {
-	query := `SELECT id, user_identifier, last_active, created_at FROM end_users ORDER BY last_active DESC`
-	rows, err := s.db.QueryContext(ctx, query)
+	query := `SELECT id, tenant_id, user_identifier, last_active, created_at, COALESCE(language, '') FROM end_users WHERE tenant_id = ? ORDER BY last_active DESC`
+	rows, err := s.db.QueryContext(ctx, query, types.TenantFrom(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -68,10 +69,50 @@ func (s *MySQLEndUserStore) ListUsers(ctx context.Context) ([]types.EndUser, err
 	var users []types.EndUser
 	for rows.Next() {
 		var u types.EndUser
-		if err := rows.Scan(&u.ID, &u.UserIdentifier, &u.LastActive, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.TenantID, &u.UserIdentifier, &u.LastActive, &u.CreatedAt, &u.Language); err != nil {
 			return nil, err
 		}
 		users = append(users, u)
 	}
 	return users, nil
 }
+
+// GetLanguage 读取用户的 Prompt 语言偏好
+func (s *MySQLEndUserStore) GetLanguage(ctx context.Context, identifier string) (string, error) {
+	var language string
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(language, '') FROM end_users WHERE tenant_id = ? AND user_identifier = ?`, types.TenantFrom(ctx), identifier).Scan(&language)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return language, err
+}
+
+// SetLanguage 设置用户的 Prompt 语言偏好（空字符串表示恢复默认）
+func (s *MySQLEndUserStore) SetLanguage(ctx context.Context, identifier string, language string) error {
+	query := `
+		INSERT INTO end_users (tenant_id, user_identifier, language)
+		VALUES (?, ?, NULLIF(?, ''))
+		ON DUPLICATE KEY UPDATE language = VALUES(language)
+	`
+	_, err := s.db.ExecContext(ctx, query, types.TenantFrom(ctx), identifier, language)
+	return err
+}
+
+// ListTenants 列出出现过终端用户的全部租户
+func (s *MySQLEndUserStore) ListTenants(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT tenant_id FROM end_users ORDER BY tenant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenantID)
+	}
+	return tenants, rows.Err()
+}
@@ -0,0 +1,421 @@
+package store
+
+import (
+	"ai-memory/pkg/config"
+	"ai-memory/pkg/types"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// tableNamePattern 表名白名单（表名无法参数化，只允许字母数字下划线）
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PgVectorStore 基于 Postgres + pgvector 的长期记忆存储
+// 表结构与 Qdrant payload 对应：content / type / timestamp 为独立列，metadata 为 JSONB。
+type PgVectorStore struct {
+	db    *sql.DB
+	table string
+}
+
+func NewPgVectorStore(cfg *config.Config) (*PgVectorStore, error) {
+	if !tableNamePattern.MatchString(cfg.PgVectorTable) {
+		return nil, fmt.Errorf("invalid pgvector table name: %q", cfg.PgVectorTable)
+	}
+
+	db, err := sql.Open("postgres", cfg.PgVectorDSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres connection: %w", err)
+	}
+
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+
+	return &PgVectorStore{
+		db:    db,
+		table: cfg.PgVectorTable,
+	}, nil
+}
+
+// Init ensures the extension, table and indexes exist (对应 QdrantStore.Init 创建 collection).
+func (s *PgVectorStore) Init(ctx context.Context, vectorSize int) error {
+	table := pq.QuoteIdentifier(s.table)
+
+	stmts := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id        UUID PRIMARY KEY,
+			content   TEXT NOT NULL,
+			type      TEXT NOT NULL DEFAULT '',
+			timestamp TIMESTAMPTZ NOT NULL,
+			metadata  JSONB NOT NULL DEFAULT '{}'::jsonb,
+			embedding vector(%d) NOT NULL
+		)`, table, vectorSize),
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+
+	// 已有表的向量维度必须与配置一致，否则写入会失败
+	var existingSize int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT atttypmod FROM pg_attribute WHERE attrelid = $1::regclass AND attname = 'embedding'`,
+		s.table,
+	).Scan(&existingSize)
+	if err != nil {
+		return fmt.Errorf("failed to inspect table: %w", err)
+	}
+	if existingSize > 0 && existingSize != vectorSize {
+		return fmt.Errorf("table %s vector size %d does not match configured size %d", s.table, existingSize, vectorSize)
+	}
+
+	indexes := []string{
+		// 余弦距离 HNSW 索引（对应 Qdrant Distance_Cosine）
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING hnsw (embedding vector_cosine_ops)`,
+			pq.QuoteIdentifier(s.table+"_embedding_hnsw_idx"), table),
+		// metadata 包含查询索引（user_id / tags / category 过滤）
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING gin (metadata jsonb_path_ops)`,
+			pq.QuoteIdentifier(s.table+"_metadata_gin_idx"), table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (type)`,
+			pq.QuoteIdentifier(s.table+"_type_idx"), table),
+	}
+	for _, stmt := range indexes {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+	return nil
+}
+
+// Add upserts records with their embeddings.
+func (s *PgVectorStore) Add(ctx context.Context, records []types.Record) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, content, type, timestamp, metadata, embedding)
+		VALUES ($1, $2, $3, $4, $5, $6::vector)
+		ON CONFLICT (id) DO UPDATE SET
+			content = EXCLUDED.content,
+			type = EXCLUDED.type,
+			timestamp = EXCLUDED.timestamp,
+			metadata = EXCLUDED.metadata,
+			embedding = EXCLUDED.embedding
+	`, pq.QuoteIdentifier(s.table))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		if r.Embedding == nil {
+			continue
+		}
+
+		metadata, err := json.Marshal(toPayloadMap(r.Metadata))
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata: %w", err)
+		}
+
+		if _, err := stmt.ExecContext(ctx, r.ID, r.Content, string(r.Type), r.Timestamp, string(metadata), formatVector(r.Embedding)); err != nil {
+			return fmt.Errorf("failed to upsert record %s: %w", r.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Search finds the nearest neighbors by cosine similarity.
+func (s *PgVectorStore) Search(ctx context.Context, vector []float32, limit int, scoreThreshold float32, filters map[string]interface{}) ([]types.Record, error) {
+	args := []interface{}{formatVector(vector), scoreThreshold, limit}
+	where, args := buildPgFilter(filters, args)
+	if where != "" {
+		where = " AND " + where
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, content, type, timestamp, metadata
+		FROM %s
+		WHERE 1 - (embedding <=> $1::vector) >= $2%s
+		ORDER BY embedding <=> $1::vector
+		LIMIT $3
+	`, pq.QuoteIdentifier(s.table), where)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPgRecords(rows)
+}
+
+// Delete removes records by ID.
+func (s *PgVectorStore) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = ANY($1::uuid[])`, pq.QuoteIdentifier(s.table))
+	_, err := s.db.ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
+
+// List retrieves records ordered by ID (与 Qdrant Scroll 顺序一致).
+// limit <= 0 表示不限条数（VectorStore 接口约定，各后端一致）.
+func (s *PgVectorStore) List(ctx context.Context, filters map[string]interface{}, limit int, offset int) ([]types.Record, error) {
+	args := []interface{}{offset}
+	where, args := buildPgFilter(filters, args)
+	if where != "" {
+		where = " WHERE " + where
+	}
+	limitClause := ""
+	if limit > 0 {
+		args = append(args, limit)
+		limitClause = fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, content, type, timestamp, metadata
+		FROM %s%s
+		ORDER BY id%s
+		OFFSET $1
+	`, pq.QuoteIdentifier(s.table), where, limitClause)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records, err := scanPgRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		records = []types.Record{}
+	}
+	return records, nil
+}
+
+// Update overwrites an existing record.
+func (s *PgVectorStore) Update(ctx context.Context, record types.Record) error {
+	// Re-use Add which does upsert.
+	return s.Add(ctx, []types.Record{record})
+}
+
+// UpdateMetadata merges fields into the JSONB metadata (metadata || fields), leaving content and embedding untouched.
+func (s *PgVectorStore) UpdateMetadata(ctx context.Context, id string, fields map[string]interface{}) error {
+	patch, err := json.Marshal(toPayloadMap(fields))
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET metadata = metadata || $2::jsonb WHERE id = $1`, pq.QuoteIdentifier(s.table))
+	result, err := s.db.ExecContext(ctx, query, id, string(patch))
+	if err != nil {
+		return fmt.Errorf("failed to update metadata of record %s: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Get retrieves a record (including its embedding).
+func (s *PgVectorStore) Get(ctx context.Context, id string) (*types.Record, error) {
+	query := fmt.Sprintf(`
+		SELECT id, content, type, timestamp, metadata, embedding::text
+		FROM %s
+		WHERE id = $1
+	`, pq.QuoteIdentifier(s.table))
+
+	var (
+		rec       types.Record
+		typeStr   string
+		metadata  []byte
+		embedding string
+	)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&rec.ID, &rec.Content, &typeStr, &rec.Timestamp, &metadata, &embedding)
+	if err == sql.ErrNoRows {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec.Type = types.MemoryType(typeStr)
+	if rec.Metadata, err = decodePgMetadata(metadata); err != nil {
+		return nil, err
+	}
+	if rec.Embedding, err = parseVector(embedding); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Count returns the number of records matching a filter.
+func (s *PgVectorStore) Count(ctx context.Context, filters map[string]interface{}) (int64, error) {
+	where, args := buildPgFilter(filters, nil)
+	if where != "" {
+		where = " WHERE " + where
+	}
+
+	var count int64
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s%s`, pq.QuoteIdentifier(s.table), where)
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Close releases the connection pool.
+func (s *PgVectorStore) Close() error {
+	return s.db.Close()
+}
+
+// buildPgFilter 将过滤条件转换为 SQL（语义与 QdrantStore 一致）
+// metadata 字段若为数组（如 tags），任一元素匹配即可；关键字条件均可命中 GIN 索引。
+func buildPgFilter(filters map[string]interface{}, args []interface{}) (string, []interface{}) {
+	if len(filters) == 0 {
+		return "", args
+	}
+	f := parseRecordFilter(filters)
+
+	var conditions []string
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	// metadata 字段等于关键字，或为包含该关键字的数组
+	keywordCond := func(field, want string) string {
+		k, v := arg(field), arg(want)
+		return fmt.Sprintf(
+			"(metadata @> jsonb_build_object(%s::text, %s::text) OR metadata @> jsonb_build_object(%s::text, jsonb_build_array(%s::text)))",
+			k, v, k, v,
+		)
+	}
+
+	if f.Type != "" {
+		conditions = append(conditions, "type = "+arg(f.Type))
+	}
+	for _, field := range f.sortedKeywordFields() {
+		conditions = append(conditions, keywordCond(field, f.Keywords[field]))
+	}
+	for _, tag := range f.RequiredTags {
+		conditions = append(conditions, keywordCond("tags", tag))
+	}
+	if len(f.ExcludedTags) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"NOT (COALESCE(metadata->'tags', '[]'::jsonb) ?| %s::text[])", arg(pq.Array(f.ExcludedTags)),
+		))
+	}
+	if f.MinDecayScore != nil {
+		conditions = append(conditions, fmt.Sprintf(
+			"jsonb_typeof(metadata->'decay_score') = 'number' AND (metadata->>'decay_score')::float8 >= %s", arg(*f.MinDecayScore),
+		))
+	}
+	if f.TimeStart != nil {
+		conditions = append(conditions, "timestamp >= "+arg(*f.TimeStart))
+	}
+	if f.TimeEnd != nil {
+		conditions = append(conditions, "timestamp <= "+arg(*f.TimeEnd))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// scanPgRecords 读取 (id, content, type, timestamp, metadata) 结果集
+func scanPgRecords(rows *sql.Rows) ([]types.Record, error) {
+	var records []types.Record
+	for rows.Next() {
+		var (
+			rec      types.Record
+			typeStr  string
+			metadata []byte
+		)
+		if err := rows.Scan(&rec.ID, &rec.Content, &typeStr, &rec.Timestamp, &metadata); err != nil {
+			return nil, err
+		}
+		rec.Type = types.MemoryType(typeStr)
+
+		meta, err := decodePgMetadata(metadata)
+		if err != nil {
+			return nil, err
+		}
+		rec.Metadata = meta
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// decodePgMetadata 解析 JSONB metadata，数值类型与 QdrantStore 返回的一致（int64/float64）
+func decodePgMetadata(data []byte) (map[string]interface{}, error) {
+	raw := make(map[string]interface{})
+	if len(data) == 0 {
+		return raw, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+
+	meta, _ := normalizePayloadValue(raw).(map[string]interface{})
+	return meta, nil
+}
+
+// formatVector 转换为 pgvector 文本格式: [1,2,3]
+func formatVector(v []float32) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(f), 'f', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// parseVector 解析 pgvector 文本格式
+func parseVector(s string) ([]float32, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	vec := make([]float32, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vector: %w", err)
+		}
+		vec[i] = float32(f)
+	}
+	return vec, nil
+}
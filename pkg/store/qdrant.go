@@ -11,6 +11,9 @@ import (
 	"time"
 
 	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type QdrantStore struct {
@@ -120,6 +123,51 @@ func toPayloadMap(m map[string]interface{}) map[string]interface{} {
 	return clean
 }
 
+// buildQdrantFilter 将过滤条件转换为 Qdrant Filter（Search / List / Count 共用）
+// 普通键按关键字匹配 metadata 字段；tags 需全部包含，excluded_tags 任一命中即排除（any-of），
+// min_decay_score 与时间范围使用 Range / DatetimeRange 条件。
+func buildQdrantFilter(filters map[string]interface{}) *qdrant.Filter {
+	if len(filters) == 0 {
+		return nil
+	}
+	f := parseRecordFilter(filters)
+
+	var must, mustNot []*qdrant.Condition
+	if f.Type != "" {
+		must = append(must, qdrant.NewMatchKeyword("type", f.Type))
+	}
+	for _, field := range f.sortedKeywordFields() {
+		must = append(must, qdrant.NewMatchKeyword("metadata."+field, f.Keywords[field]))
+	}
+	for _, tag := range f.RequiredTags {
+		must = append(must, qdrant.NewMatchKeyword("metadata.tags", tag))
+	}
+	if len(f.ExcludedTags) > 0 {
+		mustNot = append(mustNot, qdrant.NewMatchKeywords("metadata.tags", f.ExcludedTags...))
+	}
+	if f.MinDecayScore != nil {
+		must = append(must, qdrant.NewRange("metadata.decay_score", &qdrant.Range{Gte: f.MinDecayScore}))
+	}
+	if f.TimeStart != nil || f.TimeEnd != nil {
+		dtRange := &qdrant.DatetimeRange{}
+		if f.TimeStart != nil {
+			dtRange.Gte = timestamppb.New(*f.TimeStart)
+		}
+		if f.TimeEnd != nil {
+			dtRange.Lte = timestamppb.New(*f.TimeEnd)
+		}
+		must = append(must, qdrant.NewDatetimeRange("timestamp", dtRange))
+	}
+
+	if len(must) == 0 && len(mustNot) == 0 {
+		return nil
+	}
+	return &qdrant.Filter{
+		Must:    must,
+		MustNot: mustNot,
+	}
+}
+
 func (s *QdrantStore) Add(ctx context.Context, records []types.Record) error {
 	var points []*qdrant.PointStruct
 	for _, r := range records {
@@ -160,38 +208,7 @@ func (s *QdrantStore) Add(ctx context.Context, records []types.Record) error {
 }
 
 func (s *QdrantStore) Search(ctx context.Context, vector []float32, limit int, scoreThreshold float32, filters map[string]interface{}) ([]types.Record, error) {
-	// Build Filter
-	var qdrantFilter *qdrant.Filter
-	if len(filters) > 0 {
-		var conditions []*qdrant.Condition
-		for k, v := range filters {
-			// Create a match condition for each filter
-			// Assuming exact match for string/int values
-			valStr := fmt.Sprintf("%v", v)
-
-			// Hack fix for user_id nesting in Qdrant Payload vs InMemory Metadata
-			key := k
-			if k == "user_id" {
-				key = "metadata.user_id"
-			}
-
-			conditions = append(conditions, &qdrant.Condition{
-				ConditionOneOf: &qdrant.Condition_Field{
-					Field: &qdrant.FieldCondition{
-						Key: key,
-						Match: &qdrant.Match{
-							MatchValue: &qdrant.Match_Keyword{
-								Keyword: valStr,
-							},
-						},
-					},
-				},
-			})
-		}
-		qdrantFilter = &qdrant.Filter{
-			Must: conditions,
-		}
-	}
+	qdrantFilter := buildQdrantFilter(filters)
 
 	searchResult, err := s.client.GetPointsClient().Search(ctx, &qdrant.SearchPoints{
 		CollectionName: s.collection,
@@ -325,38 +342,9 @@ func (s *QdrantStore) Delete(ctx context.Context, ids []string) error {
 // List is not efficiently supported by Vector DBs usually (scan),
 // but needed for Summarize/Clear interface.
 // Implementation using Scroll.
-// List uses Scroll to retrieve records with optional filtering.
+// List uses Scroll to retrieve records with optional filtering; limit <= 0 means no limit.
 func (s *QdrantStore) List(ctx context.Context, filters map[string]interface{}, limit int, offset int) ([]types.Record, error) {
-	// Build Filter
-	var qdrantFilter *qdrant.Filter
-	if len(filters) > 0 {
-		var conditions []*qdrant.Condition
-		for k, v := range filters {
-			valStr := fmt.Sprintf("%v", v)
-
-			// Hack fix for user_id nesting
-			key := k
-			if k == "user_id" {
-				key = "metadata.user_id"
-			}
-
-			conditions = append(conditions, &qdrant.Condition{
-				ConditionOneOf: &qdrant.Condition_Field{
-					Field: &qdrant.FieldCondition{
-						Key: key,
-						Match: &qdrant.Match{
-							MatchValue: &qdrant.Match_Keyword{
-								Keyword: valStr,
-							},
-						},
-					},
-				},
-			})
-		}
-		qdrantFilter = &qdrant.Filter{
-			Must: conditions,
-		}
-	}
+	qdrantFilter := buildQdrantFilter(filters)
 
 	// Logic for offset: Qdrant Scroll uses "Offset" as a PointID to start AFTER.
 	// It does not support integer offset for skipping N items efficiently.
@@ -376,12 +364,14 @@ func (s *QdrantStore) List(ctx context.Context, filters map[string]interface{},
 	// Actually, Qdrant Go client ScrollPoints takes "Offset" which is a PointId.
 	// We'll iterate.
 
+	// limit <= 0 表示不限条数，一直滚动到末尾
+	unlimited := limit <= 0
 	currentCount := 0
 	targetCount := limit + offset
 
-	for currentCount < targetCount {
+	for unlimited || currentCount < targetCount {
 		batchSize := uint32(100) // Fetch in batches
-		if uint32(targetCount-currentCount) < batchSize {
+		if !unlimited && uint32(targetCount-currentCount) < batchSize {
 			batchSize = uint32(targetCount - currentCount)
 		}
 
@@ -416,7 +406,7 @@ func (s *QdrantStore) List(ctx context.Context, filters map[string]interface{},
 	if start >= len(allPoints) {
 		return []types.Record{}, nil
 	}
-	if end > len(allPoints) {
+	if unlimited || end > len(allPoints) {
 		end = len(allPoints)
 	}
 
@@ -464,6 +454,28 @@ func (s *QdrantStore) Update(ctx context.Context, record types.Record) error {
 	return s.Add(ctx, []types.Record{record})
 }
 
+// UpdateMetadata merges fields into the nested metadata payload (SetPayload), leaving content and vector untouched.
+func (s *QdrantStore) UpdateMetadata(ctx context.Context, id string, fields map[string]interface{}) error {
+	key := "metadata"
+	operationInfo, err := s.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: s.collection,
+		Wait:           func(b bool) *bool { return &b }(true),
+		Payload:        qdrant.NewValueMap(toPayloadMap(fields)),
+		PointsSelector: qdrant.NewPointsSelector(qdrant.NewIDUUID(id)),
+		Key:            &key,
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if operationInfo.Status != qdrant.UpdateStatus_Completed && operationInfo.Status != qdrant.UpdateStatus_Acknowledged {
+		return fmt.Errorf("set payload not completed: %v", operationInfo.Status)
+	}
+	return nil
+}
+
 // Get retrieves a record.
 func (s *QdrantStore) Get(ctx context.Context, id string) (*types.Record, error) {
 	points, err := s.client.GetPointsClient().Get(ctx, &qdrant.GetPoints{
@@ -479,7 +491,7 @@ func (s *QdrantStore) Get(ctx context.Context, id string) (*types.Record, error)
 	}
 
 	if len(points.Result) == 0 {
-		return nil, fmt.Errorf("not found")
+		return nil, ErrRecordNotFound
 	}
 
 	pt := points.Result[0]
@@ -527,31 +539,7 @@ func (s *QdrantStore) Get(ctx context.Context, id string) (*types.Record, error)
 }
 
 func (s *QdrantStore) Count(ctx context.Context, filters map[string]interface{}) (int64, error) {
-	// Build Filter (Same logic as List/Search)
-	var qdrantFilter *qdrant.Filter
-	if len(filters) > 0 {
-		var conditions []*qdrant.Condition
-		for k, v := range filters {
-			valStr := fmt.Sprintf("%v", v)
-			key := k
-			if k == "user_id" {
-				key = "metadata.user_id"
-			}
-			conditions = append(conditions, &qdrant.Condition{
-				ConditionOneOf: &qdrant.Condition_Field{
-					Field: &qdrant.FieldCondition{
-						Key: key,
-						Match: &qdrant.Match{
-							MatchValue: &qdrant.Match_Keyword{
-								Keyword: valStr,
-							},
-						},
-					},
-				},
-			})
-		}
-		qdrantFilter = &qdrant.Filter{Must: conditions}
-	}
+	qdrantFilter := buildQdrantFilter(filters)
 
 	countResult, err := s.client.GetPointsClient().Count(ctx, &qdrant.CountPoints{
 		CollectionName: s.collection,
@@ -85,9 +85,9 @@ func (r *RedisStore) ScanKeys(ctx context.Context, pattern string) ([]string, er
 	return keys, nil
 }
 
-// Update searches all STM lists for the record and updates it.
+// Update searches the current tenant's STM lists for the record and updates it.
 func (r *RedisStore) Update(ctx context.Context, record types.Record) error {
-	iter := r.client.Scan(ctx, 0, "memory:stm:*:*", 0).Iterator()
+	iter := r.client.Scan(ctx, 0, types.TenantKey(types.TenantFrom(ctx), "memory:stm:*:*"), 0).Iterator()
 	found := false
 
 	for iter.Next(ctx) {
@@ -119,9 +119,9 @@ func (r *RedisStore) Update(ctx context.Context, record types.Record) error {
 	return nil
 }
 
-// Get finds a record by ID in STM.
+// Get finds a record by ID in the current tenant's STM.
 func (r *RedisStore) Get(ctx context.Context, id string) (*types.Record, error) {
-	iter := r.client.Scan(ctx, 0, "memory:stm:*:*", 0).Iterator()
+	iter := r.client.Scan(ctx, 0, types.TenantKey(types.TenantFrom(ctx), "memory:stm:*:*"), 0).Iterator()
 	for iter.Next(ctx) {
 		key := iter.Val()
 		items, err := r.client.LRange(ctx, key, 0, -1).Result()
@@ -28,6 +28,7 @@ func NewStagingStore(client *redis.Client, ttlDays int) *StagingStore {
 
 // AddOrIncrement 添加或更新暂存区条目（频次+1）
 // 【需求3.1】集成语义去重：使用向量相似度检测
+// 条目键按 context 中的租户加前缀（见 types.TenantKey），不同租户的同名用户互不可见。
 func (s *StagingStore) AddOrIncrement(ctx context.Context, userID, sessionID, content string, judgeResult *types.JudgeResult, embedder Embedder) error {
 	// 1. 生成embedding（用于语义去重）
 	var embedding []float32
@@ -45,25 +46,7 @@ func (s *StagingStore) AddOrIncrement(ctx context.Context, userID, sessionID, co
 		similarEntry, _ := s.SearchSimilar(ctx, userID, embedding, 0.95)
 		if similarEntry != nil {
 			// 找到相似条目，增加计数
-			similarEntry.OccurrenceCount++
-			similarEntry.LastSeenAt = time.Now()
-			similarEntry.ValueScore = judgeResult.ValueScore
-			similarEntry.ConfidenceScore = judgeResult.ConfidenceScore
-			similarEntry.Category = judgeResult.Category
-			similarEntry.ExtractedTags = judgeResult.Tags
-			similarEntry.ExtractedEntities = judgeResult.Entities
-
-			// 记录 SessionID (去重)
-			foundSession := false
-			for _, sid := range similarEntry.SessionIDs {
-				if sid == sessionID {
-					foundSession = true
-					break
-				}
-			}
-			if !foundSession && sessionID != "" {
-				similarEntry.SessionIDs = append(similarEntry.SessionIDs, sessionID)
-			}
+			applyJudgeResult(similarEntry, judgeResult, sessionID, time.Now())
 
 			// 更新
 			data, _ := json.Marshal(similarEntry)
@@ -73,7 +56,8 @@ func (s *StagingStore) AddOrIncrement(ctx context.Context, userID, sessionID, co
 	}
 
 	// 3. 无相似条目或embedding失败，使用原有逻辑（hash去重）
-	entryID := fmt.Sprintf("staging:%s:%s", userID, hash(content))
+	tenantID := types.TenantFrom(ctx)
+	entryID := types.TenantKey(tenantID, fmt.Sprintf("staging:%s:%s", userID, hash(content)))
 
 	// 检查是否已存在
 	exists, err := s.client.Exists(ctx, entryID).Result()
@@ -95,46 +79,11 @@ func (s *StagingStore) AddOrIncrement(ctx context.Context, userID, sessionID, co
 			return fmt.Errorf("解析暂存区条目失败: %w", err)
 		}
 
-		// 频次+1
-		entry.OccurrenceCount++
-		entry.LastSeenAt = now
-
-		// 更新分数（取最新判定结果）
-		entry.ValueScore = judgeResult.ValueScore
-		entry.ConfidenceScore = judgeResult.ConfidenceScore
-		entry.Category = judgeResult.Category
-		entry.ExtractedTags = judgeResult.Tags
-		entry.ExtractedEntities = judgeResult.Entities
-
-		// 记录 SessionID (去重)
-		foundSession := false
-		for _, sid := range entry.SessionIDs {
-			if sid == sessionID {
-				foundSession = true
-				break
-			}
-		}
-		if !foundSession && sessionID != "" {
-			entry.SessionIDs = append(entry.SessionIDs, sessionID)
-		}
+		// 频次+1，更新分数（取最新判定结果）
+		applyJudgeResult(&entry, judgeResult, sessionID, now)
 	} else {
 		// 创建新条目
-		entry = types.StagingEntry{
-			ID:                entryID,
-			Content:           content,
-			Embedding:         embedding, // 存储embedding
-			UserID:            userID,
-			SessionIDs:        []string{sessionID},
-			FirstSeenAt:       now,
-			LastSeenAt:        now,
-			OccurrenceCount:   1,
-			ValueScore:        judgeResult.ValueScore,
-			ConfidenceScore:   judgeResult.ConfidenceScore,
-			Category:          judgeResult.Category,
-			ExtractedTags:     judgeResult.Tags,
-			ExtractedEntities: judgeResult.Entities,
-			Status:            types.StagingPending,
-		}
+		entry = newStagingEntry(entryID, tenantID, userID, sessionID, content, embedding, judgeResult, now)
 	}
 
 	// 序列化并存储
@@ -158,7 +107,7 @@ func (s *StagingStore) AddOrIncrement(ctx context.Context, userID, sessionID, co
 //
 // 返回：最相似的条目（如无则返回nil）
 func (s *StagingStore) SearchSimilar(ctx context.Context, userID string, queryVector []float32, threshold float64) (*types.StagingEntry, error) {
-	pattern := fmt.Sprintf("staging:%s:*", userID)
+	pattern := types.TenantKey(types.TenantFrom(ctx), fmt.Sprintf("staging:%s:*", userID))
 	var cursor uint64
 	var bestEntry *types.StagingEntry
 	var bestSimilarity float64
@@ -203,12 +152,23 @@ func (s *StagingStore) SearchSimilar(ctx context.Context, userID string, queryVe
 	return bestEntry, nil
 }
 
-// GetPendingEntries 获取待晋升的暂存区条目
+// GetPendingEntries 获取待晋升的暂存区条目（跨全部租户，条目的 TenantID 标明归属）
 func (s *StagingStore) GetPendingEntries(ctx context.Context, minOccurrences int, minWaitHours int) ([]*types.StagingEntry, error) {
-	// 扫描所有staging keys
+	var entries []*types.StagingEntry
+	for _, pattern := range types.TenantKeyPatterns("staging:*") {
+		matched, err := s.scanPending(ctx, pattern, minOccurrences, minWaitHours)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, matched...)
+	}
+	return entries, nil
+}
+
+// scanPending 扫描单个键模式下待晋升的条目
+func (s *StagingStore) scanPending(ctx context.Context, pattern string, minOccurrences int, minWaitHours int) ([]*types.StagingEntry, error) {
 	var cursor uint64
 	var entries []*types.StagingEntry
-	pattern := "staging:*"
 
 	for {
 		keys, nextCursor, err := s.client.Scan(ctx, cursor, pattern, 100).Result()
@@ -254,7 +214,7 @@ func (s *StagingStore) GetPendingEntries(ctx context.Context, minOccurrences int
 
 // GetAllByUser 获取用户的所有暂存区条目（用于Admin界面）
 func (s *StagingStore) GetAllByUser(ctx context.Context, userID string) ([]*types.StagingEntry, error) {
-	pattern := fmt.Sprintf("staging:%s:*", userID)
+	pattern := types.TenantKey(types.TenantFrom(ctx), fmt.Sprintf("staging:%s:*", userID))
 	var cursor uint64
 	var entries []*types.StagingEntry
 
@@ -294,17 +254,7 @@ func (s *StagingStore) GetBySession(ctx context.Context, userID, sessionID strin
 		return nil, err
 	}
 
-	var sessionEntries []*types.StagingEntry
-	for _, entry := range allEntries {
-		for _, sid := range entry.SessionIDs {
-			if sid == sessionID {
-				sessionEntries = append(sessionEntries, entry)
-				break
-			}
-		}
-	}
-
-	return sessionEntries, nil
+	return filterBySession(allEntries, sessionID), nil
 }
 
 // Update 更新暂存区条目状态
@@ -330,6 +280,66 @@ func (s *StagingStore) DeleteBatch(ctx context.Context, entryIDs []string) error
 	return s.client.Del(ctx, entryIDs...).Err()
 }
 
+// newStagingEntry 根据判定结果创建新的暂存区条目
+func newStagingEntry(entryID, tenantID, userID, sessionID, content string, embedding []float32, judgeResult *types.JudgeResult, now time.Time) types.StagingEntry {
+	return types.StagingEntry{
+		ID:                entryID,
+		Content:           content,
+		Embedding:         embedding, // 存储embedding
+		TenantID:          tenantID,
+		UserID:            userID,
+		SessionIDs:        []string{sessionID},
+		FirstSeenAt:       now,
+		LastSeenAt:        now,
+		OccurrenceCount:   1,
+		ValueScore:        judgeResult.ValueScore,
+		ConfidenceScore:   judgeResult.ConfidenceScore,
+		Category:          judgeResult.Category,
+		ExtractedTags:     judgeResult.Tags,
+		ExtractedEntities: judgeResult.Entities,
+		Status:            types.StagingPending,
+		PromptVersions:    judgeResult.PromptVersions,
+	}
+}
+
+// applyJudgeResult 已有条目再次出现：频次+1，并以最新判定结果覆盖分数与标签
+func applyJudgeResult(entry *types.StagingEntry, judgeResult *types.JudgeResult, sessionID string, now time.Time) {
+	entry.OccurrenceCount++
+	entry.LastSeenAt = now
+	entry.ValueScore = judgeResult.ValueScore
+	entry.ConfidenceScore = judgeResult.ConfidenceScore
+	entry.Category = judgeResult.Category
+	entry.ExtractedTags = judgeResult.Tags
+	entry.ExtractedEntities = judgeResult.Entities
+	if judgeResult.PromptVersions != nil {
+		entry.PromptVersions = judgeResult.PromptVersions
+	}
+
+	// 记录 SessionID (去重)
+	for _, sid := range entry.SessionIDs {
+		if sid == sessionID {
+			return
+		}
+	}
+	if sessionID != "" {
+		entry.SessionIDs = append(entry.SessionIDs, sessionID)
+	}
+}
+
+// filterBySession 筛选该会话触达过的条目
+func filterBySession(entries []*types.StagingEntry, sessionID string) []*types.StagingEntry {
+	var sessionEntries []*types.StagingEntry
+	for _, entry := range entries {
+		for _, sid := range entry.SessionIDs {
+			if sid == sessionID {
+				sessionEntries = append(sessionEntries, entry)
+				break
+			}
+		}
+	}
+	return sessionEntries
+}
+
 // hash 使用 MD5 生成唯一哈希值
 func hash(s string) string {
 	h := md5.New()
@@ -1,6 +1,63 @@
 package types
 
-import "time"
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultTenant 未指定租户时使用的租户（升级前的数据均归属该租户）
+const DefaultTenant = "default"
+
+// tenantIDPattern 租户ID会拼入 Redis 键与过滤条件，只允许字母、数字、下划线和中划线
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidTenantID 校验租户ID格式
+func ValidTenantID(tenantID string) bool {
+	return tenantIDPattern.MatchString(tenantID)
+}
+
+type tenantKey struct{}
+
+// WithTenant 标记后续操作所属的租户（应用）
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom 读取 context 中的租户，未设置时返回 DefaultTenant
+func TenantFrom(ctx context.Context) string {
+	if tenantID, _ := ctx.Value(tenantKey{}).(string); tenantID != "" {
+		return tenantID
+	}
+	return DefaultTenant
+}
+
+// tenantKeyPrefix 非默认租户的 Redis 键前缀
+const tenantKeyPrefix = "tenant:"
+
+// TenantKey 为 Redis 键加上租户前缀；默认租户不加前缀，兼容升级前的键
+func TenantKey(tenantID, key string) string {
+	if tenantID == "" || tenantID == DefaultTenant {
+		return key
+	}
+	return tenantKeyPrefix + tenantID + ":" + key
+}
+
+// SplitTenantKey 拆分 TenantKey 生成的键，返回租户与原始键
+func SplitTenantKey(key string) (tenantID, rest string) {
+	if strings.HasPrefix(key, tenantKeyPrefix) {
+		if tenantID, rest, ok := strings.Cut(key[len(tenantKeyPrefix):], ":"); ok {
+			return tenantID, rest
+		}
+	}
+	return DefaultTenant, key
+}
+
+// TenantKeyPatterns 跨全部租户扫描时使用的键模式（默认租户 + 其他租户）
+func TenantKeyPatterns(pattern string) []string {
+	return []string{pattern, tenantKeyPrefix + "*:" + pattern}
+}
 
 // MemoryType defines the category of a memory record.
 type MemoryType string
@@ -43,6 +100,7 @@ type Record struct {
 
 // LTMMetadata 长期记忆的增强元数据结构
 type LTMMetadata struct {
+	TenantID  string    `json:"tenant_id"`
 	UserID    string    `json:"user_id"`
 	SessionID string    `json:"session_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
@@ -60,6 +118,9 @@ type LTMMetadata struct {
 	// 来源追踪
 	SourceType       string  `json:"source_type"`       // staging/manual/legacy
 	ConfidenceOrigin float64 `json:"confidence_origin"` // 写入时的信心分数
+
+	// Prompt 追踪（模板名 -> 语言@版本）
+	PromptVersions map[string]string `json:"prompt_versions,omitempty"`
 }
 
 // StagingEntry 暂存区条目（候选记忆）
@@ -67,6 +128,7 @@ type StagingEntry struct {
 	ID                string            `json:"id"`
 	Content           string            `json:"content"`
 	Embedding         []float32         `json:"embedding,omitempty"` // 新增：用于语义去重
+	TenantID          string            `json:"tenant_id,omitempty"` // 为空表示默认租户（升级前的条目）
 	UserID            string            `json:"user_id"`
 	FirstSeenAt       time.Time         `json:"first_seen_at"`
 	LastSeenAt        time.Time         `json:"last_seen_at"`
@@ -79,6 +141,9 @@ type StagingEntry struct {
 	Status            StagingStatus     `json:"status"`
 	ConfirmedBy       string            `json:"confirmed_by"` // auto/user
 	SessionIDs        []string          `json:"session_ids"`  // 记录所有触达过该事实的会话
+
+	// 判定/摘要使用的 Prompt 模板版本
+	PromptVersions map[string]string `json:"prompt_versions,omitempty"`
 }
 
 // JudgeResult LLM判定模型的输出
@@ -91,6 +156,9 @@ type JudgeResult struct {
 	Entities        map[string]string `json:"entities"`     // 实体映射
 	ShouldStage     bool              `json:"should_stage"` // 是否应进入暂存区
 	IsCritical      bool              `json:"is_critical"`  // 是否属于关键事实/强烈意图（可直接晋升LTM）
+
+	// 产生该结果的 Prompt 模板版本（模板名 -> 语言@版本）
+	PromptVersions map[string]string `json:"prompt_versions,omitempty"`
 }
 
 // RecallOptions 增强的召回查询选项
@@ -107,15 +175,66 @@ type RecallOptions struct {
 	// 时间范围
 	TimeRangeStart *time.Time `json:"time_range_start,omitempty"`
 	TimeRangeEnd   *time.Time `json:"time_range_end,omitempty"`
+
+	// 检索模式: vector / lexical / hybrid（为空使用 RECALL_SEARCH_MODE）
+	SearchMode string `json:"search_mode,omitempty"`
+	// 混合检索时 RRF 融合权重（均未设置时各为 1.0）
+	VectorWeight  float64 `json:"vector_weight,omitempty"`
+	LexicalWeight float64 `json:"lexical_weight,omitempty"`
+}
+
+// 上下文组装分区
+const (
+	ContextSectionSTM     = "stm"     // 当前会话的最近对话
+	ContextSectionStaging = "staging" // 暂存区中的候选事实
+	ContextSectionLTM     = "ltm"     // 长期记忆事实
+)
+
+// ContextOptions 上下文组装选项（在召回条件基础上增加 token 预算）
+type ContextOptions struct {
+	RecallOptions
+
+	MaxTokens      int            `json:"max_tokens"`                // 总 token 预算（<=0 使用默认值）
+	SectionBudgets map[string]int `json:"section_budgets,omitempty"` // 各分区预算上限，键为 stm / staging / ltm
+	Priority       []string       `json:"priority,omitempty"`        // 分区分配预算的优先顺序，默认 stm > ltm > staging
+}
+
+// ContextSection 单个分区的组装结果
+type ContextSection struct {
+	Name      string   `json:"name"`
+	Budget    int      `json:"budget"`    // 分区可用预算
+	Tokens    int      `json:"tokens"`    // 实际占用（含标题）
+	Included  int      `json:"included"`  // 纳入的条目数
+	Dropped   int      `json:"dropped"`   // 因预算不足丢弃的条目数
+	Truncated bool     `json:"truncated"` // 最后一条是否被截断
+	Records   []Record `json:"records"`
+}
+
+// ContextResult 可直接注入 Prompt 的上下文块
+type ContextResult struct {
+	Context   string           `json:"context"`
+	Tokens    int              `json:"tokens"`
+	MaxTokens int              `json:"max_tokens"`
+	Sections  []ContextSection `json:"sections"`
 }
 
 // EndUser represents a user interacting with the AI.
 type EndUser struct {
 	ID             int       `json:"id"`
+	TenantID       string    `json:"tenant_id"`
 	UserIdentifier string    `json:"user_identifier"`
 	LastActive     time.Time `json:"last_active"`
 	CreatedAt      time.Time `json:"created_at"`
+	Language       string    `json:"language,omitempty"` // Prompt 语言偏好，空表示使用默认语言
 	// Stats (not in DB)
 	SessionCount int `json:"session_count"`
 	LTMCount     int `json:"ltm_count"`
 }
+
+// Tenant 条目所属租户（升级前写入的条目没有租户字段，归属默认租户）
+func (e *StagingEntry) Tenant() string {
+	if e.TenantID == "" {
+		return DefaultTenant
+	}
+	return e.TenantID
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"ai-memory/pkg/config"
+	"ai-memory/pkg/llm"
+	"ai-memory/pkg/logger"
+	"ai-memory/pkg/memory"
+	"ai-memory/pkg/store"
+	"strings"
+	"time"
+)
+
+// providerLimiters 每个提供商共享一个并发限制器（对话与向量化共用同一配额）
+var providerLimiters = make(map[string]chan struct{})
+
+// newResilience 为指定提供商的调用链创建重试/并发/熔断执行器
+func newResilience(cfg *config.Config, kind, provider string) *llm.Resilience {
+	limiter, ok := providerLimiters[provider]
+	if !ok {
+		limiter = llm.NewConcurrencyLimiter(cfg.LLMMaxConcurrency)
+		providerLimiters[provider] = limiter
+	}
+
+	return llm.NewResilience(kind+":"+provider, llm.ResilienceConfig{
+		MaxRetries:       cfg.LLMMaxRetries,
+		BaseDelay:        time.Duration(cfg.LLMRetryBaseDelayMs) * time.Millisecond,
+		MaxDelay:         time.Duration(cfg.LLMRetryMaxDelaySeconds) * time.Second,
+		FailureThreshold: cfg.LLMBreakerFailureThreshold,
+		OpenDuration:     time.Duration(cfg.LLMBreakerOpenSeconds) * time.Second,
+	}, limiter)
+}
+
+// newLLMClient 根据 LLM_PROVIDER 创建对话模型客户端（判定、摘要、合并等），
+// 配置了 LLM_FALLBACK_PROVIDERS 时主提供商失败后按顺序切换
+func newLLMClient(cfg *config.Config) llm.LLM {
+	providers := []llm.NamedLLM{{Name: cfg.LLMProvider, LLM: newProviderLLM(cfg, cfg.LLMProvider)}}
+	for _, name := range parseProviderList(cfg.LLMFallbackProviders, cfg.LLMProvider) {
+		logger.System("LLM fallback provider enabled", "provider", name)
+		providers = append(providers, llm.NamedLLM{Name: name, LLM: newProviderLLM(cfg, name)})
+	}
+	return llm.NewFallbackLLM(providers...)
+}
+
+func newProviderLLM(cfg *config.Config, provider string) llm.LLM {
+	var client llm.LLM
+	switch provider {
+	case "openai":
+		logger.System("Using OpenAI LLM Provider", "model", cfg.OpenAIModel)
+		client = llm.NewOpenAIClient(cfg)
+	case "ollama":
+		logger.System("Using Ollama LLM Provider", "base_url", cfg.OllamaBaseURL, "model", cfg.OllamaModel)
+		client = llm.NewOllamaClient(cfg)
+	case "anthropic":
+		if cfg.AnthropicKey == "" {
+			panic("ANTHROPIC_API_KEY is required when using the anthropic provider")
+		}
+		logger.System("Using Anthropic LLM Provider", "model", cfg.AnthropicModel)
+		client = llm.NewAnthropicClient(cfg)
+	default:
+		panic("unknown llm provider: " + provider)
+	}
+	return llm.NewResilientLLM(client, newResilience(cfg, "llm", provider))
+}
+
+// newEmbedder 根据 EMBEDDING_PROVIDER 创建向量化客户端（未配置时沿用 LLM_PROVIDER），
+// 备用提供商来自 EMBEDDING_FALLBACK_PROVIDERS，维度需通过 VerifyDimensions 校验
+func newEmbedder(cfg *config.Config, redisStore *store.RedisStore) *llm.FallbackEmbedder {
+	primary := cfg.EmbeddingProvider
+	if primary == "" {
+		primary = cfg.LLMProvider
+	}
+
+	caches := newEmbeddingCaches(cfg, redisStore)
+	providers := []llm.NamedEmbedder{{Name: primary, Embedder: newProviderEmbedder(cfg, primary, caches)}}
+	for _, name := range parseProviderList(cfg.EmbeddingFallbackProviders, primary) {
+		logger.System("Embedding fallback provider enabled", "provider", name)
+		providers = append(providers, llm.NamedEmbedder{Name: name, Embedder: newProviderEmbedder(cfg, name, caches)})
+	}
+	return llm.NewFallbackEmbedder(cfg.EmbeddingDimension, providers...)
+}
+
+// newProviderEmbedder 创建单个提供商的向量化客户端；缓存按 提供商/模型 区分，各提供商共享同一组缓存
+func newProviderEmbedder(cfg *config.Config, provider string, caches []memory.EmbeddingCache) memory.Embedder {
+	var client memory.Embedder
+	var model string
+	switch provider {
+	case "openai":
+		logger.System("Using OpenAI Embedding Provider", "model", cfg.OpenAIEmbeddingModel)
+		client = llm.NewOpenAIClient(cfg)
+		model = cfg.OpenAIEmbeddingModel
+	case "ollama":
+		logger.System("Using Ollama Embedding Provider", "base_url", cfg.OllamaBaseURL, "model", cfg.OllamaEmbeddingModel)
+		client = llm.NewOllamaClient(cfg)
+		model = cfg.OllamaEmbeddingModel
+	case "anthropic":
+		panic("anthropic does not provide embeddings, use openai or ollama for embeddings")
+	default:
+		panic("unknown embedding provider: " + provider)
+	}
+
+	client = llm.NewResilientEmbedder(client, newResilience(cfg, "embedding", provider))
+	if len(caches) == 0 {
+		return client
+	}
+	return memory.NewCachingEmbedder(client, provider+"/"+model, caches...)
+}
+
+// newEmbeddingCaches 向量化缓存：进程内 LRU 为一级，EMBEDDING_CACHE_REDIS 开启时 Redis 为二级
+func newEmbeddingCaches(cfg *config.Config, redisStore *store.RedisStore) []memory.EmbeddingCache {
+	if !cfg.EmbeddingCacheEnabled {
+		return nil
+	}
+
+	caches := []memory.EmbeddingCache{store.NewInMemoryEmbeddingCache(cfg.EmbeddingCacheMaxEntries)}
+	if cfg.EmbeddingCacheRedis {
+		if redisStore == nil {
+			redisStore = store.NewRedisStore(cfg)
+		}
+		caches = append(caches, store.NewRedisEmbeddingCache(redisStore.GetClient(), time.Duration(cfg.EmbeddingCacheTTLHours)*time.Hour))
+	}
+	logger.System("Embedding cache enabled", "max_entries", cfg.EmbeddingCacheMaxEntries, "redis", cfg.EmbeddingCacheRedis)
+	return caches
+}
+
+// parseProviderList 解析逗号分隔的提供商列表，去重并排除主提供商
+func parseProviderList(list, primary string) []string {
+	seen := map[string]bool{primary: true}
+	var providers []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		providers = append(providers, name)
+	}
+	return providers
+}
+
+// newJudgeCache 根据 JUDGE_CACHE_PROVIDER 创建判定缓存；memory 返回 nil，沿用 Manager 默认的进程内 LRU
+func newJudgeCache(cfg *config.Config, redisStore *store.RedisStore) memory.JudgeCache {
+	switch cfg.JudgeCacheProvider {
+	case "memory":
+		return nil
+	case "redis":
+		if redisStore == nil {
+			redisStore = store.NewRedisStore(cfg)
+		}
+		logger.System("Using Redis Judge Cache", "addr", cfg.RedisAddr, "ttl_hours", cfg.JudgeCacheTTLHours)
+		return store.NewRedisJudgeCache(redisStore.GetClient(), time.Duration(cfg.JudgeCacheTTLHours)*time.Hour)
+	default:
+		panic("unknown judge cache provider: " + cfg.JudgeCacheProvider)
+	}
+}
@@ -9,20 +9,66 @@ CREATE TABLE IF NOT EXISTS users (
     id INT AUTO_INCREMENT PRIMARY KEY,
     username VARCHAR(255) NOT NULL UNIQUE,
     password_hash VARCHAR(255) NOT NULL,
+    must_change_password BOOLEAN NOT NULL DEFAULT FALSE COMMENT '首次登录后必须修改密码（随机生成的初始密码）',
+    role VARCHAR(32) NOT NULL DEFAULT 'support' COMMENT '角色: admin / ops / support，权限见 pkg/auth/role.go',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE COMMENT '停用后无法登录，已有会话立即失效',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+-- 已有库升级: ALTER TABLE users ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+-- 已有库升级（原有账号均为管理员）:
+--   ALTER TABLE users ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'support', ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+--   UPDATE users SET role = 'admin';
 
--- 4. Initial Admin User (Optional, app creates this on startup if missing)
--- Password: admin123
-INSERT INTO users (username, password_hash) VALUES ('admin', '$2a$10$FrMJpuNsOfEY.5edOFDoSOWsswfLVPG.MutG8xNcXtcZrc75YyYxu') ON DUPLICATE KEY UPDATE id=id;
+-- 4. Initial Admin User
+-- 不再内置默认密码：用户表为空时，应用启动会按 AUTH_BOOTSTRAP_ADMIN_USERNAME / AUTH_BOOTSTRAP_ADMIN_PASSWORD 创建管理员
+-- （密码留空则随机生成并输出到启动日志）。旧版本导入过 admin/admin123 的库，
+-- 应用启动时会把仍使用该密码的账号标记为 must_change_password 并输出警告，下次登录必须先修改密码。
+
+-- 4.1 管理后台登录会话（只保存令牌的 SHA-256）
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    access_hash CHAR(64) NOT NULL UNIQUE COMMENT '访问令牌 SHA-256',
+    refresh_hash CHAR(64) NOT NULL UNIQUE COMMENT '刷新令牌 SHA-256',
+    expires_at TIMESTAMP NOT NULL COMMENT '访问令牌过期时间',
+    refresh_expires_at TIMESTAMP NOT NULL COMMENT '刷新令牌过期时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_user (user_id),
+    INDEX idx_refresh_expires (refresh_expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) COMMENT='管理后台登录会话';
+
+-- 4.2 API Key（机器客户端调用记忆 API，只保存密钥的 SHA-256）
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL COMMENT '用途说明，如调用方服务名',
+    key_prefix VARCHAR(16) NOT NULL COMMENT '明文前缀，仅用于识别',
+    key_hash CHAR(64) NOT NULL UNIQUE COMMENT '密钥 SHA-256',
+    scopes VARCHAR(255) NOT NULL COMMENT '授权范围，逗号分隔: memory:read / memory:write / admin',
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' COMMENT '绑定的租户，密钥只能访问该租户的数据',
+    created_by INT NULL COMMENT '创建人 users.id',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    last_used_ip VARCHAR(64) NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL COMMENT '吊销时间，非空即失效',
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+) COMMENT='机器客户端 API Key';
+-- 已有库升级（多租户）: ALTER TABLE api_keys ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' AFTER scopes;
 
 -- 5. End Users Table (Tracks users interacting with the AI)
 CREATE TABLE IF NOT EXISTS end_users (
     id INT AUTO_INCREMENT PRIMARY KEY,
-    user_identifier VARCHAR(255) NOT NULL UNIQUE,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' COMMENT '租户（应用）ID，不同租户的同名用户相互隔离',
+    user_identifier VARCHAR(255) NOT NULL,
     last_active TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    language VARCHAR(16) DEFAULT NULL COMMENT 'Prompt 语言偏好（zh / en），NULL 表示使用 PROMPT_DEFAULT_LANGUAGE',
+    UNIQUE KEY uk_tenant_user (tenant_id, user_identifier)
 );
+-- 已有库升级: ALTER TABLE end_users ADD COLUMN language VARCHAR(16) DEFAULT NULL;
+-- 已有库升级（多租户）:
+--   ALTER TABLE end_users ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' AFTER id,
+--     DROP INDEX user_identifier, ADD UNIQUE KEY uk_tenant_user (tenant_id, user_identifier);
 
 -- 6. 监控指标时间序列表
 CREATE TABLE IF NOT EXISTS metrics_timeseries (
@@ -30,10 +76,12 @@ CREATE TABLE IF NOT EXISTS metrics_timeseries (
     metric_type VARCHAR(50) NOT NULL COMMENT '指标类型: promotion, queue_length, cache_hit_rate',
     value FLOAT NOT NULL COMMENT '指标值',
     category VARCHAR(50) DEFAULT NULL COMMENT '分类标签（如记忆分类）',
+    tenant_id VARCHAR(64) DEFAULT NULL COMMENT '租户ID（仅晋升记录，系统级指标为 NULL）',
     timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '记录时间',
     INDEX idx_type_time (metric_type, timestamp),
     INDEX idx_timestamp (timestamp)
 ) COMMENT='监控指标时间序列数据（支持24小时+长期趋势分析）';
+-- 已有库升级（多租户）: ALTER TABLE metrics_timeseries ADD COLUMN tenant_id VARCHAR(64) DEFAULT NULL AFTER category;
 
 -- 7. 监控指标累计统计表
 CREATE TABLE IF NOT EXISTS metrics_cumulative (
@@ -59,8 +107,11 @@ CREATE TABLE IF NOT EXISTS alerts (
     message TEXT COMMENT '告警消息内容',
     timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '告警时间',
     metadata TEXT COMMENT '元数据(JSON格式)',
-    INDEX idx_timestamp (timestamp)
+    tenant_id VARCHAR(64) DEFAULT NULL COMMENT '告警所属租户，系统级告警为 NULL',
+    INDEX idx_timestamp (timestamp),
+    INDEX idx_tenant_time (tenant_id, timestamp)
 ) COMMENT='告警历史记录';
+-- 已有库升级（多租户）: ALTER TABLE alerts ADD COLUMN tenant_id VARCHAR(64) DEFAULT NULL, ADD INDEX idx_tenant_time (tenant_id, timestamp);
 
 -- 9. 告警规则配置表
 CREATE TABLE IF NOT EXISTS alert_rule_configs (
@@ -79,7 +130,9 @@ INSERT INTO alert_rule_configs (id, name, description, enabled, cooldown_seconds
 ('queue_backlog', '队列积压告警', 'Staging队列长度超过阈值', TRUE, 600, '{"threshold": 100}'),
 ('low_success_rate', '晋升成功率过低', '记忆晋升成功率低于阈值', TRUE, 1800, '{"threshold": 60}'),
 ('cache_anomaly', '缓存命中率异常', '判定缓存命中率异常（智能检测）', TRUE, 900, '{"window_minutes": 5, "min_samples": 500, "warn_threshold": 30, "error_threshold": 15}'),
-('decay_spike', '记忆衰减突增', '遗忘的记忆数量突然增加', TRUE, 3600, '{"threshold": 1000}')
+('decay_spike', '记忆衰减突增', '遗忘的记忆数量突然增加', TRUE, 3600, '{"threshold": 1000}'),
+('llm_circuit_open', 'LLM调用熔断', 'LLM或Embedding提供商连续失败触发熔断', TRUE, 300, '{}'),
+('llm_budget_exhausted', 'LLM预算用尽', '终端用户或全局的LLM每日预算已用尽', TRUE, 3600, '{}')
 ON DUPLICATE KEY UPDATE updated_at = CURRENT_TIMESTAMP;
 
 -- 10. 告警统计数据表
@@ -96,3 +149,53 @@ CREATE TABLE IF NOT EXISTS alert_stats (
 INSERT INTO alert_stats (id, total_checks, notify_success, notify_failed)
 VALUES (1, 0, 0, 0)
 ON DUPLICATE KEY UPDATE id=id;
+
+-- 11. LLM 用量统计表
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' COMMENT '租户ID',
+    user_id VARCHAR(255) NOT NULL DEFAULT '' COMMENT '终端用户ID（后台任务无法归属时为空）',
+    operation VARCHAR(50) NOT NULL COMMENT '调用类型: judge_batch, summarize, extract_tags, merge_strategy, embed',
+    provider VARCHAR(50) NOT NULL COMMENT '提供商: openai, ollama, anthropic',
+    model VARCHAR(100) NOT NULL COMMENT '模型名称',
+    calls INT NOT NULL DEFAULT 0 COMMENT '调用次数',
+    prompt_tokens BIGINT NOT NULL DEFAULT 0 COMMENT '输入 token 数',
+    completion_tokens BIGINT NOT NULL DEFAULT 0 COMMENT '输出 token 数',
+    embedding_tokens BIGINT NOT NULL DEFAULT 0 COMMENT '向量化 token 数',
+    cost_usd DECIMAL(14, 6) NOT NULL DEFAULT 0 COMMENT '估算成本（美元）',
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '记录时间（持久化批次时间）',
+    INDEX idx_timestamp (timestamp),
+    INDEX idx_user_time (user_id, timestamp),
+    INDEX idx_tenant_time (tenant_id, timestamp),
+    INDEX idx_operation_time (operation, timestamp)
+) COMMENT='LLM 调用用量与成本（按持久化周期聚合）';
+-- 已有库升级（多租户）: ALTER TABLE llm_usage ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' AFTER id, ADD INDEX idx_tenant_time (tenant_id, timestamp);
+
+-- 12. Prompt 模板表（覆盖内置模板与 PROMPT_TEMPLATE_DIR 中的同名同语言模板，启动时加载）
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL COMMENT '模板名: judge_value, judge_batch, extract_tags, summarize, merge_strategy',
+    language VARCHAR(16) NOT NULL COMMENT '语言: zh, en',
+    version VARCHAR(50) NOT NULL DEFAULT '' COMMENT '版本号（留空时使用内容哈希），记录到记忆的 metadata.prompt_versions',
+    content TEXT NOT NULL COMMENT 'Go text/template 模板内容',
+    enabled BOOLEAN DEFAULT TRUE COMMENT '是否启用',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_name_language (name, language)
+) COMMENT='Prompt 模板';
+
+-- 13. 审计日志表（只追加：记忆编辑/删除、暂存区审核、后台任务合并/遗忘、告警规则与账号变更）
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '操作时间',
+    tenant_id VARCHAR(64) DEFAULT NULL COMMENT '租户ID（系统级操作为空）',
+    actor VARCHAR(255) NOT NULL COMMENT '操作人: 后台用户名、apikey:<name> 或 system:<任务名>',
+    action VARCHAR(64) NOT NULL COMMENT '动作: memory.update, memory.delete, stm.clear, staging.confirm, staging.reject, ltm.merge, ltm.evict, alert_rule.update, account.*, apikey.*',
+    target_id VARCHAR(255) NOT NULL DEFAULT '' COMMENT '操作对象ID（记忆/暂存条目/会话/规则/账号）',
+    user_id VARCHAR(255) NOT NULL DEFAULT '' COMMENT '被操作记忆所属的终端用户',
+    detail TEXT COMMENT '变更详情（JSON）',
+    INDEX idx_timestamp (timestamp),
+    INDEX idx_actor_time (actor, timestamp),
+    INDEX idx_user_time (user_id, timestamp),
+    INDEX idx_action_time (action, timestamp),
+    INDEX idx_tenant_time (tenant_id, timestamp)
+) COMMENT='审计日志（应用只执行 INSERT / SELECT）';